@@ -0,0 +1,105 @@
+// gadget sets up the Pi's USB gadget with pkg/gadget, the idempotent replacement for init/enable-rpi-hid. Without -c
+// it sets up the same gadget as the script. Run it as root.
+//
+//	gadget up                        create or update the gadget and bind it
+//	gadget down                      unbind and remove it
+//	gadget status                    show what is in configfs
+//	gadget -c configs/gadget.json up
+//	gadget -root /tmp/configfs -udc-dir /tmp/udc up
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/scirelli/turkey-pi/pkg/gadget"
+)
+
+func main() {
+	var configPath = flag.String("c", "", "Gadget config JSON, the gadget init/enable-rpi-hid sets up when empty.")
+	var switchPro = flag.Bool("switchpro", false, "Use the Pro Controller gadget from init/enable-switchpro-hid instead.")
+	var root = flag.String("root", gadget.DEFAULT_ROOT, "configfs usb_gadget directory.")
+	var udcDir = flag.String("udc-dir", gadget.DEFAULT_UDC_DIR, "Directory listing the USB device controllers.")
+	var asJSON = flag.Bool("json", false, "Print status as JSON.")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] up|down|status\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var config gadget.Config = gadget.DefaultConfig()
+	if *switchPro {
+		config = gadget.SwitchProConfig()
+	}
+	if *configPath != "" {
+		var err error
+		if config, err = gadget.LoadConfig(*configPath); err != nil {
+			fail(err)
+		}
+	}
+	var m = gadget.Manager{Root: *root, UDCDir: *udcDir}
+
+	switch flag.Arg(0) {
+	case "up":
+		changed, err := m.Up(config)
+		if err != nil {
+			fail(err)
+		}
+		if changed {
+			fmt.Printf("Gadget '%s' is up\n", config.Name)
+		} else {
+			fmt.Printf("Gadget '%s' is up, nothing changed\n", config.Name)
+		}
+	case "down":
+		if err := m.Down(config.Name); err != nil {
+			fail(err)
+		}
+		fmt.Printf("Gadget '%s' is down\n", config.Name)
+	case "status":
+		status, err := m.Status(config.Name)
+		if err != nil {
+			fail(err)
+		}
+		if *asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.Encode(status)
+			return
+		}
+		printStatus(status)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+func printStatus(s gadget.Status) {
+	if !s.Exists {
+		fmt.Printf("Gadget '%s' does not exist\n", s.Name)
+		return
+	}
+	udc := s.UDC
+	if udc == "" {
+		udc = "not bound"
+	}
+	fmt.Printf("Gadget '%s' %s:%s, %s\n", s.Name, s.VendorID, s.ProductID, udc)
+	for _, f := range s.Functions {
+		linked := "linked"
+		if !f.Linked {
+			linked = "not linked"
+		}
+		fmt.Printf("  hid.%-8s %-12s %s\n", f.Name, linked, f.Device)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
@@ -0,0 +1,170 @@
+// hiddecode decodes the keyboard traffic in a usbmon capture taken on the host: the text from
+// /sys/kernel/debug/usb/usbmon/<bus>u or a pcap/pcapng file from tcpdump or Wireshark. It prints each key going down
+// and up, the LED reports the host sends and the text the host typed, along with any ghost, stuck or dropped keys.
+//
+//	cat /sys/kernel/debug/usb/usbmon/1u > session.usbmon
+//	go run ./cmd/hiddecode session.usbmon
+//	go run ./cmd/hiddecode -layout de -device 1:3 session.pcapng
+//
+// The keyboard is the first device whose interrupt IN reports are 8 bytes unless -device picks one.
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/scirelli/turkey-pi/pkg/keyboard"
+	"github.com/scirelli/turkey-pi/pkg/keyboard/hidtest"
+	"github.com/scirelli/turkey-pi/pkg/usbmon"
+)
+
+func main() {
+	var layoutName = flag.String("layout", keyboard.DEFAULT_LAYOUT, "Keyboard layout the host uses, one of: "+strings.Join(keyboard.LayoutNames(), ", "))
+	var deviceFlag = flag.String("device", "", "Keyboard as bus:device, e.g. 1:3. Found from the reports when empty.")
+	var repeat = flag.Duration("repeat", 500*time.Millisecond, "Host key repeat delay, keys held longer are reported stuck. 0 to not check.")
+	var capsLock = flag.Bool("caps", false, "Caps Lock is on when the capture starts.")
+	var raw = flag.Bool("raw", false, "Print every report as well as the key events.")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [capture file, stdin when omitted]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	layout, err := keyboard.LookupLayout(*layoutName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	var in io.Reader = os.Stdin
+	if flag.NArg() > 0 {
+		f, err := os.Open(flag.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		in = f
+	}
+	packets, err := readPackets(in)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	var bus, device int
+	if *deviceFlag != "" {
+		if bus, device, err = parseDevice(*deviceFlag); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	} else if bus, device, err = findKeyboard(packets); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	var decoder *hidtest.Decoder = hidtest.NewDecoder(layout)
+	decoder.CapsLock, decoder.RepeatDelay = *capsLock, *repeat
+	decode(os.Stdout, packets, bus, device, decoder, *raw)
+	if len(decoder.Problems) > 0 {
+		os.Exit(1)
+	}
+}
+
+// readPackets pcap and pcapng files are told apart from usbmon text by their first bytes.
+func readPackets(r io.Reader) ([]usbmon.Packet, error) {
+	var br *bufio.Reader = bufio.NewReader(r)
+
+	magic, _ := br.Peek(4)
+	if len(magic) == 4 {
+		for _, m := range []uint32{usbmon.PCAP_MAGIC, usbmon.PCAP_MAGIC_NANO, usbmon.PCAPNG_SECTION} {
+			if binary.LittleEndian.Uint32(magic) == m || binary.BigEndian.Uint32(magic) == m {
+				return usbmon.ReadCapture(br)
+			}
+		}
+	}
+	return usbmon.ReadText(br)
+}
+
+func parseDevice(s string) (bus, device int, err error) {
+	parts := strings.Split(s, ":")
+	if len(parts) == 2 {
+		bus, err = strconv.Atoi(parts[0])
+		if err == nil {
+			device, err = strconv.Atoi(parts[1])
+		}
+		if err == nil {
+			return bus, device, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("Device '%s' should be bus:device, e.g. 1:3", s)
+}
+
+// findKeyboard the first device sending 8 byte interrupt IN reports with the reserved byte 0, a boot keyboard.
+func findKeyboard(packets []usbmon.Packet) (bus, device int, err error) {
+	for _, p := range packets {
+		if report, ok := p.InputReport(); ok && len(report) == keyboard.ReportSz && report[1] == 0 {
+			return p.Bus, p.Device, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("No keyboard reports in the capture, use -device to pick the device")
+}
+
+// decode prints the keyboard's key events and LED reports as it feeds them to decoder, then the text it typed.
+func decode(w io.Writer, packets []usbmon.Packet, bus, device int, decoder *hidtest.Decoder, raw bool) {
+	var start time.Time
+	var reports, events int
+
+	fmt.Fprintf(w, "Keyboard on bus %d device %d, %s layout\n", bus, device, decoder.Layout)
+	for _, p := range packets {
+		if p.Bus != bus || p.Device != device {
+			continue
+		}
+		if start.IsZero() {
+			start = p.Time
+		}
+		at := p.Time.Sub(start).Seconds()
+
+		if report, ok := p.InputReport(); ok {
+			if len(report) != keyboard.ReportSz {
+				continue
+			}
+			var r = hidtest.Report{Time: p.Time}
+			copy(r.Data[:], report)
+			if raw {
+				fmt.Fprintf(w, "%12.6f  in    % x\n", at, report)
+			}
+			decoder.Decode(r)
+			reports++
+		} else if report, ok := p.OutputReport(); ok && len(report) == keyboard.LED_REPORT_SZ {
+			if raw {
+				fmt.Fprintf(w, "%12.6f  out   % x\n", at, report)
+			}
+			decoder.SetLEDs(keyboard.LEDState(report[0]))
+			fmt.Fprintf(w, "%12.6f  leds  %s\n", at, keyboard.LEDState(report[0]))
+		}
+
+		for ; events < len(decoder.Events); events++ {
+			e := decoder.Events[events]
+			if e.Rune != 0 {
+				fmt.Fprintf(w, "%12.6f  %-4s  %-16s %s\n", e.Time.Sub(start).Seconds(), e.Kind, keyboard.KeyName(e.Keycode), strconv.QuoteRune(e.Rune))
+			} else {
+				fmt.Fprintf(w, "%12.6f  %-4s  %s\n", e.Time.Sub(start).Seconds(), e.Kind, keyboard.KeyName(e.Keycode))
+			}
+		}
+	}
+	decoder.Finish()
+
+	fmt.Fprintf(w, "\n%d reports\n", reports)
+	fmt.Fprintf(w, "Text: %s\n", strconv.Quote(decoder.Text()))
+	for _, p := range decoder.Problems {
+		fmt.Fprintf(w, "Problem: %s at %.6f\n", p, p.Time.Sub(start).Seconds())
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scirelli/turkey-pi/pkg/keyboard"
+	"github.com/scirelli/turkey-pi/pkg/keyboard/hidtest"
+	"github.com/scirelli/turkey-pi/pkg/usbmon"
+)
+
+// decodeFile what hiddecode prints for a capture with its default flags.
+func decodeFile(t *testing.T, name string) string {
+	t.Helper()
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	packets, err := readPackets(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	bus, device, err := findKeyboard(packets)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	layout, err := keyboard.LookupLayout(keyboard.DEFAULT_LAYOUT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoder := hidtest.NewDecoder(layout)
+	decoder.RepeatDelay = 500 * time.Millisecond
+
+	var out bytes.Buffer
+	decode(&out, packets, bus, device, decoder, false)
+	return out.String()
+}
+
+func TestGolden(t *testing.T) {
+	for _, name := range []string{"keyboard.usbmon", "keyboard.pcap", "keyboard.pcapng", "problems.usbmon"} {
+		t.Run(name, func(t *testing.T) {
+			want, err := ioutil.ReadFile(filepath.Join("testdata", strings.TrimSuffix(name, filepath.Ext(name))+".txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := decodeFile(t, name); got != string(want) {
+				t.Errorf("%s decodes to\n%s\nwant\n%s", name, got, want)
+			}
+		})
+	}
+}
+
+func TestTruncatedCapture(t *testing.T) {
+	for _, name := range []string{"keyboard.pcap", "keyboard.pcapng"} {
+		t.Run(name, func(t *testing.T) {
+			data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			whole, err := readPackets(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			packets, err := readPackets(bytes.NewReader(data[:len(data)-5]))
+			var formatErr *usbmon.FormatError
+			if !errors.As(err, &formatErr) || !strings.Contains(formatErr.Msg, "truncated") {
+				t.Errorf("truncated %s returned %v, want a truncated FormatError", name, err)
+			}
+			if len(packets) != len(whole)-1 {
+				t.Errorf("truncated %s read %d packets, want the %d before the cut", name, len(packets), len(whole)-1)
+			}
+
+			if _, err := usbmon.ReadCapture(bytes.NewReader(data[:3])); !errors.As(err, &formatErr) {
+				t.Errorf("3 bytes of %s returned %v, want a FormatError", name, err)
+			}
+			if _, err := usbmon.ReadCapture(bytes.NewReader(data[:20])); !errors.As(err, &formatErr) {
+				t.Errorf("the cut off header of %s returned %v, want a FormatError", name, err)
+			}
+		})
+	}
+}
+
+func TestBadMagic(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "keyboard.pcap"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = append([]byte{0xde, 0xad, 0xbe, 0xef}, data[4:]...)
+
+	var formatErr *usbmon.FormatError
+	if _, err := usbmon.ReadCapture(bytes.NewReader(data)); !errors.As(err, &formatErr) || !strings.Contains(formatErr.Msg, "magic") {
+		t.Errorf("ReadCapture with a bad magic returned %v", err)
+	}
+	pcapng, err := ioutil.ReadFile(filepath.Join("testdata", "keyboard.pcapng"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pcapng = append(append(append([]byte{}, pcapng[:8]...), 0xde, 0xad, 0xbe, 0xef), pcapng[12:]...)
+	if _, err := usbmon.ReadCapture(bytes.NewReader(pcapng)); !errors.As(err, &formatErr) || !strings.Contains(formatErr.Msg, "byte order magic") {
+		t.Errorf("ReadCapture with a bad pcapng byte order magic returned %v", err)
+	}
+	// Without a capture magic hiddecode reads usbmon text, which the binary is not.
+	if _, err := readPackets(bytes.NewReader(data)); !errors.As(err, &formatErr) || formatErr.Line != 1 {
+		t.Errorf("readPackets with a bad magic returned %v, want a FormatError on line 1", err)
+	}
+}
+
+func TestBadTextLine(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "keyboard.usbmon"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	text := strings.Join(lines[:2], "") + "ffff8a1c2f4b9e00 12345\n"
+
+	packets, err := readPackets(strings.NewReader(text))
+	var formatErr *usbmon.FormatError
+	if !errors.As(err, &formatErr) || formatErr.Line != 3 {
+		t.Errorf("a cut off line returned %v, want a FormatError on line 3", err)
+	}
+	if len(packets) != 2 {
+		t.Errorf("read %d packets before the bad line, want 2", len(packets))
+	}
+}
+
+func TestNoKeyboard(t *testing.T) {
+	if _, _, err := findKeyboard(nil); err == nil {
+		t.Error("findKeyboard found a keyboard in no packets")
+	}
+}
@@ -0,0 +1,165 @@
+// hiddescriptor prints a HID report descriptor item by item and the layout of the reports it defines. Give it the name
+// of one of the built in devices or the descriptor in hex. With -check it instead checks every built in device's
+// report length against its descriptor and that the descriptors init/enable-rpi-hid writes are the built in ones,
+// exiting 1 if any differ.
+//
+//	go run ./cmd/hiddescriptor keyboard
+//	go run ./cmd/hiddescriptor -hex "05 01 09 06 a1 01 ... c0"
+//	go run ./cmd/hiddescriptor -check
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/scirelli/turkey-pi/pkg/gadget"
+	"github.com/scirelli/turkey-pi/pkg/hid/descriptor"
+	"github.com/scirelli/turkey-pi/pkg/keyboard"
+)
+
+func main() {
+	var hexDescriptor = flag.String("hex", "", "Descriptor bytes in hex, spaces, commas and 0x prefixes are ignored.")
+	var check = flag.Bool("check", false, "Check the built in devices' report lengths and the descriptors in -script.")
+	var script = flag.String("script", "init/enable-rpi-hid", "Gadget script whose report descriptors -check compares to the built in ones.")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [%s]\n", os.Args[0], strings.Join(gadget.BuiltinDeviceNames(), "|"))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *check {
+		if !checkBuiltins() || !checkScript(*script) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	var data []byte
+	switch {
+	case *hexDescriptor != "":
+		var err error
+		if data, err = parseHex(*hexDescriptor); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	case flag.NArg() == 1:
+		d, ok := gadget.BuiltinDevices[flag.Arg(0)]
+		if !ok {
+			flag.Usage()
+			os.Exit(2)
+		}
+		data = d.ReportDescriptor
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	items, err := descriptor.Parse(data)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Print(descriptor.Format(items))
+	layout, err := descriptor.LayoutOf(items)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Println()
+	fmt.Print(descriptor.FormatLayout(layout))
+}
+
+// checkBuiltins each built in device's report length must fit its largest report. Devices without report IDs send a
+// single input report that must be exactly the report length, and the keyboard's LED report must be LED_REPORT_SZ.
+func checkBuiltins() bool {
+	var ok = true
+
+	for _, name := range gadget.BuiltinDeviceNames() {
+		d := gadget.BuiltinDevices[name]
+		layout, err := descriptor.ParseLayout(d.ReportDescriptor)
+		if err != nil {
+			fmt.Printf("%-10s %s\n", name, err)
+			ok = false
+			continue
+		}
+		var problems []string
+		if d.ReportLength < layout.MaxSize() {
+			problems = append(problems, fmt.Sprintf("report length %d is shorter than the largest report, %d bytes", d.ReportLength, layout.MaxSize()))
+		}
+		if r, found := layout.Report(descriptor.INPUT, 0); found && r.Size() != d.ReportLength {
+			problems = append(problems, fmt.Sprintf("report length %d but the input report is %d bytes", d.ReportLength, r.Size()))
+		}
+		if name == "keyboard" {
+			if r, _ := layout.Report(descriptor.OUTPUT, 0); r.Size() != keyboard.LED_REPORT_SZ {
+				problems = append(problems, fmt.Sprintf("LED_REPORT_SZ is %d but the output report is %d bytes", keyboard.LED_REPORT_SZ, r.Size()))
+			}
+		}
+		if len(problems) == 0 {
+			fmt.Printf("%-10s ok, %d reports, report length %d\n", name, len(layout.Reports), d.ReportLength)
+			continue
+		}
+		for _, p := range problems {
+			fmt.Printf("%-10s %s\n", name, p)
+		}
+		ok = false
+	}
+	return ok
+}
+
+var scriptDescriptor = regexp.MustCompile(`^echo -ne ((?:\\\\x[0-9a-fA-F]{2})+) > "\$\{(\w+)\}/report_desc"`)
+
+// checkScript every report_desc the gadget script writes must be one of the built in descriptors.
+func checkScript(path string) bool {
+	var ok = true
+
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64*1024)
+	for scanner.Scan() {
+		m := scriptDescriptor.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		data, err := parseHex(strings.ReplaceAll(m[1], `\\x`, ""))
+		if err != nil {
+			fmt.Printf("%-10s %s\n", path, err)
+			ok = false
+			continue
+		}
+		var match string
+		for _, name := range gadget.BuiltinDeviceNames() {
+			if bytes.Equal(gadget.BuiltinDevices[name].ReportDescriptor, data) {
+				match = name
+			}
+		}
+		if match == "" {
+			fmt.Printf("%-10s %s writes a %d byte descriptor that is not a built in one\n", path, m[2], len(data))
+			ok = false
+			continue
+		}
+		fmt.Printf("%-10s %s is the %s descriptor\n", path, m[2], match)
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Println(err)
+		return false
+	}
+	return ok
+}
+
+// parseHex descriptor bytes written as "05 01", "0x05, 0x01" or "0501".
+func parseHex(s string) ([]byte, error) {
+	s = strings.NewReplacer("0x", "", "0X", "", ",", "", " ", "", "\n", "", "\t", "").Replace(s)
+	return hex.DecodeString(s)
+}
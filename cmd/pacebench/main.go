@@ -0,0 +1,86 @@
+// pacebench types a run of text into a file at a set rate and reports how closely the keyboard kept to it. With
+// -optimize the text is typed with planned reports, the number of reports saved is shown and the plan is checked
+// against the one stroke at a time stream with keyboard.DecodeReports.
+//
+//	go run ./cmd/pacebench -rate 50 -chars 500 -optimize
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/scirelli/turkey-pi/pkg/keyboard"
+)
+
+func main() {
+	var rate = flag.Float64("rate", 50, "Keystrokes per second to aim for.")
+	var chars = flag.Int("chars", 500, "Number of characters to type.")
+	var runs = flag.Int("runs", 3, "Number of times to type them.")
+	var out = flag.String("o", os.DevNull, "File the reports are written to.")
+	var optimize = flag.Bool("optimize", false, "Type with planned reports, see keyboard.PlanReports.")
+	flag.Parse()
+
+	if *rate <= 0 || *chars <= 0 {
+		fmt.Fprintln(os.Stderr, "rate and chars must be greater than 0")
+		os.Exit(2)
+	}
+
+	f, err := os.OpenFile(*out, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	var kb keyboard.File
+	kb.Device = f
+	// A stroke is a press and a release, each followed by StrokeDelay.
+	kb.StrokeDelay = time.Duration(float64(time.Second) / *rate / 2)
+	kb.OptimizeReports = *optimize
+	text := strings.Repeat("The Quick brown FOX jumps, 1100 times.\n", *chars/39+1)[:*chars]
+
+	if *optimize {
+		if err := compareReports(&kb, text); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	fmt.Printf("%4s %8s %10s %10s %12s %12s %10s\n", "run", "strokes", "target", "elapsed", "target/s", "achieved/s", "max late")
+	for i := 1; i <= *runs; i++ {
+		if _, err := kb.WriteStringContext(context.Background(), text); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		stats := kb.PaceStats()
+		fmt.Printf("%4d %8d %10s %10s %12.2f %12.2f %10s\n", i, stats.Strokes, stats.Target.Round(time.Millisecond),
+			stats.Elapsed.Round(time.Millisecond), stats.TargetRate, stats.AchievedRate, stats.MaxLate.Round(time.Microsecond))
+	}
+}
+
+// compareReports prints how many reports planning saves for text and checks both streams type the same strokes.
+func compareReports(kb *keyboard.File, text string) error {
+	var strokes []keyboard.Stroke
+
+	for _, c := range text {
+		strokes = append(strokes, kb.Strokes(c, keyboard.Options{})...)
+	}
+	naive, err := keyboard.StrokeReports(strokes)
+	if err != nil {
+		return err
+	}
+	planned, err := keyboard.PlanReports(strokes)
+	if err != nil {
+		return err
+	}
+	if !keyboard.EquivalentReports(naive, planned) {
+		return fmt.Errorf("planned reports do not type the same strokes")
+	}
+	fmt.Printf("reports: %d one stroke at a time, %d planned (%.0f%%), equivalent\n\n", len(naive), len(planned),
+		100*float64(len(planned))/float64(len(naive)))
+	return nil
+}
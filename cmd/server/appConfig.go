@@ -2,11 +2,15 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"time"
 
 	"github.com/scirelli/turkey-pi/internal/app/server"
+	"github.com/scirelli/turkey-pi/pkg/keyboard"
 	"github.com/scirelli/turkey-pi/pkg/log"
+	"github.com/scirelli/turkey-pi/pkg/pointer"
 )
 
 const (
@@ -35,6 +39,66 @@ func LoadConfig(fileName string) (*AppConfig, error) {
 	return &config, nil
 }
 
+//LoadCharMap the user defined character map, entries in CharacterToKeyMap override those in CharacterToKeyFile.
+//Invalid entries from both are returned together as keyboard.CharMapErrors, a table of JavaScript keyCodes in either
+//as a keyboard.KeyCodeMapError.
+func LoadCharMap(config *AppConfig) (keyboard.CharMap, error) {
+	var charMap keyboard.CharMap = keyboard.CharMap{}
+	var errs keyboard.CharMapErrors
+
+	if config.CharacterToKeyFile != "" {
+		fileMap, err := keyboard.LoadCharMapFile(config.CharacterToKeyFile)
+		if fileErrs, ok := err.(keyboard.CharMapErrors); ok {
+			errs = append(errs, fileErrs...)
+		} else if err != nil {
+			return charMap, err
+		}
+		charMap = charMap.Merge(fileMap)
+	}
+
+	inlineMap, err := keyboard.ParseCharMap(config.CharacterToKeyMap)
+	if inlineErrs, ok := err.(keyboard.CharMapErrors); ok {
+		errs = append(errs, inlineErrs...)
+	} else if err != nil {
+		return charMap, err
+	}
+	charMap = charMap.Merge(inlineMap)
+
+	if len(errs) > 0 {
+		return charMap, errs
+	}
+	return charMap, nil
+}
+
+//RegisterTimingProfiles makes the profiles in the keyboard config available to keyboard.LookupTimingProfile.
+func RegisterTimingProfiles(config *AppConfig) error {
+	for _, c := range config.Keyboard.TimingProfiles {
+		distribution, err := keyboard.ParseDistribution(c.Jitter)
+		if err != nil {
+			return fmt.Errorf("timing profile '%s': %w", c.Name, err)
+		}
+		var rules []keyboard.DelayRule
+		for _, r := range c.Rules {
+			rules = append(rules, keyboard.DelayRule{Chars: r.Chars, Repeat: r.Repeat, Scale: r.Scale, Add: time.Duration(r.AddMs) * time.Millisecond})
+		}
+		err = keyboard.RegisterTimingProfile(&keyboard.TimingProfile{
+			Name:             c.Name,
+			Hold:             time.Duration(c.HoldMs) * time.Millisecond,
+			HoldJitter:       keyboard.Jitter{Distribution: distribution, Amount: time.Duration(c.HoldJitterMs) * time.Millisecond},
+			Gap:              time.Duration(c.GapMs) * time.Millisecond,
+			GapJitter:        keyboard.Jitter{Distribution: distribution, Amount: time.Duration(c.GapJitterMs) * time.Millisecond},
+			PunctuationPause: time.Duration(c.PunctuationPauseMs) * time.Millisecond,
+			NewlinePause:     time.Duration(c.NewlinePauseMs) * time.Millisecond,
+			Rules:            rules,
+			Seed:             c.Seed,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func Defaults(config *AppConfig) *AppConfig {
 	var logger = log.New("AppConfig", log.GetLevel(config.LogLevel))
 
@@ -51,6 +115,16 @@ func Defaults(config *AppConfig) *AppConfig {
 		logger.Infof("Defaulting keyboard file to '%s'", config.Keyboard.File)
 	}
 
+	if config.Keyboard.Layout == "" {
+		config.Keyboard.Layout = keyboard.DEFAULT_LAYOUT
+		logger.Infof("Defaulting keyboard layout to '%s'", config.Keyboard.Layout)
+	}
+
+	if config.Keyboard.LockStrategy == "" {
+		config.Keyboard.LockStrategy = string(keyboard.LOCKS_IGNORE)
+		logger.Infof("Defaulting keyboard lock strategy to '%s'", config.Keyboard.LockStrategy)
+	}
+
 	config.Server.Debug = config.Debug
 
 	server.Defaults(&config.Server)
@@ -65,10 +139,91 @@ type AppConfig struct {
 	CharacterToKeyFile string            `json:"characterToKeyFile,omitempty"`
 	CharacterToKeyMap  map[string]string `json:"characterToKeyMap"`
 	Keyboard           keyboardConfig    `json:"keyboard"`
-	Server             server.Config     `json: "server,omitempty"`
+	Mouse              mouseConfig       `json:"mouse"`
+	Media              mediaConfig       `json:"media"`
+	Gamepad            gamepadConfig     `json:"gamepad"`
+	SwitchPro          switchProConfig   `json:"switchPro"`
+	Pointer            pointerConfig     `json:"pointer"`
+	Server             server.Config     `json:"server,omitempty"`
 }
 
 type keyboardConfig struct {
 	File          string `json:"file"`
 	StrokeDelayMs int    `json:"StrokeDelayMs"`
+	Layout        string `json:"layout"`
+	LockStrategy  string `json:"lockStrategy"`
+	//OptimizeReports type with fewer reports, holding shift across capitals, see keyboard.PlanReports.
+	OptimizeReports bool `json:"optimizeReports"`
+	//TimingProfile name of a built-in or timingProfiles profile, StrokeDelayMs is used when empty.
+	TimingProfile  string                `json:"timingProfile"`
+	TimingProfiles []timingProfileConfig `json:"timingProfiles"`
+	//TargetOS the host's operating system, picks how runes Layout has no key for are entered, see keyboard.LookupUnicodeEntry.
+	TargetOS string `json:"targetOS"`
+	//Capture mirror the reports written to File into pcapng files, see capture.Capture.
+	Capture captureConfig `json:"capture"`
+}
+
+//captureConfig where keyboard captures are written, there are none when Dir is empty. Enabled starts capturing at
+//startup, otherwise POST /debug/capture/start does.
+type captureConfig struct {
+	Dir      string `json:"dir"`
+	Enabled  bool   `json:"enabled"`
+	MaxBytes int64  `json:"maxBytes"`
+	MaxFiles int    `json:"maxFiles"`
+}
+
+//mouseConfig the optional mouse gadget function, there is no mouse when File is empty.
+type mouseConfig struct {
+	File        string `json:"file"`
+	StepDelayMs int    `json:"stepDelayMs"`
+}
+
+//mediaConfig the optional consumer control gadget function for media keys, there are no media keys when File is empty.
+type mediaConfig struct {
+	File          string `json:"file"`
+	StrokeDelayMs int    `json:"strokeDelayMs"`
+}
+
+//gamepadConfig the optional gamepad gadget function, there is no gamepad when File is empty.
+type gamepadConfig struct {
+	File         string `json:"file"`
+	PressDelayMs int    `json:"pressDelayMs"`
+}
+
+//switchProConfig the optional Pro Controller, there is none when File is empty. The gadget must be set up with
+//init/enable-switchpro-hid, File is read and written.
+type switchProConfig struct {
+	File     string `json:"file"`
+	PeriodMs int    `json:"periodMs"`
+}
+
+//pointerConfig the optional absolute pointer gadget function, there is none when File is empty. Screen is needed to
+//point at pixels, see pointer.Calibration.
+type pointerConfig struct {
+	File       string              `json:"file"`
+	TapDelayMs int                 `json:"tapDelayMs"`
+	Screen     pointer.Calibration `json:"screen"`
+}
+
+//timingProfileConfig a keyboard.TimingProfile with delays in milliseconds. Jitter is the distribution for both jitters.
+type timingProfileConfig struct {
+	Name               string `json:"name"`
+	HoldMs             int    `json:"holdMs"`
+	HoldJitterMs       int    `json:"holdJitterMs"`
+	GapMs              int    `json:"gapMs"`
+	GapJitterMs        int    `json:"gapJitterMs"`
+	Jitter             string `json:"jitter"`
+	PunctuationPauseMs int    `json:"punctuationPauseMs"`
+	NewlinePauseMs     int    `json:"newlinePauseMs"`
+	Seed               int64  `json:"seed"`
+
+	Rules []delayRuleConfig `json:"rules"`
+}
+
+//delayRuleConfig a keyboard.DelayRule with AddMs in milliseconds.
+type delayRuleConfig struct {
+	Chars  string  `json:"chars"`
+	Repeat bool    `json:"repeat"`
+	Scale  float64 `json:"scale"`
+	AddMs  int     `json:"addMs"`
 }
@@ -1,14 +1,20 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
 	"time"
 
 	"github.com/scirelli/turkey-pi/internal/app/server"
+	"github.com/scirelli/turkey-pi/pkg/gamepad"
 	"github.com/scirelli/turkey-pi/pkg/keyboard"
+	"github.com/scirelli/turkey-pi/pkg/keyboard/capture"
 	"github.com/scirelli/turkey-pi/pkg/log"
+	"github.com/scirelli/turkey-pi/pkg/mouse"
+	"github.com/scirelli/turkey-pi/pkg/pointer"
+	"github.com/scirelli/turkey-pi/pkg/switchpro"
 )
 
 func main() {
@@ -23,7 +29,7 @@ func main() {
 	flag.StringVar(&configPath, "c", os.Getenv("SERVER_CONFIG"), "path to the config file (shorthand).")
 	flag.StringVar(&keyboardFile, "keyboard-file", "", fmt.Sprintf("path to the keyboard device. (default '%s')", KEYBOARD_DEFAULT_FILE))
 	flag.StringVar(&keyboardFile, "k", "", "path to the keyboard device (shorthand).")
-	flag.UintVar(&port, "port", 0, fmt.Sprintf("Port for server to listen on. (default '%n')", server.DEFAULT_PORT))
+	flag.UintVar(&port, "port", 0, fmt.Sprintf("Port for server to listen on. (default '%d')", server.DEFAULT_PORT))
 	flag.UintVar(&port, "p", 0, "Port for server to listen on.")
 
 	cwd, err := os.Getwd()
@@ -47,18 +53,132 @@ func main() {
 	}
 
 	logger.Infof("Keyboard file '%s'", keyboardFile)
-	f, err := os.OpenFile(keyboardFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	f, err := os.OpenFile(keyboardFile, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
 	if err != nil {
 		logger.Fatal(err)
 	}
 	var kf keyboard.File
-	kf.File = *f
+	kf.Device = f
+	var kc *capture.Capture
+	if appConfig.Keyboard.Capture.Dir != "" {
+		kc = &capture.Capture{Dir: appConfig.Keyboard.Capture.Dir, MaxBytes: appConfig.Keyboard.Capture.MaxBytes, MaxFiles: appConfig.Keyboard.Capture.MaxFiles}
+		kf.Device = &capture.Tap{Device: f, Capture: kc}
+		if appConfig.Keyboard.Capture.Enabled {
+			if err := kc.Start(); err != nil {
+				logger.Fatal(err)
+			}
+			logger.Infof("Capturing keyboard reports to '%s'", appConfig.Keyboard.Capture.Dir)
+		}
+		defer kc.Stop()
+	}
 	kf.StrokeDelay = time.Millisecond * time.Duration(appConfig.Keyboard.StrokeDelayMs)
+	if kf.Layout, err = keyboard.LookupLayout(appConfig.Keyboard.Layout); err != nil {
+		logger.Fatal(err)
+	}
+	logger.Infof("Keyboard layout '%s'", kf.Layout)
+	if kf.LockStrategy, err = keyboard.ParseLockStrategy(appConfig.Keyboard.LockStrategy); err != nil {
+		logger.Fatal(err)
+	}
+	kf.OptimizeReports = appConfig.Keyboard.OptimizeReports
+	if kf.UnicodeEntry, err = keyboard.LookupUnicodeEntry(appConfig.Keyboard.TargetOS); err != nil {
+		logger.Fatal(err)
+	}
+	if kf.UnicodeEntry != nil {
+		logger.Infof("Keyboard Unicode entry for '%s'", appConfig.Keyboard.TargetOS)
+	}
+	if err = RegisterTimingProfiles(appConfig); err != nil {
+		logger.Fatal(err)
+	}
+	if appConfig.Keyboard.TimingProfile != "" {
+		if kf.Timing, err = keyboard.LookupTimingProfile(appConfig.Keyboard.TimingProfile); err != nil {
+			logger.Fatal(err)
+		}
+		logger.Infof("Keyboard timing profile '%s'", kf.Timing)
+	}
+	if kf.CharMap, err = LoadCharMap(appConfig); err != nil {
+		if errs, ok := err.(keyboard.CharMapErrors); ok {
+			for _, e := range errs {
+				logger.Error(e)
+			}
+			logger.Fatalf("%d invalid character map entries", len(errs))
+		}
+		logger.Fatal(err)
+	}
+	logger.Infof("Loaded %d character map entries", len(kf.CharMap))
 	defer kf.Close()
 
-	server.New(
+	// Only a real device sends LED reports, reading a regular file would read back the reports written to it.
+	if fi, err := f.Stat(); err == nil && fi.Mode()&os.ModeCharDevice != 0 {
+		go func() {
+			if err := kf.ReadLEDs(); err != nil {
+				logger.Error(err)
+			}
+		}()
+	}
+
+	var devices server.Devices = server.Devices{Capture: kc}
+	if appConfig.Mouse.File != "" {
+		logger.Infof("Mouse file '%s'", appConfig.Mouse.File)
+		mf, err := os.OpenFile(appConfig.Mouse.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		devices.Mouse = &mouse.File{Device: mf, StepDelay: time.Millisecond * time.Duration(appConfig.Mouse.StepDelayMs)}
+		defer devices.Mouse.Close()
+	}
+	if appConfig.Media.File != "" {
+		logger.Infof("Media keys file '%s'", appConfig.Media.File)
+		cf, err := os.OpenFile(appConfig.Media.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		devices.Media = &keyboard.ConsumerFile{File: *cf, StrokeDelay: time.Millisecond * time.Duration(appConfig.Media.StrokeDelayMs)}
+		defer devices.Media.Close()
+	}
+	if appConfig.Gamepad.File != "" {
+		logger.Infof("Gamepad file '%s'", appConfig.Gamepad.File)
+		gf, err := os.OpenFile(appConfig.Gamepad.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		devices.Gamepad = &gamepad.File{Device: gf, PressDelay: time.Millisecond * time.Duration(appConfig.Gamepad.PressDelayMs)}
+		defer devices.Gamepad.Close()
+	}
+	if appConfig.Pointer.File != "" {
+		logger.Infof("Pointer file '%s'", appConfig.Pointer.File)
+		pf, err := os.OpenFile(appConfig.Pointer.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		devices.Pointer = &pointer.File{Device: pf, TapDelay: time.Millisecond * time.Duration(appConfig.Pointer.TapDelayMs)}
+		defer devices.Pointer.Close()
+		if err := devices.Pointer.Calibrate(appConfig.Pointer.Screen); err != nil {
+			logger.Fatal(err)
+		}
+	}
+	if appConfig.SwitchPro.File != "" {
+		logger.Infof("Pro Controller file '%s'", appConfig.SwitchPro.File)
+		pf, err := os.OpenFile(appConfig.SwitchPro.File, os.O_RDWR, 0)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		devices.SwitchPro = &switchpro.Controller{File: *pf, Period: time.Millisecond * time.Duration(appConfig.SwitchPro.PeriodMs)}
+		defer devices.SwitchPro.Close()
+		go func() {
+			if err := devices.SwitchPro.Run(context.Background()); err != nil {
+				logger.Error(err)
+			}
+		}()
+	}
+
+	srv, err := server.New(
 		appConfig.Server,
 		log.New("Server", appConfig.Server.LogLevel),
 		&kf,
-	).Run()
+		devices,
+	)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	srv.Run()
 }
@@ -0,0 +1,71 @@
+// switchprocheck runs a switchpro.Controller against a switchpro.FakeHost that plays switchpro.SWITCH_HANDSHAKE, and
+// prints each exchange and how regularly the controller sends its input reports once set up. It exits 1 if the
+// controller does not answer the handshake the way a Pro Controller does.
+//
+//	go run ./cmd/switchprocheck -listen 2s -v
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/scirelli/turkey-pi/pkg/switchpro"
+)
+
+func main() {
+	var period = flag.Duration("period", switchpro.DEFAULT_PERIOD, "Time between full input reports.")
+	var listen = flag.Duration("listen", time.Second, "How long to count full input reports after the handshake.")
+	var verbose = flag.Bool("v", false, "Print the reports sent and received.")
+	flag.Parse()
+
+	device, host, err := switchpro.NewPair()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer host.Close()
+
+	var controller = switchpro.Controller{File: *device, Period: *period, MAC: [6]byte{0x98, 0xB6, 0xE9, 0x00, 0x00, 0x01}}
+	defer controller.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		if err := controller.Run(ctx); err != nil && err != context.Canceled {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}()
+
+	fakeHost := switchpro.FakeHost{File: host, Listen: *listen}
+	transcript, err := fakeHost.Run()
+
+	for _, e := range transcript.Exchanges {
+		if e.Reply == nil {
+			fmt.Printf("%-28s %10s\n", e.Step.Name, "no reply")
+		} else {
+			fmt.Printf("%-28s %10s\n", e.Step.Name, e.Latency.Round(time.Microsecond))
+		}
+		if *verbose {
+			fmt.Printf("  > % x\n", e.Step.Output[:16])
+			if e.Reply != nil {
+				fmt.Printf("  < % x\n", e.Reply[:32])
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	status := controller.Status()
+	fmt.Printf("\nplayer lights %04b, IMU %t, vibration %t, %d subcommands\n", status.PlayerLights, status.IMU, status.Vibration, status.Subcommands)
+	fmt.Printf("%d full reports, every %s on average, longest gap %s, aiming for %s\n", transcript.FullReports,
+		transcript.Period.Round(time.Microsecond), transcript.MaxGap.Round(time.Microsecond), *period)
+	if status.PlayerLights == 0 || transcript.FullReports == 0 {
+		fmt.Fprintln(os.Stderr, "the controller was not set up")
+		os.Exit(1)
+	}
+}
@@ -10,7 +10,8 @@ import (
 )
 
 const (
-	DEFAULT_PORT uint = 8282
+	DEFAULT_PORT       uint   = 8282
+	DEFAULT_MACRO_FILE string = "macros.json"
 )
 
 //Load a config file.
@@ -58,6 +59,10 @@ func Defaults(config *Config) *Config {
 		config.UiUrl = "http://localhost"
 		logger.Infof("Defaulting UiUrl to '%s'\n", config.UiUrl)
 	}
+	if config.MacroFile == "" {
+		config.MacroFile = DEFAULT_MACRO_FILE
+		logger.Infof("Defaulting macroFile to '%s'\n", config.MacroFile)
+	}
 	if config.InputBufferSize == 0 {
 		config.InputBufferSize = DEFAULT_INPUT_BUFFER_SZ
 		logger.Infof("Defaulting inputBufferSize to '%d'\n", config.InputBufferSize)
@@ -77,4 +82,5 @@ type Config struct {
 	Debug           bool         `json:"-"`
 	LogLevel        log.LogLevel `json:"-"`
 	InputBufferSize uint         `json:"inputBufferSize"`
+	MacroFile       string       `json:"macroFile"`
 }
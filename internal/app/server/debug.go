@@ -0,0 +1,57 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+/*
+Debug routes. The capture routes are only available when keyboard.capture.dir is configured. A capture mirrors every
+report written to the keyboard into pcapng files in that directory, open them with Wireshark.
+
+	GET  /debug/capture
+	POST /debug/capture/start
+	POST /debug/capture/stop
+*/
+func (s *Server) registerDebugRoutes(router *mux.Router) *mux.Router {
+	router.Path("/capture").Methods("GET").HandlerFunc(s.getCaptureHandlerFunc).Name("getCapture")
+	router.Path("/capture/start").Methods("POST").HandlerFunc(s.startCaptureHandlerFunc).Name("startCapture")
+	router.Path("/capture/stop").Methods("POST").HandlerFunc(s.stopCaptureHandlerFunc).Name("stopCapture")
+
+	return router
+}
+
+func (s *Server) getCaptureHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	if s.devices.Capture == nil {
+		respondError(w, http.StatusServiceUnavailable, "No capture directory configured.")
+		return
+	}
+	respondJSON(w, http.StatusOK, s.devices.Capture.Status())
+}
+
+func (s *Server) startCaptureHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	if s.devices.Capture == nil {
+		respondError(w, http.StatusServiceUnavailable, "No capture directory configured.")
+		return
+	}
+	if err := s.devices.Capture.Start(); err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		s.logger.Error(err)
+		return
+	}
+	respondJSON(w, http.StatusOK, s.devices.Capture.Status())
+}
+
+func (s *Server) stopCaptureHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	if s.devices.Capture == nil {
+		respondError(w, http.StatusServiceUnavailable, "No capture directory configured.")
+		return
+	}
+	if err := s.devices.Capture.Stop(); err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		s.logger.Error(err)
+		return
+	}
+	respondJSON(w, http.StatusOK, s.devices.Capture.Status())
+}
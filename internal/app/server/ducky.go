@@ -0,0 +1,46 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gorilla/handlers"
+	"github.com/gorilla/mux"
+
+	"github.com/scirelli/turkey-pi/pkg/ducky"
+)
+
+func (s *Server) registerDuckyRoutes(router *mux.Router) *mux.Router {
+	router.Path("/ducky").Methods("POST").Handler(handlers.ContentTypeHandler(http.HandlerFunc(s.runDuckyScriptHandlerFunc), "text/plain")).Name("runDuckyScript")
+
+	return router
+}
+
+// runDuckyScriptHandlerFunc queues a DuckyScript payload as a job. The whole script is parsed before anything is typed.
+func (s *Server) runDuckyScriptHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	opts, err := writeOptions(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		s.logger.Error(err)
+		return
+	}
+
+	script, err := ducky.Parse(r.Body)
+	if err != nil {
+		if _, ok := err.(*ducky.ParseError); ok {
+			respondError(w, http.StatusUnprocessableEntity, err.Error())
+		} else {
+			respondError(w, 503, "Failed to read input.")
+		}
+		s.logger.Error(err)
+		return
+	}
+
+	status := s.jobs.add("ducky", 0, func(ctx context.Context, progress func(int)) error {
+		return script.RunContext(ctx, s.keyboardFile, opts)
+	})
+
+	s.respondJobAccepted(w, status, "Script recieved and is being typed out")
+}
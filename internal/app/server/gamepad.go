@@ -0,0 +1,183 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/handlers"
+	"github.com/gorilla/mux"
+
+	"github.com/scirelli/turkey-pi/pkg/gamepad"
+)
+
+/*
+Gamepad routes, only available when a gamepad file is configured. Held buttons, the hat and the axes keep their
+state between requests until changed or released. Sticks go from -127 to 127, triggers from 0 to 255.
+
+	GET  /gamepad
+	POST /gamepad/press        {"buttons": ["a"], "count": 2}
+	POST /gamepad/hold         {"buttons": ["lb", "rb"]}
+	POST /gamepad/release      {"buttons": ["lb"]}
+	POST /gamepad/release-all
+	POST /gamepad/hat          {"direction": "down", "count": 3}   taps, or holds with "hold": true
+	POST /gamepad/axes         {"axes": {"lx": -127, "rt": 255}}
+*/
+func (s *Server) registerGamepadRoutes(router *mux.Router) *mux.Router {
+	router.Path("").Methods("GET").HandlerFunc(s.getGamepadHandlerFunc).Name("getGamepad")
+	router.Path("/press").Methods("POST").Handler(handlers.ContentTypeHandler(http.HandlerFunc(s.pressGamepadHandlerFunc), "application/json")).Name("pressGamepad")
+	router.Path("/hold").Methods("POST").Handler(handlers.ContentTypeHandler(http.HandlerFunc(s.holdGamepadHandlerFunc), "application/json")).Name("holdGamepad")
+	router.Path("/release").Methods("POST").Handler(handlers.ContentTypeHandler(http.HandlerFunc(s.releaseGamepadHandlerFunc), "application/json")).Name("releaseGamepad")
+	router.Path("/release-all").Methods("POST").HandlerFunc(s.releaseAllGamepadHandlerFunc).Name("releaseAllGamepad")
+	router.Path("/hat").Methods("POST").Handler(handlers.ContentTypeHandler(http.HandlerFunc(s.hatGamepadHandlerFunc), "application/json")).Name("hatGamepad")
+	router.Path("/axes").Methods("POST").Handler(handlers.ContentTypeHandler(http.HandlerFunc(s.axesGamepadHandlerFunc), "application/json")).Name("axesGamepad")
+
+	return router
+}
+
+type gamepadRequest struct {
+	Buttons   []string       `json:"buttons"`
+	Count     int            `json:"count"`
+	Direction string         `json:"direction"`
+	Hold      bool           `json:"hold"`
+	Axes      map[string]int `json:"axes"`
+}
+
+// readGamepadRequest decodes the body and looks up the buttons if they are needed. It responds itself when it fails.
+func (s *Server) readGamepadRequest(w http.ResponseWriter, r *http.Request, needButtons bool) (req gamepadRequest, buttons gamepad.Button, ok bool) {
+	defer r.Body.Close()
+
+	if s.devices.Gamepad == nil {
+		respondError(w, http.StatusServiceUnavailable, "No gamepad configured.")
+		return req, buttons, false
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusUnprocessableEntity, err.Error())
+		s.logger.Error(err)
+		return req, buttons, false
+	}
+	if !needButtons {
+		return req, buttons, true
+	}
+	if len(req.Buttons) == 0 {
+		respondError(w, http.StatusUnprocessableEntity, "Field 'buttons' is required")
+		return req, buttons, false
+	}
+	for _, name := range req.Buttons {
+		b, err := gamepad.ButtonByName(name)
+		if err != nil {
+			respondError(w, http.StatusUnprocessableEntity, err.Error())
+			return req, buttons, false
+		}
+		buttons |= b
+	}
+	if req.Count == 0 {
+		req.Count = 1
+	}
+	return req, buttons, true
+}
+
+func (s *Server) getGamepadHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	if s.devices.Gamepad == nil {
+		respondError(w, http.StatusServiceUnavailable, "No gamepad configured.")
+		return
+	}
+	s.respondGamepad(w, nil)
+}
+
+func (s *Server) pressGamepadHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	req, buttons, ok := s.readGamepadRequest(w, r, true)
+	if !ok {
+		return
+	}
+	s.respondGamepad(w, s.devices.Gamepad.Press(buttons, req.Count))
+}
+
+func (s *Server) holdGamepadHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	_, buttons, ok := s.readGamepadRequest(w, r, true)
+	if !ok {
+		return
+	}
+	s.respondGamepad(w, s.devices.Gamepad.Hold(buttons))
+}
+
+func (s *Server) releaseGamepadHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	_, buttons, ok := s.readGamepadRequest(w, r, true)
+	if !ok {
+		return
+	}
+	s.respondGamepad(w, s.devices.Gamepad.Release(buttons))
+}
+
+func (s *Server) releaseAllGamepadHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	if s.devices.Gamepad == nil {
+		respondError(w, http.StatusServiceUnavailable, "No gamepad configured.")
+		return
+	}
+	s.respondGamepad(w, s.devices.Gamepad.ReleaseAll())
+}
+
+func (s *Server) hatGamepadHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	req, _, ok := s.readGamepadRequest(w, r, false)
+	if !ok {
+		return
+	}
+	hat, err := gamepad.HatByName(req.Direction)
+	if err != nil {
+		respondError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	if req.Hold || hat == gamepad.HAT_CENTER {
+		s.respondGamepad(w, s.devices.Gamepad.SetHat(hat))
+		return
+	}
+	if req.Count == 0 {
+		req.Count = 1
+	}
+	s.respondGamepad(w, s.devices.Gamepad.TapHat(hat, req.Count))
+}
+
+func (s *Server) axesGamepadHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	req, _, ok := s.readGamepadRequest(w, r, false)
+	if !ok {
+		return
+	}
+	if len(req.Axes) == 0 {
+		respondError(w, http.StatusUnprocessableEntity, "Field 'axes' is required")
+		return
+	}
+
+	var values map[gamepad.Axis]int = make(map[gamepad.Axis]int, len(req.Axes))
+	for name, v := range req.Axes {
+		axis, err := gamepad.AxisByName(name)
+		if err != nil {
+			respondError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		values[axis] = v
+	}
+	s.respondGamepad(w, s.devices.Gamepad.SetAxes(values))
+}
+
+// respondGamepad the state of the gamepad after a request, or 502 if err is set.
+func (s *Server) respondGamepad(w http.ResponseWriter, err error) {
+	if err != nil {
+		respondError(w, 502, "Failed to write to the gamepad.")
+		s.logger.Error(err)
+		return
+	}
+
+	state := s.devices.Gamepad.State()
+	var axes map[string]int = make(map[string]int, gamepad.AXIS_COUNT)
+	for a := gamepad.Axis(0); int(a) < gamepad.AXIS_COUNT; a++ {
+		axes[a.String()] = state.Axes[a]
+	}
+	respondJSON(w, http.StatusOK, struct {
+		Buttons []string       `json:"buttons"`
+		Hat     string         `json:"hat"`
+		Axes    map[string]int `json:"axes"`
+	}{
+		Buttons: gamepad.ButtonNames(state.Buttons),
+		Hat:     state.Hat.String(),
+		Axes:    axes,
+	})
+}
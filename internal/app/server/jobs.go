@@ -0,0 +1,309 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/scirelli/turkey-pi/pkg/log"
+)
+
+/*
+Job routes
+
+	Everything that types on the keyboard is queued as a job and run one at a time, so two requests never interleave
+	their reports. Submitting work answers 202 Accepted with the job and a Location header pointing at it.
+
+	GET    /jobs       queued, running and recently finished jobs, oldest first
+	GET    /jobs/{id}  a job's state and progress
+	DELETE /jobs/{id}  cancel a queued or running job
+*/
+func (s *Server) registerJobRoutes(router *mux.Router) *mux.Router {
+	router.Path("").Methods("GET").HandlerFunc(s.listJobsHandlerFunc).Name("listJobs")
+	router.Path("/{id}").Methods("GET").HandlerFunc(s.getJobHandlerFunc).Name("getJob")
+	router.Path("/{id}").Methods("DELETE").HandlerFunc(s.cancelJobHandlerFunc).Name("cancelJob")
+
+	return router
+}
+
+type JobState string
+
+const (
+	JOB_QUEUED    JobState = "queued"
+	JOB_RUNNING   JobState = "running"
+	JOB_DONE      JobState = "done"
+	JOB_CANCELLED JobState = "cancelled"
+	JOB_FAILED    JobState = "failed"
+)
+
+// MAX_FINISHED_JOBS how many finished jobs are kept for GET /jobs, the oldest are dropped first.
+const MAX_FINISHED_JOBS int = 100
+
+// JobStatus what the job routes report about a job. Typed and Total count characters and are only set for text.
+type JobStatus struct {
+	ID       string     `json:"id"`
+	Kind     string     `json:"kind"`
+	State    JobState   `json:"state"`
+	Typed    int        `json:"typed"`
+	Total    int        `json:"total"`
+	Error    string     `json:"error,omitempty"`
+	Created  time.Time  `json:"created"`
+	Started  *time.Time `json:"started,omitempty"`
+	Finished *time.Time `json:"finished,omitempty"`
+}
+
+func (j JobStatus) finished() bool {
+	return j.State == JOB_DONE || j.State == JOB_CANCELLED || j.State == JOB_FAILED
+}
+
+// jobFunc does the work of a job. It must stop when ctx is done and report characters typed with progress.
+type jobFunc func(ctx context.Context, progress func(typed int)) error
+
+type job struct {
+	status JobStatus
+	run    jobFunc
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// jobQueue runs jobs one at a time in the order they were added.
+type jobQueue struct {
+	logger log.Logger
+
+	mu     sync.Mutex
+	nextID uint64
+	jobs   []*job
+	wake   chan struct{}
+	direct int // direct writes in progress, see exclusive
+}
+
+func newJobQueue(logger log.Logger) *jobQueue {
+	var q = jobQueue{
+		logger: logger,
+		wake:   make(chan struct{}, 1),
+	}
+
+	go q.work()
+	return &q
+}
+
+// add queues run and returns its status.
+func (q *jobQueue) add(kind string, total int, run jobFunc) JobStatus {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.nextID++
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &job{
+		status: JobStatus{
+			ID:      strconv.FormatUint(q.nextID, 10),
+			Kind:    kind,
+			State:   JOB_QUEUED,
+			Total:   total,
+			Created: time.Now(),
+		},
+		run:    run,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	q.jobs = append(q.jobs, j)
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return j.status
+}
+
+func (q *jobQueue) get(id string) (JobStatus, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if j := q.find(id); j != nil {
+		return j.status, true
+	}
+	return JobStatus{}, false
+}
+
+func (q *jobQueue) list() []JobStatus {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var statuses []JobStatus = make([]JobStatus, len(q.jobs))
+	for i, j := range q.jobs {
+		statuses[i] = j.status
+	}
+	return statuses
+}
+
+var errJobFinished = errors.New("job has already finished")
+
+// cancel stops a running job or drops a queued one. ok is false if there is no such job.
+func (q *jobQueue) cancel(id string) (status JobStatus, ok bool, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	j := q.find(id)
+	if j == nil {
+		return JobStatus{}, false, nil
+	}
+	if j.status.finished() {
+		return j.status, true, errJobFinished
+	}
+	j.cancel()
+	if j.status.State == JOB_QUEUED {
+		q.finish(j, JOB_CANCELLED, nil)
+	}
+	return j.status, true, nil
+}
+
+// find the job with id, q.mu must be held.
+func (q *jobQueue) find(id string) *job {
+	for _, j := range q.jobs {
+		if j.status.ID == id {
+			return j
+		}
+	}
+	return nil
+}
+
+var errJobsPending = errors.New("a job is queued or typing, wait for it to finish or cancel it")
+
+// exclusive runs fn outside the queue, for writes that must answer straight away. It fails with errJobsPending
+// without calling fn if a job is queued or running, and no job starts until fn returns.
+func (q *jobQueue) exclusive(fn func() error) error {
+	q.mu.Lock()
+	for _, j := range q.jobs {
+		if !j.status.finished() {
+			q.mu.Unlock()
+			return errJobsPending
+		}
+	}
+	q.direct++
+	q.mu.Unlock()
+
+	defer func() {
+		q.mu.Lock()
+		q.direct--
+		q.mu.Unlock()
+		select {
+		case q.wake <- struct{}{}:
+		default:
+		}
+	}()
+	return fn()
+}
+
+// next the oldest queued job, marked as running. It blocks until there is one and no exclusive write is running.
+func (q *jobQueue) next() *job {
+	for {
+		q.mu.Lock()
+		for _, j := range q.jobs {
+			if j.status.State == JOB_QUEUED && q.direct == 0 {
+				now := time.Now()
+				j.status.State = JOB_RUNNING
+				j.status.Started = &now
+				q.mu.Unlock()
+				return j
+			}
+		}
+		q.mu.Unlock()
+		<-q.wake
+	}
+}
+
+func (q *jobQueue) work() {
+	for {
+		j := q.next()
+		err := j.run(j.ctx, func(typed int) {
+			q.mu.Lock()
+			j.status.Typed = typed
+			q.mu.Unlock()
+		})
+
+		q.mu.Lock()
+		switch {
+		case j.ctx.Err() != nil:
+			q.finish(j, JOB_CANCELLED, nil)
+			q.logger.Infof("Job %s cancelled after %d of %d char", j.status.ID, j.status.Typed, j.status.Total)
+		case err != nil:
+			q.finish(j, JOB_FAILED, err)
+			q.logger.Errorf("Job %s failed: %s", j.status.ID, err)
+		default:
+			q.finish(j, JOB_DONE, nil)
+		}
+		j.cancel()
+		q.mu.Unlock()
+	}
+}
+
+// finish records how j ended and drops the oldest finished jobs past MAX_FINISHED_JOBS, q.mu must be held.
+func (q *jobQueue) finish(j *job, state JobState, err error) {
+	var now = time.Now()
+	var finished int
+
+	j.status.State = state
+	j.status.Finished = &now
+	if err != nil {
+		j.status.Error = err.Error()
+	}
+
+	for i := len(q.jobs) - 1; i >= 0; i-- {
+		if !q.jobs[i].status.finished() {
+			continue
+		}
+		if finished++; finished > MAX_FINISHED_JOBS {
+			q.jobs = append(q.jobs[:i], q.jobs[i+1:]...)
+		}
+	}
+}
+
+// respondJobAccepted answers a request that queued a job.
+func (s *Server) respondJobAccepted(w http.ResponseWriter, status JobStatus, msg string) {
+	w.Header().Set("Location", "/jobs/"+status.ID)
+	respondJSON(w, http.StatusAccepted, struct {
+		Msg string    `json:"Msg"`
+		Job JobStatus `json:"job"`
+	}{
+		Msg: msg,
+		Job: status,
+	})
+}
+
+func (s *Server) listJobsHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, struct {
+		Jobs []JobStatus `json:"jobs"`
+	}{
+		Jobs: s.jobs.list(),
+	})
+}
+
+func (s *Server) getJobHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	status, ok := s.jobs.get(id)
+	if !ok {
+		respondError(w, http.StatusNotFound, fmt.Sprintf("No job '%s'", id))
+		return
+	}
+	respondJSON(w, http.StatusOK, status)
+}
+
+func (s *Server) cancelJobHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	status, ok, err := s.jobs.cancel(id)
+	if !ok {
+		respondError(w, http.StatusNotFound, fmt.Sprintf("No job '%s'", id))
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusConflict, fmt.Sprintf("Job '%s' is %s", id, status.State))
+		return
+	}
+	respondJSON(w, http.StatusOK, status)
+}
@@ -0,0 +1,212 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/scirelli/turkey-pi/pkg/log"
+)
+
+func testJobQueue() *jobQueue {
+	return newJobQueue(log.New("TEST", log.Fatal))
+}
+
+// waitJob waits for the job with id to reach state.
+func waitJob(t *testing.T, q *jobQueue, id string, state JobState) JobStatus {
+	t.Helper()
+	var deadline = time.Now().Add(5 * time.Second)
+
+	for {
+		status, ok := q.get(id)
+		if !ok {
+			t.Fatalf("no job %s", id)
+		}
+		if status.State == state {
+			return status
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s is %s, want %s", id, status.State, state)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// blockingJob a job that runs until gate is closed or it is cancelled.
+func blockingJob(gate chan struct{}) jobFunc {
+	return func(ctx context.Context, progress func(int)) error {
+		select {
+		case <-gate:
+		case <-ctx.Done():
+		}
+		return nil
+	}
+}
+
+func TestJobsRunInOrder(t *testing.T) {
+	var q = testJobQueue()
+	var gate = make(chan struct{})
+	var ran = make(chan string, 3)
+
+	first := q.add("text", 0, func(ctx context.Context, progress func(int)) error {
+		ran <- "first"
+		<-gate
+		return nil
+	})
+	second := q.add("text", 0, func(ctx context.Context, progress func(int)) error {
+		ran <- "second"
+		return nil
+	})
+	third := q.add("ducky", 0, func(ctx context.Context, progress func(int)) error {
+		ran <- "third"
+		return nil
+	})
+
+	waitJob(t, q, first.ID, JOB_RUNNING)
+	for _, id := range []string{second.ID, third.ID} {
+		if status, _ := q.get(id); status.State != JOB_QUEUED {
+			t.Errorf("job %s is %s while the first runs, want queued", id, status.State)
+		}
+	}
+	close(gate)
+	status := waitJob(t, q, third.ID, JOB_DONE)
+	if status.Started == nil || status.Finished == nil || status.Finished.Before(*status.Started) {
+		t.Errorf("done job started %v and finished %v", status.Started, status.Finished)
+	}
+
+	for _, want := range []string{"first", "second", "third"} {
+		if got := <-ran; got != want {
+			t.Errorf("%s ran, want %s", got, want)
+		}
+	}
+	var ids []string
+	for _, status := range q.list() {
+		ids = append(ids, status.ID)
+	}
+	if len(ids) != 3 || ids[0] != first.ID || ids[2] != third.ID {
+		t.Errorf("listed %v, want the jobs oldest first", ids)
+	}
+}
+
+func TestJobProgressAndFailure(t *testing.T) {
+	var q = testJobQueue()
+
+	status := q.add("text", 5, func(ctx context.Context, progress func(int)) error {
+		progress(3)
+		return errors.New("device went away")
+	})
+	status = waitJob(t, q, status.ID, JOB_FAILED)
+	if status.Typed != 3 || status.Total != 5 || status.Error != "device went away" {
+		t.Errorf("failed job is %+v", status)
+	}
+}
+
+func TestJobCancel(t *testing.T) {
+	var q = testJobQueue()
+	var gate = make(chan struct{})
+	defer close(gate)
+	var queuedRan = make(chan struct{}, 1)
+
+	running := q.add("text", 0, blockingJob(gate))
+	queued := q.add("text", 0, func(ctx context.Context, progress func(int)) error {
+		queuedRan <- struct{}{}
+		return nil
+	})
+	waitJob(t, q, running.ID, JOB_RUNNING)
+
+	status, ok, err := q.cancel(queued.ID)
+	if !ok || err != nil || status.State != JOB_CANCELLED {
+		t.Errorf("cancelling a queued job returned %+v, %v, %v", status, ok, err)
+	}
+	if _, ok, err = q.cancel(running.ID); !ok || err != nil {
+		t.Errorf("cancelling the running job returned %v, %v", ok, err)
+	}
+	waitJob(t, q, running.ID, JOB_CANCELLED)
+	if _, _, err = q.cancel(running.ID); err != errJobFinished {
+		t.Errorf("cancelling a cancelled job returned %v, want errJobFinished", err)
+	}
+	if _, ok, _ = q.cancel("nope"); ok {
+		t.Error("cancelled a job that does not exist")
+	}
+
+	// A later job runs once the cancelled ones are out of the way, the dropped one never does.
+	after := q.add("text", 0, func(ctx context.Context, progress func(int)) error { return nil })
+	waitJob(t, q, after.ID, JOB_DONE)
+	select {
+	case <-queuedRan:
+		t.Error("the cancelled queued job ran")
+	default:
+	}
+}
+
+func TestExclusive(t *testing.T) {
+	var q = testJobQueue()
+	var gate = make(chan struct{})
+
+	called := false
+	if err := q.exclusive(func() error { called = true; return nil }); err != nil || !called {
+		t.Fatalf("exclusive on an idle queue returned %v, called %v", err, called)
+	}
+
+	running := q.add("text", 0, blockingJob(gate))
+	waitJob(t, q, running.ID, JOB_RUNNING)
+	called = false
+	if err := q.exclusive(func() error { called = true; return nil }); err != errJobsPending || called {
+		t.Errorf("exclusive with a running job returned %v, called %v", err, called)
+	}
+	close(gate)
+	waitJob(t, q, running.ID, JOB_DONE)
+
+	// A job added during an exclusive write waits for it.
+	var queued JobStatus
+	err := q.exclusive(func() error {
+		queued = q.add("text", 0, func(ctx context.Context, progress func(int)) error { return nil })
+		time.Sleep(20 * time.Millisecond)
+		if status, _ := q.get(queued.ID); status.State != JOB_QUEUED {
+			t.Errorf("job is %s during an exclusive write, want queued", status.State)
+		}
+		return errors.New("write failed")
+	})
+	if err == nil || err.Error() != "write failed" {
+		t.Errorf("exclusive returned %v, want the write's error", err)
+	}
+	waitJob(t, q, queued.ID, JOB_DONE)
+}
+
+// Only the newest MAX_FINISHED_JOBS finished jobs are kept, unfinished ones are never dropped.
+func TestFinishedJobsTrimmed(t *testing.T) {
+	const extra = 5
+	var q = testJobQueue()
+	var gate = make(chan struct{})
+	defer close(gate)
+
+	var last JobStatus
+	for i := 0; i < MAX_FINISHED_JOBS+extra; i++ {
+		last = q.add("text", 0, func(ctx context.Context, progress func(int)) error { return nil })
+	}
+	waitJob(t, q, last.ID, JOB_DONE)
+
+	jobs := q.list()
+	if len(jobs) != MAX_FINISHED_JOBS {
+		t.Fatalf("%d jobs kept, want %d", len(jobs), MAX_FINISHED_JOBS)
+	}
+	if jobs[0].ID != strconv.Itoa(extra+1) {
+		t.Errorf("oldest job kept is %s, want %d", jobs[0].ID, extra+1)
+	}
+	if _, ok := q.get("1"); ok {
+		t.Error("the oldest finished job is still listed")
+	}
+
+	running := q.add("text", 0, blockingJob(gate))
+	queued := q.add("text", 0, func(ctx context.Context, progress func(int)) error { return nil })
+	waitJob(t, q, running.ID, JOB_RUNNING)
+	if _, _, err := q.cancel(queued.ID); err != nil {
+		t.Fatal(err)
+	}
+	jobs = q.list()
+	if len(jobs) != MAX_FINISHED_JOBS+1 || jobs[len(jobs)-2].ID != running.ID {
+		t.Errorf("%d jobs kept after a cancel, want %d finished and the running one", len(jobs), MAX_FINISHED_JOBS)
+	}
+}
@@ -0,0 +1,157 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/handlers"
+	"github.com/gorilla/mux"
+
+	"github.com/scirelli/turkey-pi/pkg/keyboard"
+	"github.com/scirelli/turkey-pi/pkg/macro"
+)
+
+/*
+Live key routes, keys stay down between requests until released. Events are recorded while a macro recording is in progress.
+They answer 409 Conflict while a job is queued or typing, the reports of the two would interleave.
+
+	POST /keys/press        {"key": "LEFT_SHIFT"}
+	POST /keys/release      {"key": "LEFT_SHIFT"}
+	POST /keys/tap          {"key": "ENTER"}
+	POST /keys/release-all
+*/
+func (s *Server) registerKeyRoutes(router *mux.Router) *mux.Router {
+	router.Path("/press").Methods("POST").Handler(handlers.ContentTypeHandler(http.HandlerFunc(s.pressKeyHandlerFunc), "application/json")).Name("pressKey")
+	router.Path("/release").Methods("POST").Handler(handlers.ContentTypeHandler(http.HandlerFunc(s.releaseKeyHandlerFunc), "application/json")).Name("releaseKey")
+	router.Path("/tap").Methods("POST").Handler(handlers.ContentTypeHandler(http.HandlerFunc(s.tapKeyHandlerFunc), "application/json")).Name("tapKey")
+	router.Path("/release-all").Methods("POST").HandlerFunc(s.releaseAllKeysHandlerFunc).Name("releaseAllKeys")
+
+	return router
+}
+
+type keyRequest struct {
+	Key string `json:"key"`
+}
+
+// readKey decodes the key named in the request body.
+func readKey(r *http.Request) (byte, error) {
+	var req keyRequest
+
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return 0, err
+	}
+	key, ok := keyboard.KeyByName(req.Key)
+	if !ok {
+		return 0, fmt.Errorf("Unknown key '%s'", req.Key)
+	}
+	return key, nil
+}
+
+func (s *Server) pressKeyHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	key, err := readKey(r)
+	if err != nil {
+		respondError(w, http.StatusUnprocessableEntity, err.Error())
+		s.logger.Error(err)
+		return
+	}
+	if err := s.jobs.exclusive(func() error { return s.keyboardFile.Press(key) }); err != nil {
+		s.respondKeyError(w, err)
+		return
+	}
+	s.recorder.Record(macro.Press, key)
+	s.respondHeld(w)
+}
+
+func (s *Server) releaseKeyHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	key, err := readKey(r)
+	if err != nil {
+		respondError(w, http.StatusUnprocessableEntity, err.Error())
+		s.logger.Error(err)
+		return
+	}
+	if err := s.jobs.exclusive(func() error { return s.keyboardFile.Release(key) }); err != nil {
+		s.respondKeyError(w, err)
+		return
+	}
+	s.recorder.Record(macro.Release, key)
+	s.respondHeld(w)
+}
+
+func (s *Server) tapKeyHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	key, err := readKey(r)
+	if err != nil {
+		respondError(w, http.StatusUnprocessableEntity, err.Error())
+		s.logger.Error(err)
+		return
+	}
+	// Tapping a held key leaves it held, a recording must not release it either.
+	var wasHeld bool
+	err = s.jobs.exclusive(func() error {
+		wasHeld = s.keyboardFile.IsHeld(key)
+		if !wasHeld {
+			s.recorder.Record(macro.Press, key)
+		}
+		return s.keyboardFile.Tap(key)
+	})
+	if err != nil {
+		s.respondKeyError(w, err)
+		return
+	}
+	if !wasHeld {
+		s.recorder.Record(macro.Release, key)
+	}
+	s.respondHeld(w)
+}
+
+func (s *Server) releaseAllKeysHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	held := s.heldKeys()
+	if err := s.jobs.exclusive(s.keyboardFile.ReleaseAll); err != nil {
+		s.respondKeyError(w, err)
+		return
+	}
+	for _, key := range held {
+		s.recorder.Record(macro.Release, key)
+	}
+	s.respondHeld(w)
+}
+
+func (s *Server) respondKeyError(w http.ResponseWriter, err error) {
+	if _, ok := err.(*keyboard.RolloverError); ok {
+		respondError(w, http.StatusConflict, err.Error())
+	} else if err == errJobsPending {
+		respondError(w, http.StatusConflict, err.Error())
+	} else {
+		respondError(w, 502, "Failed to send key.")
+	}
+	s.logger.Error(err)
+}
+
+// heldKeys the keys held down, modifiers first as KEYCODE_LEFT_CONTROL to KEYCODE_RIGHT_GUI.
+func (s *Server) heldKeys() []byte {
+	var held []byte
+
+	modifiers, keys := s.keyboardFile.Held()
+	for i := 0; i < 8; i++ {
+		if modifiers&(1<<i) != 0 {
+			held = append(held, keyboard.KEYCODE_LEFT_CONTROL+byte(i))
+		}
+	}
+	return append(held, keys...)
+}
+
+// respondHeld responds with the keys still held down.
+func (s *Server) respondHeld(w http.ResponseWriter) {
+	var names []string = []string{}
+
+	for _, key := range s.heldKeys() {
+		names = append(names, keyboard.KeyName(key))
+	}
+
+	respondJSON(w, http.StatusOK, struct {
+		Held []string `json:"held"`
+	}{
+		Held: names,
+	})
+}
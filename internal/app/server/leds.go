@@ -0,0 +1,59 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func (s *Server) registerKeyboardRoutes(router *mux.Router) *mux.Router {
+	router.Path("/leds").Methods("GET").HandlerFunc(s.getLEDsHandlerFunc).Name("getLEDs")
+	router.Path("/pace").Methods("GET").HandlerFunc(s.getPaceHandlerFunc).Name("getPace")
+
+	return router
+}
+
+// getLEDsHandlerFunc the lock key state last reported by the host. known is false until the host has sent an LED report.
+func (s *Server) getLEDsHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	state, known := s.keyboardFile.LEDs()
+
+	respondJSON(w, http.StatusOK, struct {
+		Known      bool `json:"known"`
+		NumLock    bool `json:"numLock"`
+		CapsLock   bool `json:"capsLock"`
+		ScrollLock bool `json:"scrollLock"`
+		Compose    bool `json:"compose"`
+		Kana       bool `json:"kana"`
+		Raw        byte `json:"raw"`
+	}{
+		Known:      known,
+		NumLock:    state.NumLock(),
+		CapsLock:   state.CapsLock(),
+		ScrollLock: state.ScrollLock(),
+		Compose:    state.Compose(),
+		Kana:       state.Kana(),
+		Raw:        byte(state),
+	})
+}
+
+// getPaceHandlerFunc how closely the last finished write kept to its timing.
+func (s *Server) getPaceHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	stats := s.keyboardFile.PaceStats()
+
+	respondJSON(w, http.StatusOK, struct {
+		Strokes                  int     `json:"strokes"`
+		TargetMs                 int64   `json:"targetMs"`
+		ElapsedMs                int64   `json:"elapsedMs"`
+		TargetKeystrokesPerSec   float64 `json:"targetKeystrokesPerSec"`
+		AchievedKeystrokesPerSec float64 `json:"achievedKeystrokesPerSec"`
+		MaxLateMs                float64 `json:"maxLateMs"`
+	}{
+		Strokes:                  stats.Strokes,
+		TargetMs:                 stats.Target.Milliseconds(),
+		ElapsedMs:                stats.Elapsed.Milliseconds(),
+		TargetKeystrokesPerSec:   stats.TargetRate,
+		AchievedKeystrokesPerSec: stats.AchievedRate,
+		MaxLateMs:                float64(stats.MaxLate) / float64(time.Millisecond),
+	})
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/handlers"
+	"github.com/gorilla/mux"
+
+	"github.com/scirelli/turkey-pi/pkg/macro"
+)
+
+/*
+Macro routes
+
+	GET    /macros                      names of the saved macros
+	GET    /macros/{name}               a macro as JSON
+	PUT    /macros/{name}               save a macro, e.g. one copied from another turkey-pi
+	DELETE /macros/{name}
+	POST   /macros/{name}/record/start  start recording events sent to /keys
+	POST   /macros/{name}/record/stop   stop recording and save the macro
+	POST   /macros/{name}/play?speed=2  replay the macro, speed scales the timing
+*/
+func (s *Server) registerMacroRoutes(router *mux.Router) *mux.Router {
+	router.Path("").Methods("GET").HandlerFunc(s.listMacrosHandlerFunc).Name("listMacros")
+	router.Path("/{name}").Methods("GET").HandlerFunc(s.getMacroHandlerFunc).Name("getMacro")
+	router.Path("/{name}").Methods("PUT").Handler(handlers.ContentTypeHandler(http.HandlerFunc(s.putMacroHandlerFunc), "application/json")).Name("putMacro")
+	router.Path("/{name}").Methods("DELETE").HandlerFunc(s.deleteMacroHandlerFunc).Name("deleteMacro")
+	router.Path("/{name}/record/start").Methods("POST").HandlerFunc(s.startRecordingHandlerFunc).Name("startRecording")
+	router.Path("/{name}/record/stop").Methods("POST").HandlerFunc(s.stopRecordingHandlerFunc).Name("stopRecording")
+	router.Path("/{name}/play").Methods("POST").HandlerFunc(s.playMacroHandlerFunc).Name("playMacro")
+
+	return router
+}
+
+func (s *Server) listMacrosHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, struct {
+		Macros    []string `json:"macros"`
+		Recording string   `json:"recording,omitempty"`
+	}{
+		Macros:    s.macros.Names(),
+		Recording: s.recorder.Recording(),
+	})
+}
+
+func (s *Server) getMacroHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	m, ok := s.macros.Get(name)
+	if !ok {
+		respondError(w, http.StatusNotFound, fmt.Sprintf("No macro named '%s'", name))
+		return
+	}
+	respondJSON(w, http.StatusOK, m)
+}
+
+func (s *Server) putMacroHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var m macro.Macro
+
+	if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+		respondError(w, http.StatusUnprocessableEntity, err.Error())
+		s.logger.Error(err)
+		return
+	}
+	m.Name = mux.Vars(r)["name"]
+	if err := s.macros.Save(&m); err != nil {
+		respondError(w, http.StatusUnprocessableEntity, err.Error())
+		s.logger.Error(err)
+		return
+	}
+	respondJSON(w, http.StatusOK, &m)
+}
+
+func (s *Server) deleteMacroHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	ok, err := s.macros.Delete(name)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to save macros.")
+		s.logger.Error(err)
+		return
+	}
+	if !ok {
+		respondError(w, http.StatusNotFound, fmt.Sprintf("No macro named '%s'", name))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) startRecordingHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	if err := s.recorder.Start(name); err != nil {
+		respondError(w, http.StatusConflict, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusAccepted, struct {
+		Msg string `json:"Msg"`
+	}{
+		Msg: fmt.Sprintf("Recording macro '%s', send keys to /keys", name),
+	})
+}
+
+func (s *Server) stopRecordingHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	if recording := s.recorder.Recording(); recording != name {
+		respondError(w, http.StatusConflict, fmt.Sprintf("Macro '%s' is not being recorded", name))
+		return
+	}
+	m, err := s.recorder.Stop()
+	if err != nil {
+		respondError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err := s.macros.Save(m); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to save macro.")
+		s.logger.Error(err)
+		return
+	}
+	respondJSON(w, http.StatusOK, m)
+}
+
+func (s *Server) playMacroHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	var speed float64 = 1
+	var err error
+
+	name := mux.Vars(r)["name"]
+	m, ok := s.macros.Get(name)
+	if !ok {
+		respondError(w, http.StatusNotFound, fmt.Sprintf("No macro named '%s'", name))
+		return
+	}
+	if v := r.URL.Query().Get("speed"); v != "" {
+		if speed, err = strconv.ParseFloat(v, 64); err != nil || speed <= 0 {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid speed '%s', expected a number greater than 0", v))
+			return
+		}
+	}
+
+	status := s.jobs.add("macro", 0, func(ctx context.Context, progress func(int)) error {
+		return macro.PlayContext(ctx, s.keyboardFile, m, speed)
+	})
+
+	s.respondJobAccepted(w, status, fmt.Sprintf("Macro '%s' (%d events) is being played", name, len(m.Events)))
+}
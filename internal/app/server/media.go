@@ -0,0 +1,68 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/handlers"
+	"github.com/gorilla/mux"
+
+	"github.com/scirelli/turkey-pi/pkg/keyboard"
+)
+
+/*
+Media key routes, only available when a media file is configured. Keys are tapped in order, see
+keyboard.ConsumerUsageByName for the names.
+
+	POST /write/media  {"keys": ["volume_up", "volume_up", "play_pause"]}
+*/
+func (s *Server) registerMediaRoutes(router *mux.Router) *mux.Router {
+	router.Path("/media").Methods("POST").Handler(handlers.ContentTypeHandler(http.HandlerFunc(s.writeMediaHandlerFunc), "application/json")).Name("writeMedia")
+
+	return router
+}
+
+type mediaRequest struct {
+	Keys []string `json:"keys"`
+}
+
+func (s *Server) writeMediaHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var req mediaRequest
+
+	if s.devices.Media == nil {
+		respondError(w, http.StatusServiceUnavailable, "No media keys configured.")
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusUnprocessableEntity, err.Error())
+		s.logger.Error(err)
+		return
+	}
+	if len(req.Keys) == 0 {
+		respondError(w, http.StatusUnprocessableEntity, "Field 'keys' is required")
+		return
+	}
+
+	var usages []uint16 = make([]uint16, len(req.Keys))
+	for i, name := range req.Keys {
+		usage, err := keyboard.ConsumerUsageByName(name)
+		if err != nil {
+			respondError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		usages[i] = usage
+	}
+
+	if err := s.devices.Media.TapAll(usages...); err != nil {
+		respondError(w, 502, "Failed to write media keys.")
+		s.logger.Error(err)
+		return
+	}
+	respondJSON(w, http.StatusOK, struct {
+		Msg string `json:"Msg"`
+	}{
+		Msg: fmt.Sprintf("Sent %d media keys", len(usages)),
+	})
+}
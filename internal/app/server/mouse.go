@@ -0,0 +1,147 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/handlers"
+	"github.com/gorilla/mux"
+
+	"github.com/scirelli/turkey-pi/pkg/mouse"
+)
+
+/*
+Mouse routes, only available when a mouse file is configured. Movement is relative and in mouse counts, buttons
+stay down between requests until released.
+
+	POST /mouse/move         {"x": 100, "y": -20}
+	POST /mouse/click        {"button": "left", "count": 2}
+	POST /mouse/press        {"button": "left"}
+	POST /mouse/release      {"button": "left"}
+	POST /mouse/release-all
+	POST /mouse/drag         {"button": "left", "x": 300, "y": 0}
+	POST /mouse/scroll       {"amount": -3}
+*/
+func (s *Server) registerMouseRoutes(router *mux.Router) *mux.Router {
+	router.Path("/move").Methods("POST").Handler(handlers.ContentTypeHandler(http.HandlerFunc(s.moveMouseHandlerFunc), "application/json")).Name("moveMouse")
+	router.Path("/click").Methods("POST").Handler(handlers.ContentTypeHandler(http.HandlerFunc(s.clickMouseHandlerFunc), "application/json")).Name("clickMouse")
+	router.Path("/press").Methods("POST").Handler(handlers.ContentTypeHandler(http.HandlerFunc(s.pressMouseHandlerFunc), "application/json")).Name("pressMouse")
+	router.Path("/release").Methods("POST").Handler(handlers.ContentTypeHandler(http.HandlerFunc(s.releaseMouseHandlerFunc), "application/json")).Name("releaseMouse")
+	router.Path("/release-all").Methods("POST").HandlerFunc(s.releaseAllMouseHandlerFunc).Name("releaseAllMouse")
+	router.Path("/drag").Methods("POST").Handler(handlers.ContentTypeHandler(http.HandlerFunc(s.dragMouseHandlerFunc), "application/json")).Name("dragMouse")
+	router.Path("/scroll").Methods("POST").Handler(handlers.ContentTypeHandler(http.HandlerFunc(s.scrollMouseHandlerFunc), "application/json")).Name("scrollMouse")
+
+	return router
+}
+
+type mouseRequest struct {
+	Button string `json:"button"`
+	Count  int    `json:"count"`
+	X      int    `json:"x"`
+	Y      int    `json:"y"`
+	Amount int    `json:"amount"`
+}
+
+// readMouseRequest decodes the body and looks up the button if one is needed. It responds itself when it fails.
+func (s *Server) readMouseRequest(w http.ResponseWriter, r *http.Request, needButton bool) (req mouseRequest, button mouse.Button, ok bool) {
+	defer r.Body.Close()
+
+	if s.devices.Mouse == nil {
+		respondError(w, http.StatusServiceUnavailable, "No mouse configured.")
+		return req, button, false
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusUnprocessableEntity, err.Error())
+		s.logger.Error(err)
+		return req, button, false
+	}
+	if needButton {
+		var err error
+		if button, err = mouse.ButtonByName(req.Button); err != nil {
+			respondError(w, http.StatusUnprocessableEntity, err.Error())
+			return req, button, false
+		}
+	}
+	return req, button, true
+}
+
+func (s *Server) moveMouseHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	req, _, ok := s.readMouseRequest(w, r, false)
+	if !ok {
+		return
+	}
+	s.respondMouse(w, s.devices.Mouse.Move(req.X, req.Y))
+}
+
+func (s *Server) clickMouseHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	req, button, ok := s.readMouseRequest(w, r, true)
+	if !ok {
+		return
+	}
+	if req.Count == 0 {
+		req.Count = 1
+	}
+	s.respondMouse(w, s.devices.Mouse.Click(button, req.Count))
+}
+
+func (s *Server) pressMouseHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	_, button, ok := s.readMouseRequest(w, r, true)
+	if !ok {
+		return
+	}
+	s.respondMouse(w, s.devices.Mouse.Press(button))
+}
+
+func (s *Server) releaseMouseHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	_, button, ok := s.readMouseRequest(w, r, true)
+	if !ok {
+		return
+	}
+	s.respondMouse(w, s.devices.Mouse.Release(button))
+}
+
+func (s *Server) releaseAllMouseHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	if s.devices.Mouse == nil {
+		respondError(w, http.StatusServiceUnavailable, "No mouse configured.")
+		return
+	}
+	s.respondMouse(w, s.devices.Mouse.ReleaseAll())
+}
+
+func (s *Server) dragMouseHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	req, button, ok := s.readMouseRequest(w, r, true)
+	if !ok {
+		return
+	}
+	s.respondMouse(w, s.devices.Mouse.Drag(button, req.X, req.Y))
+}
+
+func (s *Server) scrollMouseHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	req, _, ok := s.readMouseRequest(w, r, false)
+	if !ok {
+		return
+	}
+	s.respondMouse(w, s.devices.Mouse.Scroll(req.Amount))
+}
+
+// respondMouse the buttons held after a mouse request, or 502 if err is set.
+func (s *Server) respondMouse(w http.ResponseWriter, err error) {
+	if err != nil {
+		respondError(w, 502, "Failed to write to the mouse.")
+		s.logger.Error(err)
+		return
+	}
+
+	var held []string = []string{}
+	buttons := s.devices.Mouse.Buttons()
+	for _, name := range []string{"left", "right", "middle", "back", "forward"} {
+		if b, _ := mouse.ButtonByName(name); buttons&b != 0 {
+			held = append(held, name)
+		}
+	}
+	respondJSON(w, http.StatusOK, struct {
+		Buttons []string `json:"buttons"`
+	}{
+		Buttons: held,
+	})
+}
@@ -0,0 +1,210 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/handlers"
+	"github.com/gorilla/mux"
+
+	"github.com/scirelli/turkey-pi/pkg/mouse"
+	"github.com/scirelli/turkey-pi/pkg/pointer"
+)
+
+/*
+Absolute pointer routes, only available when a pointer file is configured. Coordinates are normalized from 0 to 1
+with 0, 0 the top left corner, or pixels with "units": "px" once the screen size is calibrated. Taps use the left
+button unless another is given. Press, release and scroll answer 409 Conflict until the pointer has been moved or
+tapped, every report carries a position and it would otherwise click in the top left corner.
+
+	GET  /pointer
+	PUT  /pointer/calibration  {"width": 1920, "height": 1080}
+	POST /pointer/move         {"x": 0.5, "y": 0.5}
+	POST /pointer/tap          {"x": 960, "y": 540, "units": "px", "button": "left", "count": 2}
+	POST /pointer/press        {"button": "left"}
+	POST /pointer/release      {"button": "left"}
+	POST /pointer/scroll       {"amount": -3}
+*/
+func (s *Server) registerPointerRoutes(router *mux.Router) *mux.Router {
+	router.Path("").Methods("GET").HandlerFunc(s.getPointerHandlerFunc).Name("getPointer")
+	router.Path("/calibration").Methods("PUT").Handler(handlers.ContentTypeHandler(http.HandlerFunc(s.calibratePointerHandlerFunc), "application/json")).Name("calibratePointer")
+	router.Path("/move").Methods("POST").Handler(handlers.ContentTypeHandler(http.HandlerFunc(s.movePointerHandlerFunc), "application/json")).Name("movePointer")
+	router.Path("/tap").Methods("POST").Handler(handlers.ContentTypeHandler(http.HandlerFunc(s.tapPointerHandlerFunc), "application/json")).Name("tapPointer")
+	router.Path("/press").Methods("POST").Handler(handlers.ContentTypeHandler(http.HandlerFunc(s.pressPointerHandlerFunc), "application/json")).Name("pressPointer")
+	router.Path("/release").Methods("POST").Handler(handlers.ContentTypeHandler(http.HandlerFunc(s.releasePointerHandlerFunc), "application/json")).Name("releasePointer")
+	router.Path("/scroll").Methods("POST").Handler(handlers.ContentTypeHandler(http.HandlerFunc(s.scrollPointerHandlerFunc), "application/json")).Name("scrollPointer")
+
+	return router
+}
+
+const (
+	UNITS_NORMALIZED string = "normalized"
+	UNITS_PIXELS     string = "px"
+)
+
+type pointerRequest struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Units  string  `json:"units"`
+	Button string  `json:"button"`
+	Count  int     `json:"count"`
+	Amount int     `json:"amount"`
+}
+
+// point the request's coordinates as a normalized point.
+func (req pointerRequest) point(c pointer.Calibration) (pointer.Point, error) {
+	switch req.Units {
+	case "", UNITS_NORMALIZED:
+		if req.X < 0 || req.X > 1 || req.Y < 0 || req.Y > 1 {
+			return pointer.Point{}, fmt.Errorf("Normalized coordinates must be between 0 and 1, got %g, %g. Use \"units\": \"px\" for pixels", req.X, req.Y)
+		}
+		return pointer.Point{X: req.X, Y: req.Y}, nil
+	case UNITS_PIXELS, "pixels":
+		if req.X != float64(int(req.X)) || req.Y != float64(int(req.Y)) {
+			return pointer.Point{}, fmt.Errorf("Pixel coordinates must be whole numbers, got %g, %g", req.X, req.Y)
+		}
+		return c.Pixel(int(req.X), int(req.Y))
+	}
+	return pointer.Point{}, fmt.Errorf("Unknown units '%s', expected %s or %s", req.Units, UNITS_NORMALIZED, UNITS_PIXELS)
+}
+
+// readPointerRequest decodes the body and looks up the button, left when none is given. It responds itself when it
+// fails.
+func (s *Server) readPointerRequest(w http.ResponseWriter, r *http.Request) (req pointerRequest, button mouse.Button, ok bool) {
+	defer r.Body.Close()
+
+	if s.devices.Pointer == nil {
+		respondError(w, http.StatusServiceUnavailable, "No pointer configured.")
+		return req, button, false
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusUnprocessableEntity, err.Error())
+		s.logger.Error(err)
+		return req, button, false
+	}
+	if req.Button == "" {
+		return req, mouse.BUTTON_LEFT, true
+	}
+	var err error
+	if button, err = mouse.ButtonByName(req.Button); err != nil {
+		respondError(w, http.StatusUnprocessableEntity, err.Error())
+		return req, button, false
+	}
+	return req, button, true
+}
+
+// readPointerPoint reads a request with coordinates. It responds itself when it fails.
+func (s *Server) readPointerPoint(w http.ResponseWriter, r *http.Request) (req pointerRequest, button mouse.Button, p pointer.Point, ok bool) {
+	if req, button, ok = s.readPointerRequest(w, r); !ok {
+		return req, button, p, false
+	}
+	var err error
+	if p, err = req.point(s.devices.Pointer.Calibration()); err != nil {
+		respondError(w, http.StatusUnprocessableEntity, err.Error())
+		return req, button, p, false
+	}
+	return req, button, p, true
+}
+
+func (s *Server) getPointerHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	if s.devices.Pointer == nil {
+		respondError(w, http.StatusServiceUnavailable, "No pointer configured.")
+		return
+	}
+	s.respondPointer(w, nil)
+}
+
+func (s *Server) calibratePointerHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var c pointer.Calibration
+
+	if s.devices.Pointer == nil {
+		respondError(w, http.StatusServiceUnavailable, "No pointer configured.")
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		respondError(w, http.StatusUnprocessableEntity, err.Error())
+		s.logger.Error(err)
+		return
+	}
+	if err := s.devices.Pointer.Calibrate(c); err != nil {
+		respondError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	s.respondPointer(w, nil)
+}
+
+func (s *Server) movePointerHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	_, _, p, ok := s.readPointerPoint(w, r)
+	if !ok {
+		return
+	}
+	s.respondPointer(w, s.devices.Pointer.Move(p))
+}
+
+func (s *Server) tapPointerHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	req, button, p, ok := s.readPointerPoint(w, r)
+	if !ok {
+		return
+	}
+	if req.Count == 0 {
+		req.Count = 1
+	}
+	s.respondPointer(w, s.devices.Pointer.Tap(p, button, req.Count))
+}
+
+func (s *Server) pressPointerHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	_, button, ok := s.readPointerRequest(w, r)
+	if !ok {
+		return
+	}
+	s.respondPointer(w, s.devices.Pointer.Press(button))
+}
+
+func (s *Server) releasePointerHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	_, button, ok := s.readPointerRequest(w, r)
+	if !ok {
+		return
+	}
+	s.respondPointer(w, s.devices.Pointer.Release(button))
+}
+
+func (s *Server) scrollPointerHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	req, _, ok := s.readPointerRequest(w, r)
+	if !ok {
+		return
+	}
+	s.respondPointer(w, s.devices.Pointer.Scroll(req.Amount))
+}
+
+// respondPointer where the pointer is, the buttons held and the calibration, 409 if it has not been put anywhere yet
+// or 502 if writing failed.
+func (s *Server) respondPointer(w http.ResponseWriter, err error) {
+	if _, ok := err.(*pointer.UnplacedError); ok {
+		respondError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		respondError(w, 502, "Failed to write to the pointer.")
+		s.logger.Error(err)
+		return
+	}
+
+	var held []string = []string{}
+	buttons := s.devices.Pointer.Buttons()
+	for _, name := range []string{"left", "right", "middle", "back", "forward"} {
+		if b, _ := mouse.ButtonByName(name); buttons&b != 0 {
+			held = append(held, name)
+		}
+	}
+	respondJSON(w, http.StatusOK, struct {
+		Position    pointer.Point       `json:"position"`
+		Buttons     []string            `json:"buttons"`
+		Calibration pointer.Calibration `json:"calibration"`
+	}{
+		Position:    s.devices.Pointer.Position(),
+		Buttons:     held,
+		Calibration: s.devices.Pointer.Calibration(),
+	})
+}
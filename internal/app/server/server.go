@@ -1,6 +1,8 @@
 package server
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -8,31 +10,61 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
+	"unicode/utf8"
 
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 
+	"github.com/scirelli/turkey-pi/pkg/gamepad"
 	"github.com/scirelli/turkey-pi/pkg/keyboard"
+	"github.com/scirelli/turkey-pi/pkg/keyboard/capture"
 	"github.com/scirelli/turkey-pi/pkg/log"
+	"github.com/scirelli/turkey-pi/pkg/macro"
+	"github.com/scirelli/turkey-pi/pkg/mouse"
+	"github.com/scirelli/turkey-pi/pkg/pointer"
+	"github.com/scirelli/turkey-pi/pkg/switchpro"
 )
 
 const (
-	DEFAULT_INPUT_BUFFER_SZ uint = 500
-	inputLogLength          uint = 20
+	DEFAULT_INPUT_BUFFER_SZ uint   = 500
+	inputLogLength          uint   = 20
+	ESCAPES_HEADER          string = "X-Key-Escapes"
 )
 
-func New(config Config, logger log.Logger, kb *keyboard.File) *Server {
+// Devices the optional gadget functions besides the keyboard, nil when not configured.
+type Devices struct {
+	Mouse     *mouse.File
+	Media     *keyboard.ConsumerFile
+	Gamepad   *gamepad.File
+	SwitchPro *switchpro.Controller
+	Pointer   *pointer.File
+	Capture   *capture.Capture // Capture the keyboard's report capture, see /debug/capture
+}
+
+// New a server for kb and devices. It fails if the macro file exists but can not be loaded, starting with no macros
+// would overwrite it on the next save.
+func New(config Config, logger log.Logger, kb *keyboard.File, devices Devices) (*Server, error) {
 	var server = Server{
 		config:        config,
 		logger:        logger,
 		keyboardFile:  kb,
+		devices:       devices,
 		inputBufferSz: config.InputBufferSize,
+		recorder:      &macro.Recorder{},
+		jobs:          newJobQueue(logger),
 	}
 
+	macros, err := macro.Load(config.MacroFile)
+	if err != nil {
+		return nil, err
+	}
+	server.macros = macros
+
 	server.addr = fmt.Sprintf("%s:%d", config.Address, config.Port)
 	server.registerHTTPHandlers()
 
-	return &server
+	return &server, nil
 }
 
 type Server struct {
@@ -40,7 +72,11 @@ type Server struct {
 	addr          string
 	config        Config
 	keyboardFile  *keyboard.File
+	devices       Devices
 	inputBufferSz uint
+	recorder      *macro.Recorder
+	macros        *macro.Store
+	jobs          *jobQueue
 }
 
 func (s *Server) Run() {
@@ -51,7 +87,19 @@ func (s *Server) Run() {
 func (s *Server) registerHTTPHandlers() {
 	r := mux.NewRouter()
 
-	s.registerStringRoutes(r.PathPrefix("/write").Subrouter())
+	writeRouter := r.PathPrefix("/write").Subrouter()
+	s.registerStringRoutes(writeRouter)
+	s.registerDuckyRoutes(writeRouter)
+	s.registerMediaRoutes(writeRouter)
+	s.registerKeyRoutes(r.PathPrefix("/keys").Subrouter())
+	s.registerKeyboardRoutes(r.PathPrefix("/keyboard").Subrouter())
+	s.registerMacroRoutes(r.PathPrefix("/macros").Subrouter())
+	s.registerJobRoutes(r.PathPrefix("/jobs").Subrouter())
+	s.registerMouseRoutes(r.PathPrefix("/mouse").Subrouter())
+	s.registerPointerRoutes(r.PathPrefix("/pointer").Subrouter())
+	s.registerGamepadRoutes(r.PathPrefix("/gamepad").Subrouter())
+	s.registerSwitchProRoutes(r.PathPrefix("/switchpro").Subrouter())
+	s.registerDebugRoutes(r.PathPrefix("/debug").Subrouter())
 
 	r.PathPrefix("/").Handler(http.FileServer(http.Dir(filepath.Join(s.config.ContentPath, "/web/static"))))
 
@@ -92,35 +140,24 @@ func (s *Server) typeLongStringContentTypeRouterHandlerFunc(w http.ResponseWrite
 
 func (s *Server) typeLongStringHandlerFunc(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
-	var err error
-	var n int
+	var body bytes.Buffer
 	var buf []byte = make([]byte, s.inputBufferSz)
-	var totalCharRead int = 0
-
-	for {
-		if n, err = r.Body.Read(buf); err == io.EOF {
-			s.logger.Debug("Reached EOF")
-			break
-		} else if err != nil {
-			respondError(w, 503, "Failed to read input.")
-			s.logger.Error(err)
-			return
-		}
 
-		totalCharRead += n
-		if _, err := s.keyboardFile.WriteStringDelayed(string(buf[:n])); err != nil {
-			respondError(w, 502, "Failed to type message.")
-			s.logger.Error(err)
-			return
-		}
-		s.logger.Debugf("Wrote '%s'...", string(buf[:min(int(inputLogLength), n)]))
+	opts, err := writeOptions(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		s.logger.Error(err)
+		return
 	}
 
-	respondJSON(w, http.StatusAccepted, struct {
-		Msg string `json: "msg"`
-	}{
-		Msg: fmt.Sprintf("Message recieved (%d char) and is being typed out", totalCharRead),
-	})
+	// The job outlives the request so the whole body is read before it is queued.
+	if _, err := io.CopyBuffer(&body, r.Body, buf); err != nil {
+		respondError(w, 503, "Failed to read input.")
+		s.logger.Error(err)
+		return
+	}
+
+	s.queueText(w, body.String(), opts)
 }
 
 func (s *Server) typeLongStringFormHandlerFunc(w http.ResponseWriter, r *http.Request) {
@@ -139,17 +176,86 @@ func (s *Server) typeLongStringFormHandlerFunc(w http.ResponseWriter, r *http.Re
 		s.logger.Error(err)
 		return
 	}
-	if _, err := s.keyboardFile.WriteStringDelayed(text); err != nil {
-		respondError(w, 502, "Failed to type message.")
+	opts, err := writeOptions(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
 		s.logger.Error(err)
 		return
 	}
 	s.logger.Debugf("Form text '%s'", text)
-	respondJSON(w, http.StatusAccepted, struct {
-		Msg string `json: "msg"`
-	}{
-		Msg: fmt.Sprintf("Message recieved (%d char) and is being typed out", len(text)),
+
+	s.queueText(w, text, opts)
+}
+
+// queueText queues a job that types text. Escapes are checked first so a bad one is reported before anything is typed.
+func (s *Server) queueText(w http.ResponseWriter, text string, opts keyboard.Options) {
+	var total int = utf8.RuneCountInString(text)
+
+	if opts.Escapes {
+		tokens, err := keyboard.ParseEscapes(text)
+		if err != nil {
+			respondError(w, http.StatusUnprocessableEntity, err.Error())
+			s.logger.Error(err)
+			return
+		}
+		total = 0
+		for _, token := range tokens {
+			total += utf8.RuneCountInString(token.Text)
+		}
+	}
+
+	status := s.jobs.add("text", total, func(ctx context.Context, progress func(int)) error {
+		opts.Progress = progress
+		_, err := s.keyboardFile.WriteStringContextWith(ctx, text, opts)
+		s.logger.Debugf("Wrote '%s'...", text[:min(int(inputLogLength), len(text))])
+		return err
 	})
+
+	s.respondJobAccepted(w, status, fmt.Sprintf("Message recieved (%d char) and is being typed out", total))
+}
+
+// writeOptions per request keyboard options taken from the query string, e.g. ?layout=de types for a host set to German.
+// The {KEY} escape syntax is enabled with ?escapes=true or the ESCAPES_HEADER header. ?locks= picks a
+// keyboard.LockStrategy for the host's lock keys and ?timing= a keyboard.TimingProfile by name. ?os= names the host's
+// operating system so runes the layout has no key for are entered by code, see keyboard.LookupUnicodeEntry.
+func writeOptions(r *http.Request) (keyboard.Options, error) {
+	var opts keyboard.Options
+	var err error
+
+	if name := r.URL.Query().Get("layout"); name != "" {
+		if opts.Layout, err = keyboard.LookupLayout(name); err != nil {
+			return opts, err
+		}
+	}
+
+	if name := r.URL.Query().Get("locks"); name != "" {
+		if opts.LockStrategy, err = keyboard.ParseLockStrategy(name); err != nil {
+			return opts, err
+		}
+	}
+
+	if name := r.URL.Query().Get("timing"); name != "" {
+		if opts.Timing, err = keyboard.LookupTimingProfile(name); err != nil {
+			return opts, err
+		}
+	}
+
+	if name := r.URL.Query().Get("os"); name != "" {
+		if opts.UnicodeEntry, err = keyboard.LookupUnicodeEntry(name); err != nil {
+			return opts, err
+		}
+	}
+
+	escapes := r.URL.Query().Get("escapes")
+	if escapes == "" {
+		escapes = r.Header.Get(ESCAPES_HEADER)
+	}
+	if escapes != "" {
+		if opts.Escapes, err = strconv.ParseBool(escapes); err != nil {
+			return opts, fmt.Errorf("Invalid escapes value '%s', expected true or false", escapes)
+		}
+	}
+	return opts, nil
 }
 
 // respondJSON makes the response with payload as json format
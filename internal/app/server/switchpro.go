@@ -0,0 +1,163 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/handlers"
+	"github.com/gorilla/mux"
+
+	"github.com/scirelli/turkey-pi/pkg/switchpro"
+)
+
+/*
+Pro Controller routes, only available when a switchPro file is configured. The Switch only takes input once it has
+set up the controller, GET /switchpro shows how far it got. Held buttons and sticks are reported until changed or
+released. Sticks go from -127 to 127, positive Y is down.
+
+	GET  /switchpro
+	POST /switchpro/press        {"buttons": ["a"], "count": 2, "holdMs": 100}
+	POST /switchpro/hold         {"buttons": ["zl"]}
+	POST /switchpro/release      {"buttons": ["zl"]}
+	POST /switchpro/release-all
+	POST /switchpro/stick        {"stick": "left", "x": 0, "y": -127}
+*/
+func (s *Server) registerSwitchProRoutes(router *mux.Router) *mux.Router {
+	router.Path("").Methods("GET").HandlerFunc(s.getSwitchProHandlerFunc).Name("getSwitchPro")
+	router.Path("/press").Methods("POST").Handler(handlers.ContentTypeHandler(http.HandlerFunc(s.pressSwitchProHandlerFunc), "application/json")).Name("pressSwitchPro")
+	router.Path("/hold").Methods("POST").Handler(handlers.ContentTypeHandler(http.HandlerFunc(s.holdSwitchProHandlerFunc), "application/json")).Name("holdSwitchPro")
+	router.Path("/release").Methods("POST").Handler(handlers.ContentTypeHandler(http.HandlerFunc(s.releaseSwitchProHandlerFunc), "application/json")).Name("releaseSwitchPro")
+	router.Path("/release-all").Methods("POST").HandlerFunc(s.releaseAllSwitchProHandlerFunc).Name("releaseAllSwitchPro")
+	router.Path("/stick").Methods("POST").Handler(handlers.ContentTypeHandler(http.HandlerFunc(s.stickSwitchProHandlerFunc), "application/json")).Name("stickSwitchPro")
+
+	return router
+}
+
+type switchProRequest struct {
+	Buttons []string `json:"buttons"`
+	Count   int      `json:"count"`
+	HoldMs  int      `json:"holdMs"`
+	Stick   string   `json:"stick"`
+	X       int      `json:"x"`
+	Y       int      `json:"y"`
+}
+
+// readSwitchProRequest decodes the body and looks up the buttons if they are needed. It responds itself when it fails.
+func (s *Server) readSwitchProRequest(w http.ResponseWriter, r *http.Request, needButtons bool) (req switchProRequest, buttons switchpro.Button, ok bool) {
+	defer r.Body.Close()
+
+	if s.devices.SwitchPro == nil {
+		respondError(w, http.StatusServiceUnavailable, "No Pro Controller configured.")
+		return req, buttons, false
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusUnprocessableEntity, err.Error())
+		s.logger.Error(err)
+		return req, buttons, false
+	}
+	if !needButtons {
+		return req, buttons, true
+	}
+	if len(req.Buttons) == 0 {
+		respondError(w, http.StatusUnprocessableEntity, "Field 'buttons' is required")
+		return req, buttons, false
+	}
+	for _, name := range req.Buttons {
+		b, err := switchpro.ButtonByName(name)
+		if err != nil {
+			respondError(w, http.StatusUnprocessableEntity, err.Error())
+			return req, buttons, false
+		}
+		buttons |= b
+	}
+	return req, buttons, true
+}
+
+func (s *Server) getSwitchProHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	if s.devices.SwitchPro == nil {
+		respondError(w, http.StatusServiceUnavailable, "No Pro Controller configured.")
+		return
+	}
+	s.respondSwitchPro(w, nil)
+}
+
+func (s *Server) pressSwitchProHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	req, buttons, ok := s.readSwitchProRequest(w, r, true)
+	if !ok {
+		return
+	}
+	if req.Count == 0 {
+		req.Count = 1
+	}
+	var hold time.Duration = switchpro.DEFAULT_HOLD
+	if req.HoldMs > 0 {
+		hold = time.Duration(req.HoldMs) * time.Millisecond
+	}
+	s.respondSwitchPro(w, s.devices.SwitchPro.Press(r.Context(), buttons, req.Count, hold))
+}
+
+func (s *Server) holdSwitchProHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	_, buttons, ok := s.readSwitchProRequest(w, r, true)
+	if !ok {
+		return
+	}
+	s.devices.SwitchPro.Hold(buttons)
+	s.respondSwitchPro(w, nil)
+}
+
+func (s *Server) releaseSwitchProHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	_, buttons, ok := s.readSwitchProRequest(w, r, true)
+	if !ok {
+		return
+	}
+	s.devices.SwitchPro.Release(buttons)
+	s.respondSwitchPro(w, nil)
+}
+
+func (s *Server) releaseAllSwitchProHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	if s.devices.SwitchPro == nil {
+		respondError(w, http.StatusServiceUnavailable, "No Pro Controller configured.")
+		return
+	}
+	s.devices.SwitchPro.ReleaseAll()
+	s.respondSwitchPro(w, nil)
+}
+
+func (s *Server) stickSwitchProHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	req, _, ok := s.readSwitchProRequest(w, r, false)
+	if !ok {
+		return
+	}
+	stick, err := switchpro.StickByName(req.Stick)
+	if err != nil {
+		respondError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	s.devices.SwitchPro.SetStick(stick, switchpro.StickPosition{X: req.X, Y: req.Y})
+	s.respondSwitchPro(w, nil)
+}
+
+// respondSwitchPro the controller's state and how far the host has set it up. Only a press can fail, when the request
+// is cancelled before it is done.
+func (s *Server) respondSwitchPro(w http.ResponseWriter, err error) {
+	if err != nil {
+		respondError(w, http.StatusServiceUnavailable, "Press was cancelled.")
+		s.logger.Error(err)
+		return
+	}
+
+	state := s.devices.SwitchPro.State()
+	respondJSON(w, http.StatusOK, struct {
+		Buttons []string                           `json:"buttons"`
+		Sticks  map[string]switchpro.StickPosition `json:"sticks"`
+		Status  switchpro.Status                   `json:"status"`
+	}{
+		Buttons: switchpro.ButtonNames(state.Buttons),
+		Sticks: map[string]switchpro.StickPosition{
+			switchpro.STICK_LEFT.String():  state.Sticks[switchpro.STICK_LEFT],
+			switchpro.STICK_RIGHT.String(): state.Sticks[switchpro.STICK_RIGHT],
+		},
+		Status: s.devices.SwitchPro.Status(),
+	})
+}
@@ -0,0 +1,180 @@
+// Package ducky runs DuckyScript 1.0 payloads on a keyboard.File.
+//
+// Supported commands: REM, STRING, STRINGLN, DELAY, DEFAULT_DELAY (DEFAULTDELAY), REPEAT and key combinations such as
+// "GUI r", "CTRL-ALT DELETE" or "ENTER".
+// https://docs.hak5.org/hak5-usb-rubber-ducky/duckyscript-tm-quick-reference
+package ducky
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/scirelli/turkey-pi/pkg/keyboard"
+)
+
+type commandKind int
+
+const (
+	cmdString commandKind = iota
+	cmdDelay
+	cmdDefaultDelay
+	cmdRepeat
+	cmdKeys
+)
+
+type command struct {
+	kind commandKind
+	line int
+
+	text      string        //text cmdString
+	delay     time.Duration //delay cmdDelay and cmdDefaultDelay
+	count     int           //count cmdRepeat
+	modifiers byte          //modifiers cmdKeys
+	keys      []byte        //keys cmdKeys
+	chars     []rune        //chars cmdKeys, single characters resolved through the layout when the script runs.
+}
+
+// Script a parsed DuckyScript payload.
+type Script struct {
+	commands []command
+}
+
+// Parse reads a whole payload. The first invalid line is returned as a *ParseError.
+func Parse(r io.Reader) (*Script, error) {
+	var script Script
+	var scanner = bufio.NewScanner(r)
+	var line int
+
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		cmd, skip, err := parseLine(strings.TrimLeft(text, " \t"), line)
+		if err != nil {
+			return nil, err
+		}
+		if skip {
+			continue
+		}
+		if cmd.kind == cmdRepeat && len(script.commands) == 0 {
+			return nil, &ParseError{Line: line, Msg: "REPEAT has no command to repeat"}
+		}
+		script.commands = append(script.commands, cmd)
+	}
+	if err := scanner.Err(); err == bufio.ErrTooLong {
+		return nil, &ParseError{Line: line + 1, Msg: "line is too long"}
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &script, nil
+}
+
+// ParseString parses a payload held in a string.
+func ParseString(s string) (*Script, error) {
+	return Parse(strings.NewReader(s))
+}
+
+// parseLine parses one non blank line. skip is true for comments.
+func parseLine(text string, line int) (cmd command, skip bool, err error) {
+	var name, arg string = text, ""
+
+	cmd.line = line
+	if i := strings.IndexAny(text, " \t"); i >= 0 {
+		name, arg = text[:i], text[i+1:]
+	}
+
+	switch strings.ToUpper(name) {
+	case "REM":
+		return cmd, true, nil
+	case "STRING", "STRINGLN":
+		cmd.kind = cmdString
+		cmd.text = arg
+		if strings.ToUpper(name) == "STRINGLN" {
+			cmd.text += "\n"
+		}
+	case "DELAY", "DEFAULT_DELAY", "DEFAULTDELAY":
+		ms, err := strconv.Atoi(strings.TrimSpace(arg))
+		if err != nil || ms < 0 {
+			return cmd, false, &ParseError{Line: line, Msg: fmt.Sprintf("%s expects milliseconds, got '%s'", strings.ToUpper(name), arg)}
+		}
+		cmd.kind = cmdDelay
+		if strings.ToUpper(name) != "DELAY" {
+			cmd.kind = cmdDefaultDelay
+		}
+		cmd.delay = time.Duration(ms) * time.Millisecond
+	case "REPEAT", "REPLAY":
+		n, err := strconv.Atoi(strings.TrimSpace(arg))
+		if err != nil || n < 1 {
+			return cmd, false, &ParseError{Line: line, Msg: fmt.Sprintf("REPEAT expects a count, got '%s'", arg)}
+		}
+		cmd.kind = cmdRepeat
+		cmd.count = n
+	default:
+		cmd.kind = cmdKeys
+		if err = parseKeys(&cmd, text); err != nil {
+			return cmd, false, err
+		}
+	}
+
+	return cmd, false, nil
+}
+
+// parseKeys parses a key combination, keys are separated by spaces or '-', e.g. "CTRL-ALT DELETE".
+func parseKeys(cmd *command, text string) error {
+	for _, field := range strings.Fields(text) {
+		names := []string{field}
+		if len(field) > 1 && strings.Contains(field, "-") {
+			names = strings.Split(field, "-")
+		}
+		for _, name := range names {
+			if modifier, ok := modifierNames[strings.ToUpper(name)]; ok {
+				cmd.modifiers |= modifier
+			} else if key, ok := keyNames[strings.ToUpper(name)]; ok {
+				cmd.keys = append(cmd.keys, key)
+			} else if r := []rune(name); len(r) == 1 {
+				cmd.chars = append(cmd.chars, r[0])
+			} else if key, ok := keyboard.KeyByName(name); ok {
+				cmd.keys = append(cmd.keys, key)
+			} else {
+				return &ParseError{Line: cmd.line, Msg: fmt.Sprintf("unknown command or key '%s'", name)}
+			}
+		}
+	}
+	if n := len(cmd.keys) + len(cmd.chars); n > keyboard.ROLLOVER {
+		return &ParseError{Line: cmd.line, Msg: (&keyboard.RolloverError{Keys: n}).Error()}
+	}
+	return nil
+}
+
+var modifierNames = map[string]byte{
+	"GUI":     keyboard.MODIFIER_KEY_LEFT_GUI,
+	"WINDOWS": keyboard.MODIFIER_KEY_LEFT_GUI,
+	"COMMAND": keyboard.MODIFIER_KEY_LEFT_GUI,
+	"CTRL":    keyboard.MODIFIER_KEY_LEFT_CTRL,
+	"CONTROL": keyboard.MODIFIER_KEY_LEFT_CTRL,
+	"ALT":     keyboard.MODIFIER_KEY_LEFT_ALT,
+	"OPTION":  keyboard.MODIFIER_KEY_LEFT_ALT,
+	"SHIFT":   keyboard.MODIFIER_KEY_LEFT_SHIFT,
+}
+
+// keyNames DuckyScript names that differ from keyboard.KeyByName.
+var keyNames = map[string]byte{
+	"UPARROW":     keyboard.KEYCODE_ARROW_UP,
+	"DOWNARROW":   keyboard.KEYCODE_ARROW_DOWN,
+	"LEFTARROW":   keyboard.KEYCODE_ARROW_LEFT,
+	"RIGHTARROW":  keyboard.KEYCODE_ARROW_RIGHT,
+	"PAGEUP":      keyboard.KEYCODE_PAGE_UP,
+	"PAGEDOWN":    keyboard.KEYCODE_PAGE_DOWN,
+	"CAPSLOCK":    keyboard.KEYCODE_CAPS_LOCK,
+	"NUMLOCK":     keyboard.KEYCODE_NUM_LOCK,
+	"SCROLLLOCK":  keyboard.KEYCODE_SCROLL_LOCK,
+	"BREAK":       keyboard.KEYCODE_PAUSE,
+	"PRINTSCREEN": keyboard.KEYCODE_PRINTSCREEN,
+}
@@ -0,0 +1,217 @@
+package ducky
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scirelli/turkey-pi/pkg/keyboard"
+	"github.com/scirelli/turkey-pi/pkg/keyboard/hidtest"
+)
+
+func TestParse(t *testing.T) {
+	var tests = []struct {
+		name   string
+		script string
+		want   []command
+	}{
+		{
+			name:   "strings",
+			script: "STRING Hello, World\nSTRINGLN  two spaces\nstring lower case",
+			want: []command{
+				{kind: cmdString, line: 1, text: "Hello, World"},
+				{kind: cmdString, line: 2, text: " two spaces\n"},
+				{kind: cmdString, line: 3, text: "lower case"},
+			},
+		},
+		{
+			name:   "comments and blank lines keep their line numbers",
+			script: "REM opens the run dialog\n\n   \r\n  GUI r\r\nREM done",
+			want: []command{
+				{kind: cmdKeys, line: 4, modifiers: keyboard.MODIFIER_KEY_LEFT_GUI, chars: []rune{'r'}},
+			},
+		},
+		{
+			name:   "delays",
+			script: "DELAY 500\nDEFAULT_DELAY 20\nDEFAULTDELAY 0",
+			want: []command{
+				{kind: cmdDelay, line: 1, delay: 500 * time.Millisecond},
+				{kind: cmdDefaultDelay, line: 2, delay: 20 * time.Millisecond},
+				{kind: cmdDefaultDelay, line: 3},
+			},
+		},
+		{
+			name:   "repeat",
+			script: "ENTER\nREPEAT 3\nREPLAY 1",
+			want: []command{
+				{kind: cmdKeys, line: 1, keys: []byte{keyboard.KEYCODE_ENTER}},
+				{kind: cmdRepeat, line: 2, count: 3},
+				{kind: cmdRepeat, line: 3, count: 1},
+			},
+		},
+		{
+			name:   "key combinations",
+			script: "CTRL-ALT DELETE\nSHIFT UPARROW\nCONTROL-SHIFT ESC\nF5",
+			want: []command{
+				{kind: cmdKeys, line: 1, modifiers: keyboard.MODIFIER_KEY_LEFT_CTRL | keyboard.MODIFIER_KEY_LEFT_ALT, keys: []byte{keyboard.KEYCODE_DELETE}},
+				{kind: cmdKeys, line: 2, modifiers: keyboard.MODIFIER_KEY_LEFT_SHIFT, keys: []byte{keyboard.KEYCODE_ARROW_UP}},
+				{kind: cmdKeys, line: 3, modifiers: keyboard.MODIFIER_KEY_LEFT_CTRL | keyboard.MODIFIER_KEY_LEFT_SHIFT, keys: []byte{keyboard.KEYCODE_ESC}},
+				{kind: cmdKeys, line: 4, keys: []byte{keyboard.KEYCODE_F5}},
+			},
+		},
+		{
+			name:   "a single '-' is a character",
+			script: "CTRL -",
+			want: []command{
+				{kind: cmdKeys, line: 1, modifiers: keyboard.MODIFIER_KEY_LEFT_CTRL, chars: []rune{'-'}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		script, err := ParseString(test.script)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(script.commands, test.want) {
+			t.Errorf("%s: parsed\n%+v\nwant\n%+v", test.name, script.commands, test.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	var tests = []struct {
+		script string
+		line   int
+		msg    string // part of the message
+	}{
+		{"REPEAT 2", 1, "no command to repeat"},
+		{"REM nothing to repeat\n\nREPEAT 2", 3, "no command to repeat"},
+		{"ENTER\nREPEAT", 2, "REPEAT expects a count"},
+		{"ENTER\nREPEAT 0", 2, "REPEAT expects a count"},
+		{"ENTER\nREPEAT two", 2, "REPEAT expects a count, got 'two'"},
+		{"STRING a\nDELAY", 2, "DELAY expects milliseconds"},
+		{"STRING a\n\nDELAY -5", 3, "DELAY expects milliseconds, got '-5'"},
+		{"DEFAULT_DELAY 1.5", 1, "DEFAULT_DELAY expects milliseconds"},
+		{"defaultdelay x", 1, "DEFAULTDELAY expects milliseconds"},
+		{"STRING ok\nGUI NOSUCHKEY", 2, "unknown command or key 'NOSUCHKEY'"},
+		{"CTRL-NOPE", 1, "unknown command or key 'NOPE'"},
+		{"a b c d e f g", 1, "7 pressed at once"},
+		{"ENTER\n" + strings.Repeat("x", 70*1024), 2, "too long"},
+	}
+
+	for _, test := range tests {
+		_, err := ParseString(test.script)
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Errorf("%.20q: error %v, want a ParseError", test.script, err)
+			continue
+		}
+		if parseErr.Line != test.line || !strings.Contains(parseErr.Msg, test.msg) {
+			t.Errorf("%.20q: %v, want line %d: ...%s...", test.script, parseErr, test.line, test.msg)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	var tests = []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{"strings", "STRING Hello\nSTRINGLN , World\nSTRING !", "Hello, World\n!"},
+		{"keys", "STRING ab\nBACKSPACE\nSPACE\nTAB\nENTER", "a \t\n"},
+		{"repeat the last command", "STRING ab\nREPEAT 2\nENTER\nREPEAT 1", "ababab\n\n"},
+		{"repeat skips nothing after a comment", "STRING x\nREM no command\nREPEAT 2", "xxx"},
+		{"shifted characters", "SHIFT a\nSHIFT 1", "A!"},
+	}
+
+	for _, test := range tests {
+		script, err := ParseString(test.script)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		dev := hidtest.NewDevice()
+		kb := keyboard.File{Device: dev}
+		if err := script.Run(&kb, keyboard.Options{}); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if err := hidtest.Decode(nil, dev.Reports()).Check(test.want); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		}
+	}
+}
+
+// DEFAULT_DELAY applies after every command from its own line on, including each time REPEAT runs one.
+func TestRunDefaultDelay(t *testing.T) {
+	const delay = 30 * time.Millisecond
+	script, err := ParseString("ENTER\nENTER\nDEFAULT_DELAY 30\nENTER\nREPEAT 2\nDEFAULT_DELAY 0\nENTER")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dev := hidtest.NewDevice()
+	kb := keyboard.File{Device: dev}
+	if err := script.Run(&kb, keyboard.Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var presses []time.Time
+	for _, r := range dev.Reports() {
+		if r.Data[2] == keyboard.KEYCODE_ENTER {
+			presses = append(presses, r.Time)
+		}
+	}
+	if len(presses) != 6 {
+		t.Fatalf("ENTER went down %d times, want 6", len(presses))
+	}
+	for i, wait := range []bool{false, false, true, true, true} {
+		gap := presses[i+1].Sub(presses[i])
+		if wait && gap < delay {
+			t.Errorf("ENTER %d to %d took %s, want the %s default delay", i+1, i+2, gap, delay)
+		} else if !wait && gap >= delay {
+			t.Errorf("ENTER %d to %d took %s before any default delay", i+1, i+2, gap)
+		}
+	}
+}
+
+func TestRunError(t *testing.T) {
+	script, err := ParseString("REM fails on the first key\n\nSTRING a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dev := hidtest.NewDevice()
+	dev.WriteErr = io.ErrClosedPipe
+	kb := keyboard.File{Device: dev}
+
+	err = script.Run(&kb, keyboard.Options{})
+	var runErr *RunError
+	if !errors.As(err, &runErr) || runErr.Line != 3 {
+		t.Fatalf("Run returned %v, want a RunError on line 3", err)
+	}
+	if !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("%v does not wrap the write error", err)
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	script, err := ParseString("STRING a\nDELAY 10000\nSTRING b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dev := hidtest.NewDevice()
+	kb := keyboard.File{Device: dev}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := script.RunContext(ctx, &kb, keyboard.Options{}); err != context.DeadlineExceeded {
+		t.Errorf("RunContext returned %v, want context.DeadlineExceeded", err)
+	}
+	if err := hidtest.Decode(nil, dev.Reports()).Check("a"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,35 @@
+package ducky
+
+import "fmt"
+
+// ParseError an invalid line in a script, lines start at 1.
+type ParseError struct {
+	Line int
+	Msg  string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+func (e *ParseError) String() string {
+	return e.Error()
+}
+
+// RunError a line of a script that failed while it was typed, lines start at 1.
+type RunError struct {
+	Line int
+	Err  error
+}
+
+func (e *RunError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+func (e *RunError) String() string {
+	return e.Error()
+}
+
+func (e *RunError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,83 @@
+package ducky
+
+import (
+	"context"
+	"time"
+
+	"github.com/scirelli/turkey-pi/pkg/keyboard"
+)
+
+// Run types the script on kb. DEFAULT_DELAY applies from the line it appears on.
+func (s *Script) Run(kb *keyboard.File, opts keyboard.Options) error {
+	return s.RunContext(context.Background(), kb, opts)
+}
+
+// RunContext runs the script like Run but stops between strokes once ctx is done, returning ctx.Err(). A line that fails
+// to type is returned as a *RunError.
+func (s *Script) RunContext(ctx context.Context, kb *keyboard.File, opts keyboard.Options) error {
+	var defaultDelay time.Duration
+	var previous *command
+
+	for i := range s.commands {
+		cmd := &s.commands[i]
+		if cmd.kind == cmdRepeat {
+			for n := 0; n < cmd.count; n++ {
+				if err := run(ctx, kb, opts, previous, &defaultDelay); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := run(ctx, kb, opts, cmd, &defaultDelay); err != nil {
+			return err
+		}
+		previous = cmd
+	}
+	return nil
+}
+
+func run(ctx context.Context, kb *keyboard.File, opts keyboard.Options, cmd *command, defaultDelay *time.Duration) error {
+	var err error
+
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	switch cmd.kind {
+	case cmdString:
+		_, err = kb.WriteStringContextWith(ctx, cmd.text, opts)
+	case cmdDelay:
+		err = sleep(ctx, cmd.delay)
+	case cmdDefaultDelay:
+		*defaultDelay = cmd.delay
+		return nil
+	case cmdKeys:
+		modifiers, keys := cmd.modifiers, append([]byte{}, cmd.keys...)
+		for _, c := range cmd.chars {
+			strokes := kb.Strokes(c, opts)
+			last := strokes[len(strokes)-1]
+			modifiers |= last.Modifier
+			keys = append(keys, last.Keycode)
+		}
+		err = kb.PressChord(modifiers, keys...)
+	}
+	if err != nil && err == ctx.Err() {
+		return err
+	} else if err != nil {
+		return &RunError{Line: cmd.line, Err: err}
+	}
+
+	return sleep(ctx, *defaultDelay)
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	var timer = time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
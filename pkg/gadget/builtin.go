@@ -0,0 +1,74 @@
+package gadget
+
+import (
+	"sort"
+
+	"github.com/scirelli/turkey-pi/pkg/gamepad"
+	"github.com/scirelli/turkey-pi/pkg/keyboard"
+	"github.com/scirelli/turkey-pi/pkg/mouse"
+	"github.com/scirelli/turkey-pi/pkg/pointer"
+	"github.com/scirelli/turkey-pi/pkg/switchpro"
+)
+
+// BuiltinDevices the hid functions of the devices this project emulates, by the name used in HIDFunction.Device.
+var BuiltinDevices = map[string]HIDFunction{
+	"keyboard":  {Protocol: 1, Subclass: 0, ReportLength: keyboard.ReportSz, ReportDescriptor: keyboard.ReportDescriptor},
+	"mouse":     {Protocol: 2, Subclass: 1, ReportLength: mouse.ReportSz, ReportDescriptor: mouse.ReportDescriptor},
+	"media":     {Protocol: 0, Subclass: 0, ReportLength: keyboard.CONSUMER_REPORT_SZ, ReportDescriptor: keyboard.ConsumerReportDescriptor},
+	"gamepad":   {Protocol: 0, Subclass: 0, ReportLength: gamepad.ReportSz, ReportDescriptor: gamepad.ReportDescriptor},
+	"pointer":   {Protocol: 0, Subclass: 0, ReportLength: pointer.ReportSz, ReportDescriptor: pointer.ReportDescriptor},
+	"switchpro": {Protocol: 0, Subclass: 0, ReportLength: switchpro.REPORT_SZ, ReportDescriptor: switchpro.ReportDescriptor},
+}
+
+// BuiltinDeviceNames the names of BuiltinDevices.
+func BuiltinDeviceNames() []string {
+	var names []string = make([]string, 0, len(BuiltinDevices))
+	for name := range BuiltinDevices {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DefaultConfig the gadget init/enable-rpi-hid sets up, a keyboard, mouse, media keys, gamepad and pointer as
+// /dev/hidg0 to /dev/hidg4.
+func DefaultConfig() Config {
+	return Config{
+		Name:      "g1",
+		VendorID:  0x1d6b, // Linux Foundation
+		ProductID: 0x0104, // Multifunction Composite Gadget
+		BCDDevice: 0x0100,
+		BCDUSB:    0x0200,
+		Strings: Strings{
+			SerialNumber: "E8624758475EE0358BC53",
+			Manufacturer: "turkey-pi",
+			Product:      "Generic USB Keyboard",
+		},
+		Configuration: Configuration{Name: "Config 1: ECM network", MaxPower: 250},
+		Functions: []HIDFunction{
+			{Name: "usb0", Device: "keyboard"},
+			{Name: "usb1", Device: "mouse"},
+			{Name: "usb2", Device: "media"},
+			{Name: "usb3", Device: "gamepad"},
+			{Name: "usb4", Device: "pointer"},
+		},
+	}
+}
+
+// SwitchProConfig the gadget init/enable-switchpro-hid sets up, a Pro Controller with Nintendo's IDs as /dev/hidg0.
+func SwitchProConfig() Config {
+	return Config{
+		Name:      "procon",
+		VendorID:  0x057e, // Nintendo Co., Ltd
+		ProductID: 0x2009, // Switch Pro Controller
+		BCDDevice: 0x0200,
+		BCDUSB:    0x0200,
+		Strings: Strings{
+			SerialNumber: "000000000001",
+			Manufacturer: "Nintendo Co., Ltd.",
+			Product:      "Pro Controller",
+		},
+		Configuration: Configuration{Name: "Nintendo Switch Pro Controller", MaxPower: 500, Attributes: 0xa0},
+		Functions:     []HIDFunction{{Name: "usb0", Device: "switchpro"}},
+	}
+}
@@ -0,0 +1,168 @@
+// Package gadget sets up USB gadgets in configfs from a declarative Config, the Go replacement for
+// init/enable-rpi-hid. Applying a Config a second time only changes what differs, so it is safe to run on every boot.
+package gadget
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/scirelli/turkey-pi/pkg/hid/descriptor"
+)
+
+// Hex a number configfs writes in hex, such as idVendor. It is "0x1d6b" in JSON, a plain number is accepted too.
+type Hex uint16
+
+func (h Hex) String() string {
+	return fmt.Sprintf("%#04x", uint16(h))
+}
+
+func (h Hex) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.String())
+}
+
+func (h *Hex) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		var n uint16
+		if err := json.Unmarshal(data, &n); err != nil {
+			return fmt.Errorf("Expected a hex string like \"0x1d6b\" or a number, got %s", data)
+		}
+		*h = Hex(n)
+		return nil
+	}
+	n, err := strconv.ParseUint(s, 0, 16)
+	if err != nil {
+		return fmt.Errorf("Expected a hex string like \"0x1d6b\", got %q", s)
+	}
+	*h = Hex(n)
+	return nil
+}
+
+// HexBytes bytes written as a hex string in JSON, such as a report descriptor. Spaces are allowed between bytes.
+type HexBytes []byte
+
+func (b HexBytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(hex.EncodeToString(b))
+}
+
+func (b *HexBytes) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	decoded, err := hex.DecodeString(strings.Join(strings.Fields(s), ""))
+	if err != nil {
+		return fmt.Errorf("Report descriptor is not hex: %w", err)
+	}
+	*b = decoded
+	return nil
+}
+
+// Strings the English (0x409) strings the host shows for the gadget.
+type Strings struct {
+	SerialNumber string `json:"serialNumber"`
+	Manufacturer string `json:"manufacturer"`
+	Product      string `json:"product"`
+}
+
+// Configuration the gadget's single configuration, c.1.
+type Configuration struct {
+	Name       string `json:"name"`       //Name the configuration string.
+	MaxPower   int    `json:"maxPower"`   //MaxPower in mA.
+	Attributes Hex    `json:"attributes"` //Attributes bmAttributes, 0x80 bus powered when 0.
+}
+
+// HIDFunction a hid function, it shows up as /dev/hidg<#> on the Pi.
+type HIDFunction struct {
+	Name string `json:"name"` //Name the instance name, the function's directory is hid.<Name>.
+	//Device one of the devices this project emulates, see BuiltinDevices. It replaces Protocol, Subclass,
+	//ReportLength and ReportDescriptor.
+	Device           string   `json:"device,omitempty"`
+	Protocol         int      `json:"protocol"`
+	Subclass         int      `json:"subclass"`
+	ReportLength     int      `json:"reportLength"`
+	ReportDescriptor HexBytes `json:"reportDescriptor,omitempty"`
+}
+
+// Config a gadget and its functions.
+type Config struct {
+	Name          string        `json:"name"` //Name the gadget's directory under the configfs root, e.g. g1.
+	VendorID      Hex           `json:"vendorId"`
+	ProductID     Hex           `json:"productId"`
+	BCDDevice     Hex           `json:"bcdDevice"`
+	BCDUSB        Hex           `json:"bcdUSB"`
+	Strings       Strings       `json:"strings"`
+	Configuration Configuration `json:"configuration"`
+	Functions     []HIDFunction `json:"functions"`
+	UDC           string        `json:"udc,omitempty"` //UDC the device controller to bind to, the first one found when empty.
+}
+
+// LoadConfig reads a Config from a JSON file.
+func LoadConfig(fileName string) (Config, error) {
+	var c Config
+
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("%s: %w", fileName, err)
+	}
+	return c, nil
+}
+
+// Resolve fills in the functions' Device settings and checks the config can be applied.
+func (c Config) Resolve() (Config, error) {
+	var names = map[string]bool{}
+
+	if c.Name == "" || strings.ContainsAny(c.Name, "/.") {
+		return c, fmt.Errorf("Gadget name '%s' must be a plain directory name", c.Name)
+	}
+	if c.BCDUSB == 0 {
+		c.BCDUSB = 0x0200
+	}
+	if c.Configuration.Attributes == 0 {
+		c.Configuration.Attributes = 0x80
+	}
+
+	var functions []HIDFunction = make([]HIDFunction, len(c.Functions))
+	for i, f := range c.Functions {
+		if f.Name == "" || strings.ContainsAny(f.Name, "/.") {
+			return c, fmt.Errorf("Function name '%s' must be a plain name like usb0", f.Name)
+		}
+		if names[f.Name] {
+			return c, fmt.Errorf("Function '%s' is configured twice", f.Name)
+		}
+		names[f.Name] = true
+
+		if f.Device != "" {
+			d, ok := BuiltinDevices[f.Device]
+			if !ok {
+				return c, fmt.Errorf("Function '%s' has unknown device '%s', expected one of: %s", f.Name, f.Device, strings.Join(BuiltinDeviceNames(), ", "))
+			}
+			f.Protocol, f.Subclass, f.ReportLength, f.ReportDescriptor = d.Protocol, d.Subclass, d.ReportLength, d.ReportDescriptor
+		}
+		if f.ReportLength <= 0 || len(f.ReportDescriptor) == 0 {
+			return c, fmt.Errorf("Function '%s' needs a device or a report length and descriptor", f.Name)
+		}
+		layout, err := descriptor.ParseLayout(f.ReportDescriptor)
+		if err != nil {
+			return c, fmt.Errorf("Function '%s' report descriptor: %s", f.Name, err)
+		}
+		if f.ReportLength < layout.MaxSize() {
+			return c, fmt.Errorf("Function '%s' report length %d is shorter than its largest report, %d bytes", f.Name, f.ReportLength, layout.MaxSize())
+		}
+		functions[i] = f
+	}
+	c.Functions = functions
+	return c, nil
+}
+
+// dir the function's directory under functions/.
+func (f HIDFunction) dir() string {
+	return "hid." + f.Name
+}
@@ -0,0 +1,379 @@
+package gadget
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const (
+	DEFAULT_ROOT    string = "/sys/kernel/config/usb_gadget"
+	DEFAULT_UDC_DIR string = "/sys/class/udc"
+
+	CONFIG_DIR  string = "configs/c.1"
+	STRINGS_DIR string = "strings/0x409" // English
+)
+
+// NoUDCError no USB device controller to bind the gadget to, the dwc2 overlay is probably not loaded.
+type NoUDCError struct {
+	Dir string
+}
+
+func (e NoUDCError) Error() string {
+	return fmt.Sprintf("No USB device controller in '%s', is the dwc2 overlay enabled?", e.Dir)
+}
+
+func (e NoUDCError) String() string {
+	return e.Error()
+}
+
+// Manager creates, binds and removes gadgets. Both directories can point at an ordinary directory, where the files
+// configfs would create are written as plain files.
+type Manager struct {
+	Root   string //Root the configfs usb_gadget directory, DEFAULT_ROOT when empty.
+	UDCDir string //UDCDir lists the USB device controllers, DEFAULT_UDC_DIR when empty.
+}
+
+func (m Manager) root() string {
+	if m.Root == "" {
+		return DEFAULT_ROOT
+	}
+	return m.Root
+}
+
+func (m Manager) udcDir() string {
+	if m.UDCDir == "" {
+		return DEFAULT_UDC_DIR
+	}
+	return m.UDCDir
+}
+
+func (m Manager) gadgetDir(name string) string {
+	return filepath.Join(m.root(), name)
+}
+
+// Up creates or updates the gadget to match c and binds it. Attributes that already match are left alone, a bound
+// gadget is only unbound while something has to change. It returns whether anything changed.
+func (m Manager) Up(c Config) (changed bool, err error) {
+	if c, err = c.Resolve(); err != nil {
+		return false, err
+	}
+	dir := m.gadgetDir(c.Name)
+
+	if changed, err = m.apply(c, true); err != nil {
+		return false, err
+	}
+	udc, err := m.boundUDC(dir)
+	if err != nil {
+		return false, err
+	}
+	if !changed && udc != "" {
+		return false, nil
+	}
+	if udc != "" {
+		if err := m.Unbind(c.Name); err != nil {
+			return false, err
+		}
+	}
+	if _, err := m.apply(c, false); err != nil {
+		return true, err
+	}
+	return true, m.Bind(c)
+}
+
+// Bind binds the gadget to c.UDC, or the first USB device controller found, which makes it show up on the host.
+func (m Manager) Bind(c Config) error {
+	udc := c.UDC
+	if udc == "" {
+		entries, err := ioutil.ReadDir(m.udcDir())
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if len(entries) == 0 {
+			return NoUDCError{Dir: m.udcDir()}
+		}
+		udc = entries[0].Name()
+	}
+	return writeAttr(filepath.Join(m.gadgetDir(c.Name), "UDC"), []byte(udc+"\n"))
+}
+
+// Unbind disconnects the gadget from the host, it can be changed once unbound.
+func (m Manager) Unbind(name string) error {
+	dir := m.gadgetDir(name)
+	if udc, err := m.boundUDC(dir); err != nil || udc == "" {
+		return err
+	}
+	return writeAttr(filepath.Join(dir, "UDC"), []byte("\n"))
+}
+
+// Down unbinds and removes the gadget. Removing a gadget that does not exist does nothing.
+func (m Manager) Down(name string) error {
+	dir := m.gadgetDir(name)
+
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil
+	}
+	if err := m.Unbind(name); err != nil {
+		return err
+	}
+
+	// configfs only lets a gadget go in the reverse order it was built.
+	configs, _ := filepath.Glob(filepath.Join(dir, "configs", "*"))
+	for _, config := range configs {
+		links, _ := ioutil.ReadDir(config)
+		for _, l := range links {
+			if l.Mode()&os.ModeSymlink != 0 {
+				if err := os.Remove(filepath.Join(config, l.Name())); err != nil {
+					return err
+				}
+			}
+		}
+		if err := removeDirs(filepath.Join(config, "strings", "*")); err != nil {
+			return err
+		}
+	}
+	for _, pattern := range []string{"configs/*", "functions/*", "strings/*"} {
+		if err := removeDirs(filepath.Join(dir, pattern)); err != nil {
+			return err
+		}
+	}
+	return removeDir(dir)
+}
+
+// FunctionStatus a function of a gadget.
+type FunctionStatus struct {
+	Name   string `json:"name"`
+	Linked bool   `json:"linked"`           // Linked the function is part of the configuration
+	Device string `json:"device,omitempty"` // Device the /dev/hidg<#> file, known once the gadget is set up by configfs
+}
+
+// Status a gadget as found in configfs.
+type Status struct {
+	Name      string           `json:"name"`
+	Exists    bool             `json:"exists"`
+	UDC       string           `json:"udc"` // UDC the controller the gadget is bound to, empty when unbound
+	VendorID  string           `json:"vendorId"`
+	ProductID string           `json:"productId"`
+	Functions []FunctionStatus `json:"functions"`
+}
+
+// Status reads the gadget's state from configfs.
+func (m Manager) Status(name string) (Status, error) {
+	var s Status = Status{Name: name, Functions: []FunctionStatus{}}
+	dir := m.gadgetDir(name)
+
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return s, nil
+	} else if err != nil {
+		return s, err
+	}
+	s.Exists = true
+
+	var err error
+	if s.UDC, err = m.boundUDC(dir); err != nil {
+		return s, err
+	}
+	s.VendorID = readAttr(filepath.Join(dir, "idVendor"))
+	s.ProductID = readAttr(filepath.Join(dir, "idProduct"))
+
+	functions, _ := filepath.Glob(filepath.Join(dir, "functions", "hid.*"))
+	sort.Strings(functions)
+	for _, f := range functions {
+		fs := FunctionStatus{Name: strings.TrimPrefix(filepath.Base(f), "hid.")}
+		if target, err := os.Readlink(filepath.Join(dir, CONFIG_DIR, filepath.Base(f))); err == nil && filepath.Base(target) == filepath.Base(f) {
+			fs.Linked = true
+		}
+		fs.Device = hidgDevice(readAttr(filepath.Join(f, "dev")))
+		s.Functions = append(s.Functions, fs)
+	}
+	return s, nil
+}
+
+// apply writes c into configfs, or with dryRun only reports whether anything differs.
+func (m Manager) apply(c Config, dryRun bool) (changed bool, err error) {
+	dir := m.gadgetDir(c.Name)
+	var w = attrWriter{dryRun: dryRun}
+
+	w.dir(dir)
+	w.attr(filepath.Join(dir, "idVendor"), c.VendorID.String())
+	w.attr(filepath.Join(dir, "idProduct"), c.ProductID.String())
+	w.attr(filepath.Join(dir, "bcdDevice"), c.BCDDevice.String())
+	w.attr(filepath.Join(dir, "bcdUSB"), c.BCDUSB.String())
+
+	w.dir(filepath.Join(dir, STRINGS_DIR))
+	w.attr(filepath.Join(dir, STRINGS_DIR, "serialnumber"), c.Strings.SerialNumber)
+	w.attr(filepath.Join(dir, STRINGS_DIR, "manufacturer"), c.Strings.Manufacturer)
+	w.attr(filepath.Join(dir, STRINGS_DIR, "product"), c.Strings.Product)
+
+	w.dir(filepath.Join(dir, CONFIG_DIR))
+	w.attr(filepath.Join(dir, CONFIG_DIR, "MaxPower"), strconv.Itoa(c.Configuration.MaxPower))
+	w.attr(filepath.Join(dir, CONFIG_DIR, "bmAttributes"), fmt.Sprintf("%#02x", uint16(c.Configuration.Attributes)))
+	w.dir(filepath.Join(dir, CONFIG_DIR, STRINGS_DIR))
+	w.attr(filepath.Join(dir, CONFIG_DIR, STRINGS_DIR, "configuration"), c.Configuration.Name)
+
+	var wanted = map[string]bool{}
+	for _, f := range c.Functions {
+		fdir := filepath.Join(dir, "functions", f.dir())
+		wanted[f.dir()] = true
+
+		link := filepath.Join(dir, CONFIG_DIR, f.dir())
+
+		w.dir(fdir)
+		// f_hid answers EBUSY to attribute writes while the function is linked, it is unlinked for the change.
+		if functionDiffers(fdir, f) {
+			w.unlink(link)
+		}
+		w.attr(filepath.Join(fdir, "protocol"), strconv.Itoa(f.Protocol))
+		w.attr(filepath.Join(fdir, "subclass"), strconv.Itoa(f.Subclass))
+		w.attr(filepath.Join(fdir, "report_length"), strconv.Itoa(f.ReportLength))
+		w.binary(filepath.Join(fdir, "report_desc"), f.ReportDescriptor)
+		w.link(fdir, link)
+	}
+
+	// Functions no longer in the config are unlinked and removed.
+	existing, _ := filepath.Glob(filepath.Join(dir, "functions", "hid.*"))
+	for _, fdir := range existing {
+		if !wanted[filepath.Base(fdir)] {
+			w.remove(filepath.Join(dir, CONFIG_DIR, filepath.Base(fdir)), fdir)
+		}
+	}
+	return w.changed, w.err
+}
+
+// attrWriter writes only what differs and keeps the first error, so apply reads as the list of what a gadget is.
+type attrWriter struct {
+	dryRun  bool
+	changed bool
+	err     error
+}
+
+func (w *attrWriter) do(differs bool, change func() error) {
+	if w.err != nil || !differs {
+		return
+	}
+	w.changed = true
+	if !w.dryRun {
+		w.err = change()
+	}
+}
+
+func (w *attrWriter) dir(path string) {
+	_, err := os.Stat(path)
+	w.do(os.IsNotExist(err), func() error { return os.MkdirAll(path, 0755) })
+}
+
+func (w *attrWriter) attr(path string, value string) {
+	w.do(readAttr(path) != value, func() error { return writeAttr(path, []byte(value+"\n")) })
+}
+
+func (w *attrWriter) binary(path string, value []byte) {
+	current, _ := ioutil.ReadFile(path)
+	w.do(!bytes.Equal(current, value), func() error { return writeAttr(path, value) })
+}
+
+func (w *attrWriter) link(target, link string) {
+	current, err := os.Readlink(link)
+	w.do(err != nil || current != target, func() error {
+		if err == nil {
+			if err := os.Remove(link); err != nil {
+				return err
+			}
+		}
+		return os.Symlink(target, link)
+	})
+}
+
+func (w *attrWriter) unlink(link string) {
+	_, err := os.Lstat(link)
+	w.do(err == nil, func() error { return os.Remove(link) })
+}
+
+func (w *attrWriter) remove(link, dir string) {
+	w.do(true, func() error {
+		if err := os.Remove(link); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return removeDir(dir)
+	})
+}
+
+// functionDiffers any of the function's attributes in fdir differ from f.
+func functionDiffers(fdir string, f HIDFunction) bool {
+	desc, _ := ioutil.ReadFile(filepath.Join(fdir, "report_desc"))
+	return readAttr(filepath.Join(fdir, "protocol")) != strconv.Itoa(f.Protocol) ||
+		readAttr(filepath.Join(fdir, "subclass")) != strconv.Itoa(f.Subclass) ||
+		readAttr(filepath.Join(fdir, "report_length")) != strconv.Itoa(f.ReportLength) ||
+		!bytes.Equal(desc, f.ReportDescriptor)
+}
+
+// boundUDC the controller the gadget in dir is bound to, empty when unbound or the gadget does not exist yet.
+func (m Manager) boundUDC(dir string) (string, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, "UDC"))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	return strings.TrimSpace(string(data)), err
+}
+
+// readAttr the value of a configfs attribute without the trailing newline, empty when it can not be read.
+func readAttr(path string) string {
+	data, _ := ioutil.ReadFile(path)
+	return strings.TrimSpace(string(data))
+}
+
+// writeAttr writes an attribute in a single write, configfs takes each write as the whole value.
+func writeAttr(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// hidgDevice the device file for a function's dev attribute, "major:minor". The hidg driver numbers its devices by
+// minor number.
+func hidgDevice(dev string) string {
+	if i := strings.IndexByte(dev, ':'); i >= 0 {
+		return "/dev/hidg" + dev[i+1:]
+	}
+	return ""
+}
+
+// removeDirs removes the directories matching pattern.
+func removeDirs(pattern string) error {
+	dirs, _ := filepath.Glob(pattern)
+	for _, d := range dirs {
+		if err := removeDir(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeDir removes a configfs directory. configfs removes a directory's attributes and default groups, such as
+// strings, with it. An ordinary directory standing in for configfs has them removed first.
+func removeDir(dir string) error {
+	err := os.Remove(dir)
+	if err == nil || os.IsNotExist(err) || !errors.Is(err, syscall.ENOTEMPTY) && !errors.Is(err, syscall.EEXIST) {
+		return err
+	}
+	entries, _ := ioutil.ReadDir(dir)
+	for _, e := range entries {
+		if e.Mode().IsRegular() || e.IsDir() {
+			// Directories that still hold anything are left for the final Remove to report.
+			os.Remove(filepath.Join(dir, e.Name()))
+		}
+	}
+	return os.Remove(dir)
+}
@@ -0,0 +1,203 @@
+package gadget
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/scirelli/turkey-pi/pkg/keyboard"
+)
+
+const testUDC = "fe980000.usb"
+
+// testManager a Manager over temp directories with one device controller.
+func testManager(t *testing.T) Manager {
+	t.Helper()
+	var m = Manager{Root: t.TempDir(), UDCDir: t.TempDir()}
+	if err := os.Mkdir(filepath.Join(m.UDCDir, testUDC), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func up(t *testing.T, m Manager, c Config) bool {
+	t.Helper()
+	changed, err := m.Up(c)
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	return changed
+}
+
+func TestUpCreatesGadget(t *testing.T) {
+	m := testManager(t)
+	dir := filepath.Join(m.Root, "g1")
+
+	if !up(t, m, DefaultConfig()) {
+		t.Error("first Up reported no change")
+	}
+	for path, want := range map[string]string{
+		"UDC":                              testUDC,
+		"idVendor":                         "0x1d6b",
+		"idProduct":                        "0x0104",
+		"strings/0x409/product":            "Generic USB Keyboard",
+		"configs/c.1/MaxPower":             "250",
+		"configs/c.1/bmAttributes":         "0x80",
+		"functions/hid.usb0/protocol":      "1",
+		"functions/hid.usb0/report_length": "8",
+	} {
+		if got := readAttr(filepath.Join(dir, path)); got != want {
+			t.Errorf("%s is %q, want %q", path, got, want)
+		}
+	}
+	desc, _ := ioutil.ReadFile(filepath.Join(dir, "functions/hid.usb0/report_desc"))
+	if string(desc) != string(keyboard.ReportDescriptor) {
+		t.Errorf("keyboard report_desc is % x", desc)
+	}
+	if target, err := os.Readlink(filepath.Join(dir, CONFIG_DIR, "hid.usb4")); err != nil || target != filepath.Join(dir, "functions/hid.usb4") {
+		t.Errorf("hid.usb4 link is %q, %v", target, err)
+	}
+}
+
+func TestUpTwiceNoChange(t *testing.T) {
+	m := testManager(t)
+
+	up(t, m, DefaultConfig())
+	if up(t, m, DefaultConfig()) {
+		t.Error("second Up of the same config reported a change")
+	}
+}
+
+func TestUpUpdate(t *testing.T) {
+	m := testManager(t)
+	dir := filepath.Join(m.Root, "g1")
+	c := DefaultConfig()
+	up(t, m, c)
+
+	c.Strings.Product = "Other Keyboard"
+	c.Functions[1].Device = "pointer"
+	c.Functions = c.Functions[:2]
+	if !up(t, m, c) {
+		t.Fatal("Up of a changed config reported no change")
+	}
+
+	if got := readAttr(filepath.Join(dir, STRINGS_DIR, "product")); got != "Other Keyboard" {
+		t.Errorf("product is %q", got)
+	}
+	desc, _ := ioutil.ReadFile(filepath.Join(dir, "functions/hid.usb1/report_desc"))
+	if string(desc) != string(BuiltinDevices["pointer"].ReportDescriptor) {
+		t.Errorf("hid.usb1 report_desc is not the pointer's: % x", desc)
+	}
+	if target, err := os.Readlink(filepath.Join(dir, CONFIG_DIR, "hid.usb1")); err != nil || target != filepath.Join(dir, "functions/hid.usb1") {
+		t.Errorf("hid.usb1 is not relinked after its change: %q, %v", target, err)
+	}
+	for _, f := range []string{"hid.usb2", "hid.usb3", "hid.usb4"} {
+		if _, err := os.Lstat(filepath.Join(dir, CONFIG_DIR, f)); !os.IsNotExist(err) {
+			t.Errorf("%s is still linked", f)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "functions", f)); !os.IsNotExist(err) {
+			t.Errorf("%s is not removed", f)
+		}
+	}
+	if got := readAttr(filepath.Join(dir, "UDC")); got != testUDC {
+		t.Errorf("UDC is %q after the update, want %q", got, testUDC)
+	}
+	if up(t, m, c) {
+		t.Error("Up after the update reported a change")
+	}
+}
+
+func TestFunctionUnlinkedForChange(t *testing.T) {
+	m := testManager(t)
+	dir := filepath.Join(m.Root, "g1")
+	c := DefaultConfig()
+	up(t, m, c)
+
+	c.Functions[0].Device = "mouse"
+	c, err := c.Resolve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var w = attrWriter{}
+	fdir := filepath.Join(dir, "functions", c.Functions[0].dir())
+	link := filepath.Join(dir, CONFIG_DIR, c.Functions[0].dir())
+	if !functionDiffers(fdir, c.Functions[0]) {
+		t.Fatal("the keyboard function does not differ from a mouse")
+	}
+	w.unlink(link)
+	if w.err != nil || !w.changed {
+		t.Fatalf("unlink: changed %v, %v", w.changed, w.err)
+	}
+	if _, err := os.Lstat(link); !os.IsNotExist(err) {
+		t.Errorf("%s is still linked", link)
+	}
+	if functionDiffers(filepath.Join(dir, "functions/hid.usb1"), c.Functions[1]) {
+		t.Error("the unchanged mouse function differs")
+	}
+}
+
+func TestDown(t *testing.T) {
+	m := testManager(t)
+
+	up(t, m, DefaultConfig())
+	if err := m.Down("g1"); err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(m.Root, "g1")); !os.IsNotExist(err) {
+		t.Errorf("gadget directory is left behind: %v", err)
+	}
+	if err := m.Down("g1"); err != nil {
+		t.Errorf("Down of a missing gadget: %v", err)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	m := testManager(t)
+
+	s, err := m.Status("g1")
+	if err != nil || s.Exists {
+		t.Fatalf("Status before Up: %+v, %v", s, err)
+	}
+
+	up(t, m, DefaultConfig())
+	if err := writeAttr(filepath.Join(m.Root, "g1/functions/hid.usb0/dev"), []byte("237:0\n")); err != nil {
+		t.Fatal(err)
+	}
+	if s, err = m.Status("g1"); err != nil {
+		t.Fatal(err)
+	}
+	if !s.Exists || s.UDC != testUDC || s.VendorID != "0x1d6b" || s.ProductID != "0x0104" {
+		t.Errorf("Status is %+v", s)
+	}
+	if len(s.Functions) != 5 {
+		t.Fatalf("Status has %d functions, want 5", len(s.Functions))
+	}
+	for i, f := range s.Functions {
+		if !f.Linked {
+			t.Errorf("%s is not linked", f.Name)
+		}
+		if want := []string{"usb0", "usb1", "usb2", "usb3", "usb4"}[i]; f.Name != want {
+			t.Errorf("function %d is %s, want %s", i, f.Name, want)
+		}
+	}
+	if s.Functions[0].Device != "/dev/hidg0" || s.Functions[1].Device != "" {
+		t.Errorf("devices are %q and %q", s.Functions[0].Device, s.Functions[1].Device)
+	}
+
+	if err := m.Unbind("g1"); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ = m.Status("g1"); s.UDC != "" {
+		t.Errorf("UDC is %q after Unbind", s.UDC)
+	}
+}
+
+func TestBindNoUDC(t *testing.T) {
+	var m = Manager{Root: t.TempDir(), UDCDir: t.TempDir()}
+
+	_, err := m.Up(DefaultConfig())
+	if _, ok := err.(NoUDCError); !ok {
+		t.Errorf("Up without a controller returned %v, want NoUDCError", err)
+	}
+}
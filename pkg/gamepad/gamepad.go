@@ -0,0 +1,399 @@
+// Package gamepad emulates a generic USB HID gamepad on a gadget device file such as /dev/hidg3.
+package gamepad
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+Gamepad input report, 9 bytes.
+
+	Byte 0-1 buttons 1 to 16, little endian, bit 0 is button 1
+	Byte 2   hat switch in the low 4 bits, 0 is up and each step is 45° clockwise, HAT_CENTER when released
+	Byte 3   left stick X, -127 to 127, positive is right
+	Byte 4   left stick Y, -127 to 127, positive is down
+	Byte 5   right stick X
+	Byte 6   right stick Y
+	Byte 7   left trigger, 0 to 255
+	Byte 8   right trigger, 0 to 255
+*/
+const ReportSz int = 9
+
+type Report [ReportSz]byte
+
+const (
+	MAX_STICK   int = 127
+	MAX_TRIGGER int = 255
+)
+
+type Button uint16
+
+// Buttons in the order of the W3C standard gamepad, A/B/X/Y are where they are on an Xbox pad.
+const (
+	BUTTON_NONE    Button = 0
+	BUTTON_A       Button = 1 << 0
+	BUTTON_B       Button = 1 << 1
+	BUTTON_X       Button = 1 << 2
+	BUTTON_Y       Button = 1 << 3
+	BUTTON_LB      Button = 1 << 4
+	BUTTON_RB      Button = 1 << 5
+	BUTTON_LT      Button = 1 << 6 // digital trigger, the analog ones are AXIS_LT and AXIS_RT
+	BUTTON_RT      Button = 1 << 7
+	BUTTON_SELECT  Button = 1 << 8
+	BUTTON_START   Button = 1 << 9
+	BUTTON_LS      Button = 1 << 10 // left stick click
+	BUTTON_RS      Button = 1 << 11
+	BUTTON_HOME    Button = 1 << 12
+	BUTTON_CAPTURE Button = 1 << 13
+	BUTTON_15      Button = 1 << 14
+	BUTTON_16      Button = 1 << 15
+)
+
+var buttonNames = map[string]Button{
+	"a":       BUTTON_A,
+	"b":       BUTTON_B,
+	"x":       BUTTON_X,
+	"y":       BUTTON_Y,
+	"lb":      BUTTON_LB,
+	"rb":      BUTTON_RB,
+	"lt":      BUTTON_LT,
+	"rt":      BUTTON_RT,
+	"select":  BUTTON_SELECT,
+	"start":   BUTTON_START,
+	"ls":      BUTTON_LS,
+	"rs":      BUTTON_RS,
+	"home":    BUTTON_HOME,
+	"capture": BUTTON_CAPTURE,
+}
+
+// buttonAliases the PlayStation and Nintendo style names.
+var buttonAliases = map[string]Button{
+	"cross":    BUTTON_A,
+	"circle":   BUTTON_B,
+	"square":   BUTTON_X,
+	"triangle": BUTTON_Y,
+	"l1":       BUTTON_LB,
+	"r1":       BUTTON_RB,
+	"l2":       BUTTON_LT,
+	"r2":       BUTTON_RT,
+	"l3":       BUTTON_LS,
+	"r3":       BUTTON_RS,
+	"back":     BUTTON_SELECT,
+	"share":    BUTTON_SELECT,
+	"minus":    BUTTON_SELECT,
+	"options":  BUTTON_START,
+	"plus":     BUTTON_START,
+	"guide":    BUTTON_HOME,
+	"ps":       BUTTON_HOME,
+}
+
+// ButtonByName find a button by name, e.g. "a", "start" or "lb", or by number "1" to "16". Names are case insensitive.
+func ButtonByName(name string) (Button, error) {
+	var normalized = strings.ToLower(strings.TrimSpace(name))
+
+	if b, ok := buttonNames[normalized]; ok {
+		return b, nil
+	}
+	if b, ok := buttonAliases[normalized]; ok {
+		return b, nil
+	}
+	if n, err := strconv.Atoi(normalized); err == nil && n >= 1 && n <= 16 {
+		return Button(1) << (n - 1), nil
+	}
+	return BUTTON_NONE, fmt.Errorf("Unknown gamepad button '%s', expected 1 to 16 or one of: a, b, x, y, lb, rb, lt, rt, select, start, ls, rs, home, capture", name)
+}
+
+// ButtonNames the names of the buttons in b, numbered buttons without a name are "15" and "16".
+func ButtonNames(b Button) []string {
+	var names []string = []string{}
+
+	for i := 0; i < 16; i++ {
+		bit := Button(1) << i
+		if b&bit == 0 {
+			continue
+		}
+		name := strconv.Itoa(i + 1)
+		for n, nb := range buttonNames {
+			if nb == bit {
+				name = n
+			}
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+type Hat byte
+
+const (
+	HAT_UP         Hat = 0
+	HAT_UP_RIGHT   Hat = 1
+	HAT_RIGHT      Hat = 2
+	HAT_DOWN_RIGHT Hat = 3
+	HAT_DOWN       Hat = 4
+	HAT_DOWN_LEFT  Hat = 5
+	HAT_LEFT       Hat = 6
+	HAT_UP_LEFT    Hat = 7
+	HAT_CENTER     Hat = 8 // outside the logical range, the host reads it as no direction
+)
+
+var hatNames = map[string]Hat{
+	"up":         HAT_UP,
+	"up-right":   HAT_UP_RIGHT,
+	"right":      HAT_RIGHT,
+	"down-right": HAT_DOWN_RIGHT,
+	"down":       HAT_DOWN,
+	"down-left":  HAT_DOWN_LEFT,
+	"left":       HAT_LEFT,
+	"up-left":    HAT_UP_LEFT,
+	"center":     HAT_CENTER,
+}
+
+// HatByName find a hat direction by name, e.g. "up", "down-left" or "center". '_' or ' ' may be used for '-'.
+func HatByName(name string) (Hat, error) {
+	var normalized = strings.ToLower(strings.NewReplacer("_", "-", " ", "-").Replace(strings.TrimSpace(name)))
+
+	if h, ok := hatNames[normalized]; ok {
+		return h, nil
+	}
+	return HAT_CENTER, fmt.Errorf("Unknown hat direction '%s', expected one of: up, up-right, right, down-right, down, down-left, left, up-left, center", name)
+}
+
+func (h Hat) String() string {
+	for name, v := range hatNames {
+		if v == h {
+			return name
+		}
+	}
+	return "center"
+}
+
+type Axis int
+
+const (
+	AXIS_LX Axis = iota
+	AXIS_LY
+	AXIS_RX
+	AXIS_RY
+	AXIS_LT
+	AXIS_RT
+	AXIS_COUNT int = iota
+)
+
+var axisNames = map[string]Axis{
+	"lx": AXIS_LX,
+	"ly": AXIS_LY,
+	"rx": AXIS_RX,
+	"ry": AXIS_RY,
+	"lt": AXIS_LT,
+	"rt": AXIS_RT,
+}
+
+// AxisByName find an axis by name, one of lx, ly, rx, ry for the sticks or lt, rt for the triggers.
+func AxisByName(name string) (Axis, error) {
+	if a, ok := axisNames[strings.ToLower(strings.TrimSpace(name))]; ok {
+		return a, nil
+	}
+	return AXIS_LX, fmt.Errorf("Unknown gamepad axis '%s', expected one of: lx, ly, rx, ry, lt, rt", name)
+}
+
+func (a Axis) String() string {
+	for name, v := range axisNames {
+		if v == a {
+			return name
+		}
+	}
+	return strconv.Itoa(int(a))
+}
+
+// IsTrigger true for the trigger axes which go from 0 to MAX_TRIGGER, the sticks go from -MAX_STICK to MAX_STICK.
+func (a Axis) IsTrigger() bool {
+	return a == AXIS_LT || a == AXIS_RT
+}
+
+// clamp limits v to the range of the axis.
+func (a Axis) clamp(v int) int {
+	var min, max int = -MAX_STICK, MAX_STICK
+
+	if a.IsTrigger() {
+		min, max = 0, MAX_TRIGGER
+	}
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
+
+// State everything the gamepad reports, the zero value has the sticks centered but the hat pointing up, see Neutral.
+type State struct {
+	Buttons Button
+	Hat     Hat
+	Axes    [AXIS_COUNT]int
+}
+
+// Neutral nothing pressed, hat and sticks centered and triggers released.
+func Neutral() State {
+	return State{Hat: HAT_CENTER}
+}
+
+// Report the report for the state, axes out of range are clamped.
+func (s State) Report() Report {
+	var r Report = Report{byte(s.Buttons), byte(s.Buttons >> 8), byte(s.Hat & 0x0F)}
+
+	for a := AXIS_LX; a <= AXIS_RY; a++ {
+		r[3+int(a)] = byte(int8(a.clamp(s.Axes[a])))
+	}
+	r[7] = byte(AXIS_LT.clamp(s.Axes[AXIS_LT]))
+	r[8] = byte(AXIS_RT.clamp(s.Axes[AXIS_RT]))
+	return r
+}
+
+// Device where a File writes its reports. In use it is an *os.File opened on /dev/hidg<#>.
+type Device interface {
+	io.WriteCloser
+}
+
+// File represents the gamepad device file in user space /dev/hidg<#>
+type File struct {
+	Device
+	PressDelay time.Duration //PressDelay how long Press and TapHat hold a button down and the wait after letting go.
+
+	mu    sync.Mutex
+	state State
+	known bool
+}
+
+// State what the gamepad is reporting.
+func (f *File) State() State {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.current()
+}
+
+// Press presses and releases buttons count times, buttons held with Hold stay down.
+func (f *File) Press(buttons Button, count int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	held := f.current().Buttons
+	for i := 0; i < count; i++ {
+		if err := f.tap(func(s *State) { s.Buttons = held | buttons }, func(s *State) { s.Buttons = held &^ buttons }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Hold holds buttons down until they are released.
+func (f *File) Hold(buttons Button) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s := f.current()
+	s.Buttons |= buttons
+	return f.write(s)
+}
+
+// Release lets go of buttons.
+func (f *File) Release(buttons Button) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s := f.current()
+	s.Buttons &^= buttons
+	return f.write(s)
+}
+
+// ReleaseAll returns the gamepad to Neutral.
+func (f *File) ReleaseAll() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.write(Neutral())
+}
+
+// SetHat points the hat switch in direction h until it is changed, HAT_CENTER lets go of it.
+func (f *File) SetHat(h Hat) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s := f.current()
+	s.Hat = h
+	return f.write(s)
+}
+
+// TapHat points the hat in direction h and lets go count times, the way menus are usually navigated.
+func (f *File) TapHat(h Hat, count int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i := 0; i < count; i++ {
+		if err := f.tap(func(s *State) { s.Hat = h }, func(s *State) { s.Hat = HAT_CENTER }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetAxes moves the axes to the values given in one report, the values are clamped to the range of each axis.
+func (f *File) SetAxes(values map[Axis]int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s := f.current()
+	for a, v := range values {
+		if a < 0 || int(a) >= AXIS_COUNT {
+			return fmt.Errorf("Unknown gamepad axis %d", a)
+		}
+		s.Axes[a] = a.clamp(v)
+	}
+	return f.write(s)
+}
+
+// SetAxis moves one axis, see SetAxes.
+func (f *File) SetAxis(a Axis, v int) error {
+	return f.SetAxes(map[Axis]int{a: v})
+}
+
+// current the state last written, Neutral before anything is written. f.mu must be held.
+func (f *File) current() State {
+	if !f.known {
+		f.state, f.known = Neutral(), true
+	}
+	return f.state
+}
+
+// tap applies down, waits PressDelay, applies up and waits again. f.mu must be held.
+func (f *File) tap(down, up func(*State)) error {
+	s := f.current()
+	down(&s)
+	if err := f.write(s); err != nil {
+		return err
+	}
+	time.Sleep(f.PressDelay)
+	up(&s)
+	if err := f.write(s); err != nil {
+		return err
+	}
+	time.Sleep(f.PressDelay)
+	return nil
+}
+
+// write sends the report for s and remembers it, f.mu must be held.
+func (f *File) write(s State) error {
+	r := s.Report()
+	if _, err := f.Device.Write(r[:]); err != nil {
+		return err
+	}
+	f.state, f.known = s, true
+	return nil
+}
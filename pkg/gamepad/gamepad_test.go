@@ -0,0 +1,137 @@
+package gamepad
+
+import "testing"
+
+// reportRecorder a Device that keeps every report written to it.
+type reportRecorder struct {
+	reports []Report
+}
+
+func (d *reportRecorder) Write(p []byte) (int, error) {
+	var r Report
+	copy(r[:], p)
+	d.reports = append(d.reports, r)
+	return len(p), nil
+}
+
+func (d *reportRecorder) Close() error {
+	return nil
+}
+
+func TestStateReport(t *testing.T) {
+	var tests = []struct {
+		name  string
+		state State
+		want  Report
+	}{
+		{"neutral", Neutral(), Report{0, 0, 0x08, 0, 0, 0, 0, 0, 0}},
+		{"zero value points the hat up", State{}, Report{0, 0, 0x00, 0, 0, 0, 0, 0, 0}},
+		{
+			name:  "buttons are little endian",
+			state: State{Buttons: BUTTON_A | BUTTON_START | BUTTON_16, Hat: HAT_CENTER},
+			want:  Report{0x01, 0x82, 0x08, 0, 0, 0, 0, 0, 0},
+		},
+		{
+			name:  "hat directions",
+			state: State{Hat: HAT_DOWN_LEFT},
+			want:  Report{0, 0, 0x05, 0, 0, 0, 0, 0, 0},
+		},
+		{
+			name:  "sticks are signed",
+			state: State{Hat: HAT_CENTER, Axes: [AXIS_COUNT]int{AXIS_LX: -1, AXIS_LY: 127, AXIS_RX: -127, AXIS_RY: 64}},
+			want:  Report{0, 0, 0x08, 0xff, 0x7f, 0x81, 0x40, 0, 0},
+		},
+		{
+			name:  "triggers are unsigned",
+			state: State{Hat: HAT_CENTER, Axes: [AXIS_COUNT]int{AXIS_LT: 200, AXIS_RT: 255}},
+			want:  Report{0, 0, 0x08, 0, 0, 0, 0, 0xc8, 0xff},
+		},
+		{
+			name:  "axes out of range are clamped",
+			state: State{Hat: HAT_CENTER, Axes: [AXIS_COUNT]int{-500, 500, 128, -128, -10, 300}},
+			want:  Report{0, 0, 0x08, 0x81, 0x7f, 0x7f, 0x81, 0, 0xff},
+		},
+	}
+
+	for _, test := range tests {
+		if got := test.state.Report(); got != test.want {
+			t.Errorf("%s: % x, want % x", test.name, got, test.want)
+		}
+	}
+}
+
+func TestHatByName(t *testing.T) {
+	for name, want := range map[string]Hat{"up": HAT_UP, "Down_Right": HAT_DOWN_RIGHT, " up left ": HAT_UP_LEFT, "CENTER": HAT_CENTER} {
+		if h, err := HatByName(name); err != nil || h != want {
+			t.Errorf("HatByName(%q) = %d, %v, want %d", name, h, err, want)
+		}
+	}
+	if _, err := HatByName("north"); err == nil {
+		t.Error("HatByName found north")
+	}
+	for h := HAT_UP; h <= HAT_CENTER; h++ {
+		if back, err := HatByName(h.String()); err != nil || back != h {
+			t.Errorf("hat %d is named %q which finds %d, %v", h, h.String(), back, err)
+		}
+	}
+}
+
+func TestButtonByName(t *testing.T) {
+	for name, want := range map[string]Button{"a": BUTTON_A, "Cross": BUTTON_A, "r1": BUTTON_RB, "share": BUTTON_SELECT, "1": BUTTON_A, "16": BUTTON_16} {
+		if b, err := ButtonByName(name); err != nil || b != want {
+			t.Errorf("ButtonByName(%q) = %016b, %v, want %016b", name, b, err, want)
+		}
+	}
+	for _, name := range []string{"0", "17", "z"} {
+		if _, err := ButtonByName(name); err == nil {
+			t.Errorf("ButtonByName(%q) found a button", name)
+		}
+	}
+	if names := ButtonNames(BUTTON_B | BUTTON_HOME | BUTTON_15); len(names) != 3 || names[0] != "b" || names[1] != "home" || names[2] != "15" {
+		t.Errorf("ButtonNames = %v", names)
+	}
+}
+
+func TestFileReports(t *testing.T) {
+	var dev reportRecorder
+	var f = File{Device: &dev}
+
+	if err := f.Hold(BUTTON_LB); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Press(BUTTON_A, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.TapHat(HAT_RIGHT, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.SetAxes(map[Axis]int{AXIS_LX: 400, AXIS_RT: 128}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.SetAxes(map[Axis]int{Axis(AXIS_COUNT): 1}); err == nil {
+		t.Error("SetAxes with an unknown axis succeeded")
+	}
+	if err := f.ReleaseAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	var want = []Report{
+		{0x10, 0, 0x08, 0, 0, 0, 0, 0, 0},
+		// Press and TapHat keep the held button down.
+		{0x11, 0, 0x08, 0, 0, 0, 0, 0, 0}, {0x10, 0, 0x08, 0, 0, 0, 0, 0, 0},
+		{0x10, 0, 0x02, 0, 0, 0, 0, 0, 0}, {0x10, 0, 0x08, 0, 0, 0, 0, 0, 0},
+		{0x10, 0, 0x08, 0x7f, 0, 0, 0, 0, 0x80},
+		{0, 0, 0x08, 0, 0, 0, 0, 0, 0},
+	}
+	if len(dev.reports) != len(want) {
+		t.Fatalf("sent % x, want % x", dev.reports, want)
+	}
+	for i := range want {
+		if dev.reports[i] != want[i] {
+			t.Errorf("report %d is % x, want % x", i, dev.reports[i], want[i])
+		}
+	}
+	if f.State() != Neutral() {
+		t.Errorf("state %+v after ReleaseAll", f.State())
+	}
+}
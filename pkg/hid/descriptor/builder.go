@@ -0,0 +1,156 @@
+package descriptor
+
+import (
+	"strings"
+)
+
+// MainFlags the data of an Input, Output or Feature item. The zero value is Data, Array, Absolute.
+type MainFlags uint16
+
+const (
+	DATA     MainFlags = 0
+	ARRAY    MainFlags = 0
+	ABSOLUTE MainFlags = 0
+
+	CONSTANT       MainFlags = 1 << 0
+	VARIABLE       MainFlags = 1 << 1
+	RELATIVE       MainFlags = 1 << 2
+	WRAP           MainFlags = 1 << 3
+	NON_LINEAR     MainFlags = 1 << 4
+	NO_PREFERRED   MainFlags = 1 << 5
+	NULL_STATE     MainFlags = 1 << 6
+	VOLATILE       MainFlags = 1 << 7 // Output and Feature only
+	BUFFERED_BYTES MainFlags = 1 << 8
+)
+
+func (f MainFlags) String() string {
+	var names []string = []string{
+		pick(f&CONSTANT != 0, "Const", "Data"),
+		pick(f&VARIABLE != 0, "Var", "Array"),
+		pick(f&RELATIVE != 0, "Rel", "Abs"),
+	}
+	for _, flag := range []struct {
+		bit  MainFlags
+		name string
+	}{{WRAP, "Wrap"}, {NON_LINEAR, "Non-linear"}, {NO_PREFERRED, "No Preferred State"}, {NULL_STATE, "Null State"}, {VOLATILE, "Volatile"}, {BUFFERED_BYTES, "Buffered Bytes"}} {
+		if f&flag.bit != 0 {
+			names = append(names, flag.name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+func pick(cond bool, yes, no string) string {
+	if cond {
+		return yes
+	}
+	return no
+}
+
+// CollectionType the data of a Collection item.
+type CollectionType byte
+
+const (
+	COLLECTION_PHYSICAL       CollectionType = 0x00
+	COLLECTION_APPLICATION    CollectionType = 0x01
+	COLLECTION_LOGICAL        CollectionType = 0x02
+	COLLECTION_REPORT         CollectionType = 0x03
+	COLLECTION_NAMED_ARRAY    CollectionType = 0x04
+	COLLECTION_USAGE_SWITCH   CollectionType = 0x05
+	COLLECTION_USAGE_MODIFIER CollectionType = 0x06
+)
+
+var collectionNames = []string{"Physical", "Application", "Logical", "Report", "Named Array", "Usage Switch", "Usage Modifier"}
+
+func (c CollectionType) String() string {
+	if int(c) < len(collectionNames) {
+		return collectionNames[c]
+	}
+	if c >= 0x80 {
+		return "Vendor Defined"
+	}
+	return "Reserved"
+}
+
+/*
+Builder writes a descriptor item by item, each method adds one item and returns the builder so they can be chained.
+
+	var b descriptor.Builder
+	b.UsagePage(descriptor.PAGE_GENERIC_DESKTOP).Usage(descriptor.USAGE_MOUSE).Collection(descriptor.COLLECTION_APPLICATION)
+	...
+	b.EndCollection()
+	data := b.Bytes()
+
+Numbers are written in as few bytes as hold them, signed for the minimums, maximums and unit exponent, so a
+descriptor written by hand in the usual way comes out byte for byte the same.
+*/
+type Builder struct {
+	items []Item
+}
+
+// Item adds an item with data, written in as few bytes as hold it.
+func (b *Builder) Item(tag Tag, value int64) *Builder {
+	b.items = append(b.items, Item{Tag: tag, Data: encodeValue(value, tag.signed())})
+	return b
+}
+
+// Raw adds an item with exactly data, for descriptors that use a longer encoding than needed.
+func (b *Builder) Raw(tag Tag, data ...byte) *Builder {
+	b.items = append(b.items, Item{Tag: tag, Data: data})
+	return b
+}
+
+// Items the items added so far.
+func (b *Builder) Items() []Item {
+	return b.items
+}
+
+// Bytes the descriptor.
+func (b *Builder) Bytes() []byte {
+	return Encode(b.items)
+}
+
+func (b *Builder) UsagePage(page UsagePage) *Builder { return b.Item(TAG_USAGE_PAGE, int64(page)) }
+func (b *Builder) Usage(usage uint16) *Builder       { return b.Item(TAG_USAGE, int64(usage)) }
+func (b *Builder) UsageMin(usage uint16) *Builder    { return b.Item(TAG_USAGE_MIN, int64(usage)) }
+func (b *Builder) UsageMax(usage uint16) *Builder    { return b.Item(TAG_USAGE_MAX, int64(usage)) }
+func (b *Builder) LogicalMin(v int32) *Builder       { return b.Item(TAG_LOGICAL_MIN, int64(v)) }
+func (b *Builder) LogicalMax(v int32) *Builder       { return b.Item(TAG_LOGICAL_MAX, int64(v)) }
+func (b *Builder) PhysicalMin(v int32) *Builder      { return b.Item(TAG_PHYSICAL_MIN, int64(v)) }
+func (b *Builder) PhysicalMax(v int32) *Builder      { return b.Item(TAG_PHYSICAL_MAX, int64(v)) }
+func (b *Builder) UnitExponent(v int32) *Builder     { return b.Item(TAG_UNIT_EXPONENT, int64(v)) }
+func (b *Builder) Unit(unit uint32) *Builder         { return b.Item(TAG_UNIT, int64(unit)) }
+func (b *Builder) ReportSize(bits int) *Builder      { return b.Item(TAG_REPORT_SIZE, int64(bits)) }
+func (b *Builder) ReportCount(n int) *Builder        { return b.Item(TAG_REPORT_COUNT, int64(n)) }
+func (b *Builder) ReportID(id byte) *Builder         { return b.Item(TAG_REPORT_ID, int64(id)) }
+func (b *Builder) Push() *Builder                    { return b.Raw(TAG_PUSH) }
+func (b *Builder) Pop() *Builder                     { return b.Raw(TAG_POP) }
+
+// ExtendedUsage a usage with its page, for a usage on another page than the current Usage Page.
+func (b *Builder) ExtendedUsage(page UsagePage, usage uint16) *Builder {
+	return b.Raw(TAG_USAGE, byte(usage), byte(usage>>8), byte(page), byte(page>>8))
+}
+
+func (b *Builder) Input(flags MainFlags) *Builder   { return b.Item(TAG_INPUT, int64(flags)) }
+func (b *Builder) Output(flags MainFlags) *Builder  { return b.Item(TAG_OUTPUT, int64(flags)) }
+func (b *Builder) Feature(flags MainFlags) *Builder { return b.Item(TAG_FEATURE, int64(flags)) }
+
+func (b *Builder) Collection(c CollectionType) *Builder { return b.Item(TAG_COLLECTION, int64(c)) }
+func (b *Builder) EndCollection() *Builder              { return b.Raw(TAG_END_COLLECTION) }
+
+// encodeValue v in 1, 2 or 4 bytes, the fewest that hold it.
+func encodeValue(v int64, signed bool) []byte {
+	var size int = 4
+
+	switch {
+	case signed && v >= -128 && v <= 127, !signed && v >= 0 && v <= 0xFF:
+		size = 1
+	case signed && v >= -32768 && v <= 32767, !signed && v >= 0 && v <= 0xFFFF:
+		size = 2
+	}
+	var data []byte = make([]byte, size)
+	for i := range data {
+		data[i] = byte(v >> (8 * i))
+	}
+	return data
+}
@@ -0,0 +1,121 @@
+package descriptor_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/scirelli/turkey-pi/pkg/gadget"
+	"github.com/scirelli/turkey-pi/pkg/hid/descriptor"
+	"github.com/scirelli/turkey-pi/pkg/keyboard"
+)
+
+const gadgetScript = "../../../init/enable-rpi-hid"
+
+var scriptDescriptor = regexp.MustCompile(`^echo -ne ((?:\\\\x[0-9a-fA-F]{2})+) > "\$\{(\w+)\}/report_desc"`)
+
+// scriptDescriptors the report_desc bytes the gadget script writes, by the shell variable of the function directory.
+func scriptDescriptors(t *testing.T) map[string][]byte {
+	t.Helper()
+	f, err := os.Open(gadgetScript)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var descriptors = make(map[string][]byte)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64*1024)
+	for scanner.Scan() {
+		m := scriptDescriptor.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		data, err := hex.DecodeString(strings.ReplaceAll(m[1], `\\x`, ""))
+		if err != nil {
+			t.Fatalf("%s: %v", m[2], err)
+		}
+		descriptors[m[2]] = data
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return descriptors
+}
+
+func TestKeyboardDescriptorMatchesScript(t *testing.T) {
+	script := scriptDescriptors(t)["FUNCTIONS_DIR"]
+	if script == nil {
+		t.Fatalf("%s does not write a keyboard report_desc", gadgetScript)
+	}
+	if len(keyboard.ReportDescriptor) != 63 {
+		t.Errorf("keyboard descriptor is %d bytes, want 63", len(keyboard.ReportDescriptor))
+	}
+	if !bytes.Equal(keyboard.ReportDescriptor, script) {
+		t.Errorf("keyboard descriptor\n% x\ndoes not match %s\n% x", keyboard.ReportDescriptor, gadgetScript, script)
+	}
+}
+
+func TestScriptDescriptorsAreBuiltin(t *testing.T) {
+	for dir, data := range scriptDescriptors(t) {
+		var found bool
+		for _, d := range gadget.BuiltinDevices {
+			found = found || bytes.Equal(d.ReportDescriptor, data)
+		}
+		if !found {
+			t.Errorf("%s writes a %d byte descriptor that is not a built in one", dir, len(data))
+		}
+	}
+}
+
+func TestEncodeParseBuiltins(t *testing.T) {
+	for _, name := range gadget.BuiltinDeviceNames() {
+		d := gadget.BuiltinDevices[name].ReportDescriptor
+		items, err := descriptor.Parse(d)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if got := descriptor.Encode(items); !bytes.Equal(got, d) {
+			t.Errorf("%s: Encode(Parse(d))\n% x\nwant\n% x", name, got, d)
+		}
+	}
+}
+
+func TestBuiltinReportSizes(t *testing.T) {
+	for _, name := range gadget.BuiltinDeviceNames() {
+		d := gadget.BuiltinDevices[name]
+		layout, err := descriptor.ParseLayout(d.ReportDescriptor)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if layout.MaxSize() > d.ReportLength {
+			t.Errorf("%s: report length %d is shorter than the largest report, %d bytes", name, d.ReportLength, layout.MaxSize())
+		}
+		if r, found := layout.Report(descriptor.INPUT, 0); found && r.Size() != d.ReportLength {
+			t.Errorf("%s: report length %d but the input report is %d bytes", name, d.ReportLength, r.Size())
+		}
+	}
+}
+
+func TestKeyboardReportSize(t *testing.T) {
+	layout, err := descriptor.ParseLayout(keyboard.ReportDescriptor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, found := layout.Report(descriptor.INPUT, 0)
+	if !found {
+		t.Fatal("keyboard descriptor has no input report")
+	}
+	if r.Size() != keyboard.ReportSz {
+		t.Errorf("input report is %d bytes, want ReportSz %d", r.Size(), keyboard.ReportSz)
+	}
+	if r, _ := layout.Report(descriptor.OUTPUT, 0); r.Size() != keyboard.LED_REPORT_SZ {
+		t.Errorf("output report is %d bytes, want LED_REPORT_SZ %d", r.Size(), keyboard.LED_REPORT_SZ)
+	}
+}
@@ -0,0 +1,141 @@
+package descriptor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Node an item and, for a Collection, the items in it. A Collection's End Collection is its last child.
+type Node struct {
+	Item     Item
+	Children []*Node
+}
+
+// Tree nests a descriptor's items by collection.
+func Tree(items []Item) ([]*Node, error) {
+	var root = &Node{}
+	var stack []*Node = []*Node{root}
+
+	for i, it := range items {
+		parent := stack[len(stack)-1]
+		n := &Node{Item: it}
+		parent.Children = append(parent.Children, n)
+
+		switch it.Tag {
+		case TAG_COLLECTION:
+			stack = append(stack, n)
+		case TAG_END_COLLECTION:
+			if len(stack) == 1 {
+				return nil, fmt.Errorf("Item %d: End Collection without a Collection", i)
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if len(stack) != 1 {
+		return nil, fmt.Errorf("%d Collection(s) not ended", len(stack)-1)
+	}
+	return root.Children, nil
+}
+
+// Format one line per item, its bytes then what it means, indented by collection:
+//
+//	0x05, 0x01,        // Usage Page (Generic Desktop Ctrls)
+//	0x09, 0x06,        // Usage (Keyboard)
+//	0xA1, 0x01,        // Collection (Application)
+//	0x05, 0x07,        //   Usage Page (Kbrd/Keypad)
+func Format(items []Item) string {
+	var sb strings.Builder
+	var page UsagePage
+	var depth int
+
+	for _, it := range items {
+		if it.Tag == TAG_END_COLLECTION && depth > 0 {
+			depth--
+		}
+		var hex []string
+		for _, b := range it.Bytes() {
+			hex = append(hex, fmt.Sprintf("0x%02X,", b))
+		}
+		fmt.Fprintf(&sb, "%-18s // %s%s\n", strings.Join(hex, " "), strings.Repeat("  ", depth), Describe(it, page))
+
+		switch it.Tag {
+		case TAG_USAGE_PAGE:
+			page = UsagePage(it.Unsigned())
+		case TAG_COLLECTION:
+			depth++
+		}
+	}
+	fmt.Fprintf(&sb, "// %d bytes\n", len(Encode(items)))
+	return sb.String()
+}
+
+// Describe what an item means, page is the Usage Page in effect for usages.
+func Describe(it Item, page UsagePage) string {
+	switch it.Tag {
+	case TAG_USAGE_PAGE:
+		return fmt.Sprintf("%s (%s)", it.Tag, UsagePage(it.Unsigned()))
+	case TAG_USAGE, TAG_USAGE_MIN, TAG_USAGE_MAX:
+		if len(it.Data) == 4 {
+			extended := UsagePage(it.Unsigned() >> 16)
+			return fmt.Sprintf("%s (%s: %s)", it.Tag, extended, UsageName(extended, it.Unsigned()&0xFFFF))
+		}
+		return fmt.Sprintf("%s (%s)", it.Tag, UsageName(page, it.Unsigned()))
+	case TAG_INPUT, TAG_OUTPUT, TAG_FEATURE:
+		return fmt.Sprintf("%s (%s)", it.Tag, MainFlags(it.Unsigned()))
+	case TAG_COLLECTION:
+		return fmt.Sprintf("%s (%s)", it.Tag, CollectionType(it.Unsigned()))
+	case TAG_UNIT:
+		if it.Unsigned() == 0 {
+			return "Unit (None)"
+		}
+		return fmt.Sprintf("%s (0x%X)", it.Tag, it.Unsigned())
+	case TAG_END_COLLECTION, TAG_PUSH, TAG_POP:
+		return it.Tag.String()
+	case Tag(LONG_ITEM):
+		return fmt.Sprintf("Long Item (%d bytes)", len(it.Data)-2)
+	}
+	return fmt.Sprintf("%s (%d)", it.Tag, it.Value())
+}
+
+// FormatLayout one line per report and field:
+//
+//	Input report, 8 bytes
+//	  bit    0  8x1   Data,Var,Abs             Kbrd/Keypad 0xE0-0xE7
+func FormatLayout(l Layout) string {
+	var sb strings.Builder
+
+	for _, r := range l.Reports {
+		if r.ID != 0 {
+			fmt.Fprintf(&sb, "%s report %#02x, %d bytes\n", r.Kind, r.ID, r.Size())
+		} else {
+			fmt.Fprintf(&sb, "%s report, %d bytes\n", r.Kind, r.Size())
+		}
+		for _, f := range r.Fields {
+			var usages string
+			switch {
+			case f.IsConstant():
+				usages = "padding"
+			case len(f.Usages) > 0:
+				var names []string
+				var last UsagePage
+				for i, u := range f.Usages {
+					page := f.UsagePage
+					if u > 0xFFFF {
+						page, u = UsagePage(u>>16), u&0xFFFF
+					}
+					if i == 0 || page != last {
+						names = append(names, fmt.Sprintf("%s: %s", page, UsageName(page, u)))
+					} else {
+						names = append(names, UsageName(page, u))
+					}
+					last = page
+				}
+				usages = strings.Join(names, ", ")
+			default:
+				usages = fmt.Sprintf("%s %s-%s", f.UsagePage, UsageName(f.UsagePage, f.UsageMin), UsageName(f.UsagePage, f.UsageMax))
+			}
+			fmt.Fprintf(&sb, "  bit %4d  %-5s %-24s %s\n", f.Offset, fmt.Sprintf("%dx%d", f.Count, f.Size), f.Flags, usages)
+		}
+	}
+	return sb.String()
+}
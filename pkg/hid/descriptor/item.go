@@ -0,0 +1,225 @@
+// Package descriptor builds and parses HID report descriptors, see the Device Class Definition for HID 1.11 section 6.2.2.
+// Build one with Builder, read one with Parse, Format shows it the way the comments in init/enable-rpi-hid do and
+// ParseLayout works out the size of each report and where its fields are.
+package descriptor
+
+import (
+	"fmt"
+)
+
+// Type the kind of an item, bits 2-3 of its prefix byte.
+type Type byte
+
+const (
+	TYPE_MAIN   Type = 0
+	TYPE_GLOBAL Type = 1
+	TYPE_LOCAL  Type = 2
+)
+
+// Tag an item's prefix byte without the size bits, so it names the item, e.g. TAG_USAGE_PAGE is 0x04.
+type Tag byte
+
+// Main items.
+const (
+	TAG_INPUT          Tag = 0x80
+	TAG_OUTPUT         Tag = 0x90
+	TAG_COLLECTION     Tag = 0xA0
+	TAG_FEATURE        Tag = 0xB0
+	TAG_END_COLLECTION Tag = 0xC0
+)
+
+// Global items, they apply to every main item that follows until changed.
+const (
+	TAG_USAGE_PAGE    Tag = 0x04
+	TAG_LOGICAL_MIN   Tag = 0x14
+	TAG_LOGICAL_MAX   Tag = 0x24
+	TAG_PHYSICAL_MIN  Tag = 0x34
+	TAG_PHYSICAL_MAX  Tag = 0x44
+	TAG_UNIT_EXPONENT Tag = 0x54
+	TAG_UNIT          Tag = 0x64
+	TAG_REPORT_SIZE   Tag = 0x74
+	TAG_REPORT_ID     Tag = 0x84
+	TAG_REPORT_COUNT  Tag = 0x94
+	TAG_PUSH          Tag = 0xA4
+	TAG_POP           Tag = 0xB4
+)
+
+// Local items, they apply to the next main item only.
+const (
+	TAG_USAGE            Tag = 0x08
+	TAG_USAGE_MIN        Tag = 0x18
+	TAG_USAGE_MAX        Tag = 0x28
+	TAG_DESIGNATOR_INDEX Tag = 0x38
+	TAG_DESIGNATOR_MIN   Tag = 0x48
+	TAG_DESIGNATOR_MAX   Tag = 0x58
+	TAG_STRING_INDEX     Tag = 0x78
+	TAG_STRING_MIN       Tag = 0x88
+	TAG_STRING_MAX       Tag = 0x98
+	TAG_DELIMITER        Tag = 0xA8
+)
+
+// LONG_ITEM the prefix of a long item, followed by its data size and tag. No descriptor in use has one, they are
+// kept as they are.
+const LONG_ITEM byte = 0xFE
+
+var tagNames = map[Tag]string{
+	TAG_INPUT:            "Input",
+	TAG_OUTPUT:           "Output",
+	TAG_COLLECTION:       "Collection",
+	TAG_FEATURE:          "Feature",
+	TAG_END_COLLECTION:   "End Collection",
+	TAG_USAGE_PAGE:       "Usage Page",
+	TAG_LOGICAL_MIN:      "Logical Minimum",
+	TAG_LOGICAL_MAX:      "Logical Maximum",
+	TAG_PHYSICAL_MIN:     "Physical Minimum",
+	TAG_PHYSICAL_MAX:     "Physical Maximum",
+	TAG_UNIT_EXPONENT:    "Unit Exponent",
+	TAG_UNIT:             "Unit",
+	TAG_REPORT_SIZE:      "Report Size",
+	TAG_REPORT_ID:        "Report ID",
+	TAG_REPORT_COUNT:     "Report Count",
+	TAG_PUSH:             "Push",
+	TAG_POP:              "Pop",
+	TAG_USAGE:            "Usage",
+	TAG_USAGE_MIN:        "Usage Minimum",
+	TAG_USAGE_MAX:        "Usage Maximum",
+	TAG_DESIGNATOR_INDEX: "Designator Index",
+	TAG_DESIGNATOR_MIN:   "Designator Minimum",
+	TAG_DESIGNATOR_MAX:   "Designator Maximum",
+	TAG_STRING_INDEX:     "String Index",
+	TAG_STRING_MIN:       "String Minimum",
+	TAG_STRING_MAX:       "String Maximum",
+	TAG_DELIMITER:        "Delimiter",
+}
+
+func (t Tag) Type() Type {
+	return Type(t>>2) & 0x03
+}
+
+func (t Tag) String() string {
+	if name, ok := tagNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("Unknown (%#02x)", byte(t))
+}
+
+// signed true for the items whose data is a signed number.
+func (t Tag) signed() bool {
+	switch t {
+	case TAG_LOGICAL_MIN, TAG_LOGICAL_MAX, TAG_PHYSICAL_MIN, TAG_PHYSICAL_MAX, TAG_UNIT_EXPONENT:
+		return true
+	}
+	return false
+}
+
+// Item one item of a descriptor, its data is 0, 1, 2 or 4 bytes, little endian.
+type Item struct {
+	Tag  Tag
+	Data []byte
+}
+
+// Bytes the item as it appears in a descriptor.
+func (it Item) Bytes() []byte {
+	var size byte
+
+	switch len(it.Data) {
+	case 0, 1, 2:
+		size = byte(len(it.Data))
+	default:
+		size = 3 // 4 bytes
+	}
+	return append([]byte{byte(it.Tag) | size}, it.Data...)
+}
+
+// Unsigned the item's data as an unsigned number.
+func (it Item) Unsigned() uint32 {
+	var v uint32
+	for i, b := range it.Data {
+		v |= uint32(b) << (8 * i)
+	}
+	return v
+}
+
+// Signed the item's data as a signed number, sign extended from its size.
+func (it Item) Signed() int32 {
+	switch len(it.Data) {
+	case 0:
+		return 0
+	case 1:
+		return int32(int8(it.Data[0]))
+	case 2:
+		return int32(int16(it.Unsigned()))
+	}
+	return int32(it.Unsigned())
+}
+
+// Value the item's data as a number, signed for the items that are.
+func (it Item) Value() int64 {
+	if it.Tag.signed() {
+		return int64(it.Signed())
+	}
+	return int64(it.Unsigned())
+}
+
+// SyntaxError a descriptor that can not be split into items.
+type SyntaxError struct {
+	Offset int
+	Msg    string
+}
+
+func (e SyntaxError) Error() string {
+	return fmt.Sprintf("Report descriptor byte %d: %s", e.Offset, e.Msg)
+}
+
+func (e SyntaxError) String() string {
+	return e.Error()
+}
+
+// Parse splits a descriptor into its items.
+func Parse(data []byte) ([]Item, error) {
+	var items []Item
+
+	for i := 0; i < len(data); {
+		prefix := data[i]
+		if prefix == LONG_ITEM {
+			if i+2 >= len(data) {
+				return items, SyntaxError{i, "long item is cut short"}
+			}
+			size := int(data[i+1])
+			if i+3+size > len(data) {
+				return items, SyntaxError{i, "long item is cut short"}
+			}
+			items = append(items, Item{Tag: Tag(LONG_ITEM), Data: data[i+1 : i+3+size]})
+			i += 3 + size
+			continue
+		}
+
+		size := int(prefix & 0x03)
+		if size == 3 {
+			size = 4
+		}
+		if i+1+size > len(data) {
+			return items, SyntaxError{i, fmt.Sprintf("%s needs %d bytes of data, %d left", Tag(prefix&^0x03), size, len(data)-i-1)}
+		}
+		tag := Tag(prefix &^ 0x03)
+		if tag.Type() == 3 {
+			return items, SyntaxError{i, fmt.Sprintf("reserved item type in prefix %#02x", prefix)}
+		}
+		items = append(items, Item{Tag: tag, Data: data[i+1 : i+1+size]})
+		i += 1 + size
+	}
+	return items, nil
+}
+
+// Encode the descriptor made of items. Encode(Parse(d)) is d.
+func Encode(items []Item) []byte {
+	var data []byte
+	for _, it := range items {
+		if it.Tag == Tag(LONG_ITEM) {
+			data = append(append(data, LONG_ITEM), it.Data...)
+			continue
+		}
+		data = append(data, it.Bytes()...)
+	}
+	return data
+}
@@ -0,0 +1,210 @@
+package descriptor
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ReportKind which way a report goes, Input is device to host.
+type ReportKind int
+
+const (
+	INPUT ReportKind = iota
+	OUTPUT
+	FEATURE
+)
+
+func (k ReportKind) String() string {
+	switch k {
+	case OUTPUT:
+		return "Output"
+	case FEATURE:
+		return "Feature"
+	}
+	return "Input"
+}
+
+// Field the values one Input, Output or Feature item adds to a report.
+type Field struct {
+	Offset     int // Offset in bits from the start of the report, after the report ID byte if there is one
+	Size       int // Size of each value in bits
+	Count      int
+	Flags      MainFlags
+	UsagePage  UsagePage
+	Usages     []uint32 // Usages listed one by one, an extended usage has its page in the high 16 bits
+	UsageMin   uint32
+	UsageMax   uint32
+	LogicalMin int32
+	LogicalMax int32
+}
+
+// Bits the total size of the field.
+func (f Field) Bits() int {
+	return f.Size * f.Count
+}
+
+// IsConstant true for padding.
+func (f Field) IsConstant() bool {
+	return f.Flags&CONSTANT != 0
+}
+
+// Report the fields of one report.
+type Report struct {
+	Kind   ReportKind
+	ID     byte // ID 0 when the descriptor does not use report IDs
+	Bits   int
+	Fields []Field
+}
+
+// Size the report's size in bytes as sent, including the report ID byte when there is one.
+func (r Report) Size() int {
+	size := (r.Bits + 7) / 8
+	if r.ID != 0 {
+		size++
+	}
+	return size
+}
+
+// Layout every report a descriptor defines, ordered by kind and ID.
+type Layout struct {
+	Reports []Report
+}
+
+// Report the report of kind with id, id 0 when the descriptor does not use report IDs.
+func (l Layout) Report(kind ReportKind, id byte) (Report, bool) {
+	for _, r := range l.Reports {
+		if r.Kind == kind && r.ID == id {
+			return r, true
+		}
+	}
+	return Report{}, false
+}
+
+// MaxSize the size of the largest report, what a gadget's report_length must be at least.
+func (l Layout) MaxSize() int {
+	var max int
+	for _, r := range l.Reports {
+		if r.Size() > max {
+			max = r.Size()
+		}
+	}
+	return max
+}
+
+// globals the global items in effect.
+type globals struct {
+	page       UsagePage
+	logicalMin int32
+	logicalMax int32
+	size       int
+	count      int
+	id         byte
+}
+
+// locals the local items since the last main item.
+type locals struct {
+	usages   []uint32
+	usageMin uint32
+	usageMax uint32
+}
+
+// ParseLayout works out each report's size and fields.
+func ParseLayout(data []byte) (Layout, error) {
+	items, err := Parse(data)
+	if err != nil {
+		return Layout{}, err
+	}
+	return LayoutOf(items)
+}
+
+// LayoutOf works out each report's size and fields from a descriptor's items.
+func LayoutOf(items []Item) (Layout, error) {
+	type key struct {
+		kind ReportKind
+		id   byte
+	}
+	var reports = map[key]*Report{}
+	var g globals
+	var l locals
+	var stack []globals
+	var depth int
+
+	for i, it := range items {
+		switch it.Tag {
+		case TAG_USAGE_PAGE:
+			g.page = UsagePage(it.Unsigned())
+		case TAG_LOGICAL_MIN:
+			g.logicalMin = it.Signed()
+		case TAG_LOGICAL_MAX:
+			g.logicalMax = it.Signed()
+		case TAG_REPORT_SIZE:
+			g.size = int(it.Unsigned())
+		case TAG_REPORT_COUNT:
+			g.count = int(it.Unsigned())
+		case TAG_REPORT_ID:
+			if it.Unsigned() == 0 || it.Unsigned() > 0xFF {
+				return Layout{}, fmt.Errorf("Item %d: report ID %d must be from 1 to 255", i, it.Unsigned())
+			}
+			g.id = byte(it.Unsigned())
+		case TAG_PUSH:
+			stack = append(stack, g)
+		case TAG_POP:
+			if len(stack) == 0 {
+				return Layout{}, fmt.Errorf("Item %d: Pop without Push", i)
+			}
+			g, stack = stack[len(stack)-1], stack[:len(stack)-1]
+		case TAG_USAGE:
+			l.usages = append(l.usages, it.Unsigned())
+		case TAG_USAGE_MIN:
+			l.usageMin = it.Unsigned()
+		case TAG_USAGE_MAX:
+			l.usageMax = it.Unsigned()
+		case TAG_COLLECTION:
+			depth++
+			l = locals{}
+		case TAG_END_COLLECTION:
+			if depth == 0 {
+				return Layout{}, fmt.Errorf("Item %d: End Collection without a Collection", i)
+			}
+			depth--
+			l = locals{}
+		case TAG_INPUT, TAG_OUTPUT, TAG_FEATURE:
+			kind := map[Tag]ReportKind{TAG_INPUT: INPUT, TAG_OUTPUT: OUTPUT, TAG_FEATURE: FEATURE}[it.Tag]
+			r, ok := reports[key{kind, g.id}]
+			if !ok {
+				r = &Report{Kind: kind, ID: g.id}
+				reports[key{kind, g.id}] = r
+			}
+			r.Fields = append(r.Fields, Field{
+				Offset:     r.Bits,
+				Size:       g.size,
+				Count:      g.count,
+				Flags:      MainFlags(it.Unsigned()),
+				UsagePage:  g.page,
+				Usages:     l.usages,
+				UsageMin:   l.usageMin,
+				UsageMax:   l.usageMax,
+				LogicalMin: g.logicalMin,
+				LogicalMax: g.logicalMax,
+			})
+			r.Bits += g.size * g.count
+			l = locals{}
+		}
+	}
+	if depth != 0 {
+		return Layout{}, fmt.Errorf("%d Collection(s) not ended", depth)
+	}
+
+	var layout Layout
+	for _, r := range reports {
+		layout.Reports = append(layout.Reports, *r)
+	}
+	sort.Slice(layout.Reports, func(i, j int) bool {
+		a, b := layout.Reports[i], layout.Reports[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.ID < b.ID
+	})
+	return layout, nil
+}
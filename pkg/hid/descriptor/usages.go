@@ -0,0 +1,94 @@
+package descriptor
+
+import "fmt"
+
+// UsagePage the data of a Usage Page item, see the HID Usage Tables.
+type UsagePage uint16
+
+const (
+	PAGE_GENERIC_DESKTOP UsagePage = 0x01
+	PAGE_SIMULATION      UsagePage = 0x02
+	PAGE_KEYBOARD        UsagePage = 0x07
+	PAGE_LED             UsagePage = 0x08
+	PAGE_BUTTON          UsagePage = 0x09
+	PAGE_CONSUMER        UsagePage = 0x0C
+	PAGE_DIGITIZER       UsagePage = 0x0D
+	PAGE_VENDOR          UsagePage = 0xFF00 // the first vendor defined page, they go up to 0xFFFF
+)
+
+var pageNames = map[UsagePage]string{
+	PAGE_GENERIC_DESKTOP: "Generic Desktop Ctrls",
+	PAGE_SIMULATION:      "Sim Ctrls",
+	PAGE_KEYBOARD:        "Kbrd/Keypad",
+	PAGE_LED:             "LEDs",
+	PAGE_BUTTON:          "Button",
+	PAGE_CONSUMER:        "Consumer",
+	PAGE_DIGITIZER:       "Digitizer",
+}
+
+func (p UsagePage) String() string {
+	if name, ok := pageNames[p]; ok {
+		return name
+	}
+	if p >= PAGE_VENDOR {
+		return fmt.Sprintf("Vendor Defined %#04X", uint16(p))
+	}
+	return fmt.Sprintf("%#02x", uint16(p))
+}
+
+// Generic Desktop usages.
+const (
+	USAGE_POINTER    uint16 = 0x01
+	USAGE_MOUSE      uint16 = 0x02
+	USAGE_JOYSTICK   uint16 = 0x04
+	USAGE_GAMEPAD    uint16 = 0x05
+	USAGE_KEYBOARD   uint16 = 0x06
+	USAGE_X          uint16 = 0x30
+	USAGE_Y          uint16 = 0x31
+	USAGE_Z          uint16 = 0x32
+	USAGE_RX         uint16 = 0x33
+	USAGE_RY         uint16 = 0x34
+	USAGE_RZ         uint16 = 0x35
+	USAGE_WHEEL      uint16 = 0x38
+	USAGE_HAT_SWITCH uint16 = 0x39
+)
+
+// USAGE_CONSUMER_CONTROL the application usage of a consumer control, on PAGE_CONSUMER.
+const USAGE_CONSUMER_CONTROL uint16 = 0x01
+
+var genericDesktopNames = map[uint16]string{
+	USAGE_POINTER:    "Pointer",
+	USAGE_MOUSE:      "Mouse",
+	USAGE_JOYSTICK:   "Joystick",
+	USAGE_GAMEPAD:    "Game Pad",
+	USAGE_KEYBOARD:   "Keyboard",
+	USAGE_X:          "X",
+	USAGE_Y:          "Y",
+	USAGE_Z:          "Z",
+	USAGE_RX:         "Rx",
+	USAGE_RY:         "Ry",
+	USAGE_RZ:         "Rz",
+	USAGE_WHEEL:      "Wheel",
+	USAGE_HAT_SWITCH: "Hat switch",
+}
+
+var ledNames = []string{"Undefined", "Num Lock", "Caps Lock", "Scroll Lock", "Compose", "Kana"}
+
+// UsageName a usage's name on page, or its number when it has none here.
+func UsageName(page UsagePage, usage uint32) string {
+	switch page {
+	case PAGE_GENERIC_DESKTOP:
+		if name, ok := genericDesktopNames[uint16(usage)]; ok {
+			return name
+		}
+	case PAGE_LED:
+		if int(usage) < len(ledNames) {
+			return ledNames[usage]
+		}
+	case PAGE_CONSUMER:
+		if usage == uint32(USAGE_CONSUMER_CONTROL) {
+			return "Consumer Control"
+		}
+	}
+	return fmt.Sprintf("0x%02X", usage)
+}
@@ -0,0 +1,246 @@
+// Package capture mirrors the reports a keyboard.File writes, and the LED reports it reads, into pcapng files that
+// Wireshark opens as a usbmon capture taken on the host. Each file starts with the keyboard's configuration and report
+// descriptors, so Wireshark's USB HID dissector decodes every report. Files are rotated once they reach MaxBytes.
+package capture
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/scirelli/turkey-pi/pkg/keyboard"
+	"github.com/scirelli/turkey-pi/pkg/usbmon"
+)
+
+const (
+	DEFAULT_MAX_BYTES int64  = 10 * 1024 * 1024
+	DEFAULT_MAX_FILES int    = 5
+	DEFAULT_PREFIX    string = "keyboard"
+	// The address the keyboard has in the capture, a host would give it one when it enumerates.
+	DEFAULT_BUS    int = 1
+	DEFAULT_DEVICE int = 2
+
+	ENDPOINT_IN  byte = 0x81
+	ENDPOINT_OUT byte = 0x01
+
+	// URB status of a submission still waiting for the device, -EINPROGRESS.
+	statusInProgress int    = -115
+	timeLayout       string = "20060102-150405"
+)
+
+// Capture writes reports to pcapng files in Dir named <Prefix>-<time>-<n>.pcapng, keeping at most MaxFiles of them.
+// Nothing is written until Start.
+type Capture struct {
+	Dir      string
+	Prefix   string // Prefix DEFAULT_PREFIX when empty
+	MaxBytes int64  // MaxBytes start a new file once one is this big, DEFAULT_MAX_BYTES when 0
+	MaxFiles int    // MaxFiles remove the oldest files beyond this many, DEFAULT_MAX_FILES when 0
+
+	mu      sync.Mutex
+	file    *os.File
+	writer  *usbmon.Writer
+	urb     uint64
+	started time.Time
+	reports int
+	stamp   string // stamp the time in the name of the last file opened
+	seq     int    // seq the number the next file opened within stamp's second gets
+}
+
+// Status what a Capture is doing.
+type Status struct {
+	Running bool       `json:"running"`
+	File    string     `json:"file,omitempty"`
+	Started *time.Time `json:"started,omitempty"`
+	Reports int        `json:"reports"`
+	Bytes   int64      `json:"bytes"`
+	Files   []string   `json:"files"`
+}
+
+// Start opens a new capture file. Starting a running Capture does nothing.
+func (c *Capture) Start() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.file != nil {
+		return nil
+	}
+	if err := c.open(time.Now()); err != nil {
+		return err
+	}
+	c.started, c.reports = time.Now(), 0
+	return nil
+}
+
+// Stop closes the capture file. Stopping a stopped Capture does nothing.
+func (c *Capture) Stop() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.close()
+}
+
+// Status whether the Capture is running, the file being written and the files kept.
+func (c *Capture) Status() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var s = Status{Running: c.file != nil, Reports: c.reports, Files: []string{}}
+	if c.file != nil {
+		started := c.started
+		s.File, s.Started, s.Bytes = filepath.Base(c.file.Name()), &started, c.writer.Written()
+	}
+	files, _ := c.files()
+	for _, f := range files {
+		s.Files = append(s.Files, filepath.Base(f))
+	}
+	return s
+}
+
+// Input records reports the keyboard sent the host, as many as data holds.
+func (c *Capture) Input(data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.file == nil {
+		return
+	}
+	var now time.Time = time.Now()
+	for len(data) > 0 {
+		n := keyboard.ReportSz
+		if len(data) < n {
+			n = len(data)
+		}
+		c.write(now, usbmon.Packet{Event: usbmon.EVENT_COMPLETE, Transfer: usbmon.TRANSFER_INTERRUPT, Endpoint: ENDPOINT_IN, Length: n, Data: data[:n]})
+		c.reports++
+		data = data[n:]
+	}
+}
+
+// Output records an LED report the host sent the keyboard.
+func (c *Capture) Output(data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.file == nil || len(data) == 0 {
+		return
+	}
+	c.write(time.Now(), usbmon.Packet{Event: usbmon.EVENT_SUBMIT, Transfer: usbmon.TRANSFER_INTERRUPT, Endpoint: ENDPOINT_OUT, Status: statusInProgress, Length: len(data), Data: data})
+	c.reports++
+}
+
+// write adds p to the current file, starting a new one first if it is full. A Capture that can not write stops
+// rather than fail the keyboard.
+func (c *Capture) write(now time.Time, p usbmon.Packet) {
+	if c.writer.Written() >= c.maxBytes() {
+		c.close()
+		if err := c.open(now); err != nil {
+			return
+		}
+	}
+	c.urb++
+	p.ID, p.Time, p.Bus, p.Device = c.urb, now, DEFAULT_BUS, DEFAULT_DEVICE
+	if err := c.writer.WritePacket(p); err != nil {
+		c.close()
+	}
+}
+
+// open starts a new file with the descriptors a host reads when it enumerates the keyboard, then removes old files.
+func (c *Capture) open(now time.Time) error {
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return err
+	}
+	// Files rotated within the same second are numbered so the names still sort oldest first. A number is never used
+	// twice in a second, even once its file is pruned, or the new file would sort before the ones it follows.
+	if stamp := now.Format(timeLayout); stamp != c.stamp {
+		c.stamp, c.seq = stamp, 0
+	}
+	var name string
+	for ; ; c.seq++ {
+		name = filepath.Join(c.Dir, fmt.Sprintf("%s-%s-%03d.pcapng", c.prefix(), c.stamp, c.seq))
+		if _, err := os.Stat(name); os.IsNotExist(err) {
+			break
+		}
+	}
+	c.seq++
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	w, err := usbmon.NewWriter(f, "usbmon"+fmt.Sprint(DEFAULT_BUS))
+	if err != nil {
+		f.Close()
+		return err
+	}
+	c.file, c.writer = f, w
+
+	for _, p := range enumeration() {
+		// A request and its answer share an URB, that is how Wireshark pairs them.
+		if p.Event == usbmon.EVENT_SUBMIT {
+			c.urb++
+		}
+		p.ID, p.Time, p.Bus, p.Device = c.urb, now, DEFAULT_BUS, DEFAULT_DEVICE
+		if err := c.writer.WritePacket(p); err != nil {
+			c.close()
+			return err
+		}
+	}
+	c.prune()
+	return nil
+}
+
+func (c *Capture) close() error {
+	if c.file == nil {
+		return nil
+	}
+	err := c.file.Close()
+	c.file, c.writer = nil, nil
+	return err
+}
+
+// files the capture files in Dir, oldest first.
+func (c *Capture) files() ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(c.Dir, c.prefix()+"-*.pcapng"))
+	sort.Strings(files)
+	return files, err
+}
+
+// prune removes the oldest files until MaxFiles are left, counting the one being written, which is never removed.
+func (c *Capture) prune() {
+	files, err := c.files()
+	if err != nil {
+		return
+	}
+	var max int = c.MaxFiles
+	if max <= 0 {
+		max = DEFAULT_MAX_FILES
+	}
+	var excess int = len(files) - max
+	for _, f := range files {
+		if excess <= 0 {
+			break
+		}
+		if c.file != nil && f == c.file.Name() {
+			continue
+		}
+		os.Remove(f)
+		excess--
+	}
+}
+
+func (c *Capture) prefix() string {
+	if c.Prefix == "" {
+		return DEFAULT_PREFIX
+	}
+	return strings.ReplaceAll(c.Prefix, string(filepath.Separator), "_")
+}
+
+func (c *Capture) maxBytes() int64 {
+	if c.MaxBytes <= 0 {
+		return DEFAULT_MAX_BYTES
+	}
+	return c.MaxBytes
+}
@@ -0,0 +1,169 @@
+package capture
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/scirelli/turkey-pi/pkg/keyboard"
+	"github.com/scirelli/turkey-pi/pkg/usbmon"
+)
+
+// readFile the packets in a capture file.
+func readFile(t *testing.T, name string) []usbmon.Packet {
+	t.Helper()
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	packets, err := usbmon.ReadCapture(f)
+	if err != nil {
+		t.Fatalf("%s: %v", filepath.Base(name), err)
+	}
+	return packets
+}
+
+// inputReports the input reports in packets after the enumeration every file starts with.
+func inputReports(t *testing.T, name string, packets []usbmon.Packet) []keyboard.Report {
+	t.Helper()
+	var reports []keyboard.Report
+	var enum = enumeration()
+
+	if len(packets) < len(enum) {
+		t.Fatalf("%s has %d packets, fewer than the enumeration", filepath.Base(name), len(packets))
+	}
+	for i, p := range enum {
+		if packets[i].Event != p.Event || packets[i].Endpoint != p.Endpoint || string(packets[i].Data) != string(p.Data) {
+			t.Errorf("%s packet %d is not the enumeration's", filepath.Base(name), i)
+		}
+	}
+	for _, p := range packets[len(enum):] {
+		data, ok := p.InputReport()
+		if !ok {
+			t.Errorf("%s has a packet that is not an input report: %+v", filepath.Base(name), p)
+			continue
+		}
+		var r keyboard.Report
+		copy(r[:], data)
+		reports = append(reports, r)
+	}
+	return reports
+}
+
+func TestCapture(t *testing.T) {
+	var c = Capture{Dir: t.TempDir()}
+	var press = keyboard.Report{0, 0, keyboard.KEYCODE_A}
+
+	c.Input(press[:])
+	if s := c.Status(); s.Running || len(s.Files) != 0 {
+		t.Fatalf("a stopped capture is %+v", s)
+	}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	c.Input(append(press[:], make([]byte, keyboard.ReportSz)...))
+	c.Output([]byte{byte(keyboard.LED_CAPS_LOCK)})
+	s := c.Status()
+	if !s.Running || s.Reports != 3 || len(s.Files) != 1 || s.File != s.Files[0] || s.Bytes == 0 {
+		t.Errorf("status %+v, want one file with 3 reports", s)
+	}
+	if err := c.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	packets := readFile(t, filepath.Join(c.Dir, s.File))
+	packets = packets[len(enumeration()):]
+	if len(packets) != 3 {
+		t.Fatalf("%d packets after the enumeration, want 3", len(packets))
+	}
+	if data, ok := packets[0].InputReport(); !ok || string(data) != string(press[:]) {
+		t.Errorf("first packet %+v, want the key press", packets[0])
+	}
+	if data, ok := packets[2].OutputReport(); !ok || len(data) != 1 || data[0] != byte(keyboard.LED_CAPS_LOCK) {
+		t.Errorf("last packet %+v, want the LED report", packets[2])
+	}
+	for i := 1; i < len(packets); i++ {
+		if packets[i].ID <= packets[i-1].ID {
+			t.Errorf("packet %d has URB %d after %d", i, packets[i].ID, packets[i-1].ID)
+		}
+	}
+}
+
+// Files rotated within a second are numbered in order and never reuse a number, the file being written counts
+// towards MaxFiles and every file kept reads back on its own.
+func TestRotation(t *testing.T) {
+	const reports = 60
+	var c = Capture{Dir: t.TempDir(), MaxBytes: 600, MaxFiles: 2}
+
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	var seen = map[string]bool{}
+	for i := 0; i < reports; i++ {
+		r := keyboard.Report{0, 0, byte(i)}
+		c.Input(r[:])
+		if name := c.Status().File; name != "" {
+			seen[name] = true
+		}
+		if files := c.Status().Files; len(files) > 2 {
+			t.Fatalf("%d files kept after report %d: %v", len(files), i, files)
+		}
+	}
+	current := c.Status().File
+	if err := c.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) < 3 {
+		t.Fatalf("wrote %d files, the test needs more than MaxFiles", len(seen))
+	}
+
+	files, err := c.files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || filepath.Base(files[1]) != current {
+		t.Fatalf("kept %v, want 2 files ending with %s", files, current)
+	}
+	var got []keyboard.Report
+	for _, name := range files {
+		got = append(got, inputReports(t, name, readFile(t, name))...)
+	}
+	// The files kept hold the last reports without a gap.
+	if len(got) == 0 {
+		t.Fatal("no reports kept")
+	}
+	for i, r := range got {
+		if want := byte(reports - len(got) + i); r[2] != want {
+			t.Errorf("kept report %d is % x, want the key %d", i, r, want)
+		}
+	}
+}
+
+// A file left from an earlier run is not overwritten, and not counted out of order.
+func TestRotationKeepsEarlierFiles(t *testing.T) {
+	var c = Capture{Dir: t.TempDir(), MaxFiles: 3}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	first := c.Status().File
+	c.Stop()
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	second := c.Status().File
+	c.Stop()
+
+	if first == second {
+		t.Fatalf("both runs wrote %s", first)
+	}
+	if files := c.Status().Files; len(files) != 2 || files[0] != first || files[1] != second {
+		t.Errorf("files %v, want %s then %s", files, first, second)
+	}
+}
@@ -0,0 +1,59 @@
+package capture
+
+import (
+	"github.com/scirelli/turkey-pi/pkg/keyboard"
+	"github.com/scirelli/turkey-pi/pkg/usbmon"
+)
+
+const (
+	USB_REQUEST_GET_DESCRIPTOR byte = 0x06
+	USB_DESCRIPTOR_CONFIG      byte = 0x02
+	USB_DESCRIPTOR_HID_REPORT  byte = 0x22
+)
+
+/*
+configDescriptor the configuration a host reads for the keyboard function, what the kernel's f_hid reports for the
+function init/enable-rpi-hid sets up. Wireshark learns from it that the endpoints belong to a HID interface.
+
+	Configuration  1 interface, bus powered, 250mA
+	Interface 0    HID, boot subclass, keyboard protocol, 2 endpoints
+	HID 1.11       one report descriptor
+	Endpoint 0x81  interrupt IN, 8 bytes, every 4ms
+	Endpoint 0x01  interrupt OUT, 8 bytes, every 4ms
+*/
+func configDescriptor() []byte {
+	var reportLength int = len(keyboard.ReportDescriptor)
+
+	return []byte{
+		0x09, 0x02, 0x29, 0x00, 0x01, 0x01, 0x00, 0x80, 0x7D,
+		0x09, 0x04, 0x00, 0x00, 0x02, 0x03, 0x01, 0x01, 0x00,
+		0x09, 0x21, 0x11, 0x01, 0x00, 0x01, USB_DESCRIPTOR_HID_REPORT, byte(reportLength), byte(reportLength >> 8),
+		0x07, 0x05, ENDPOINT_IN, 0x03, byte(keyboard.ReportSz), 0x00, 0x04,
+		0x07, 0x05, ENDPOINT_OUT, 0x03, byte(keyboard.ReportSz), 0x00, 0x04,
+	}
+}
+
+// enumeration the GET_DESCRIPTOR requests and answers a host makes for the configuration and the report descriptor.
+func enumeration() []usbmon.Packet {
+	var config []byte = configDescriptor()
+	var report []byte = keyboard.ReportDescriptor
+
+	return []usbmon.Packet{
+		getDescriptor(0x80, USB_DESCRIPTOR_CONFIG, len(config)),
+		{Event: usbmon.EVENT_COMPLETE, Transfer: usbmon.TRANSFER_CONTROL, Endpoint: usbmon.ENDPOINT_IN, Length: len(config), Data: config},
+		getDescriptor(0x81, USB_DESCRIPTOR_HID_REPORT, len(report)),
+		{Event: usbmon.EVENT_COMPLETE, Transfer: usbmon.TRANSFER_CONTROL, Endpoint: usbmon.ENDPOINT_IN, Length: len(report), Data: report},
+	}
+}
+
+// getDescriptor the submission of a GET_DESCRIPTOR request, to the device or the interface when requestType is 0x81.
+func getDescriptor(requestType byte, descriptor byte, length int) usbmon.Packet {
+	return usbmon.Packet{
+		Event:    usbmon.EVENT_SUBMIT,
+		Transfer: usbmon.TRANSFER_CONTROL,
+		Endpoint: usbmon.ENDPOINT_IN,
+		Status:   statusInProgress,
+		Length:   length,
+		Setup:    []byte{requestType, USB_REQUEST_GET_DESCRIPTOR, 0x00, descriptor, 0x00, 0x00, byte(length), byte(length >> 8)},
+	}
+}
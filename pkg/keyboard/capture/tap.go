@@ -0,0 +1,29 @@
+package capture
+
+import (
+	"github.com/scirelli/turkey-pi/pkg/keyboard"
+)
+
+// Tap a keyboard.Device that hands everything to Device and records what got through in Capture.
+type Tap struct {
+	keyboard.Device
+	Capture *Capture
+}
+
+// Write writes p to the device and records the reports it took.
+func (t *Tap) Write(p []byte) (n int, err error) {
+	n, err = t.Device.Write(p)
+	if n > 0 {
+		t.Capture.Input(p[:n])
+	}
+	return n, err
+}
+
+// Read reads an LED report from the device and records it.
+func (t *Tap) Read(p []byte) (n int, err error) {
+	n, err = t.Device.Read(p)
+	if n > 0 {
+		t.Capture.Output(p[:n])
+	}
+	return n, err
+}
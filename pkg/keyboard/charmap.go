@@ -0,0 +1,213 @@
+package keyboard
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// MAX_KEYCODE the largest keycode the report descriptor allows in the key array (Logical Maximum 101).
+const MAX_KEYCODE byte = 0x65
+
+// CharMap user defined rune to stroke mapping. Entries override the File's layout.
+type CharMap map[rune]Stroke
+
+// ParseCharMap builds a CharMap from the characterToKeyMap config.
+//
+// Keys are either a single character or its code point written as "U+xxxx". Values are a HID keyboard usage written
+// as "U+xxxx", optionally prefixed with modifiers joined by '+', e.g. "shift+U+0034" or "ctrl+alt+U+004c".
+// Entries with an empty value are labels and are skipped. Every invalid entry is reported, not just the first.
+//
+// A table keyed by JavaScript keyCodes, like the one in configs/serverConfig.json.bak, is rejected with a
+// KeyCodeMapError. Its keys look like code points but name keys, so loading it would type 'a' as keypad 1.
+func ParseCharMap(m map[string]string) (CharMap, error) {
+	var cm = CharMap{}
+	var errs CharMapErrors
+	var keyCode *KeyCodeMapError
+
+	for key, value := range m {
+		if value == "" {
+			continue
+		}
+		r, err := parseCharMapKey(key)
+		if err != nil {
+			errs = append(errs, &CharMapError{Key: key, Value: value, Reason: err.Error()})
+			continue
+		}
+		stroke, err := ParseKeySpec(value)
+		if err != nil {
+			errs = append(errs, &CharMapError{Key: key, Value: value, Reason: err.Error()})
+			continue
+		}
+		if isKeyCodeEntry(r, stroke) && (keyCode == nil || key < keyCode.Key) {
+			keyCode = &KeyCodeMapError{Key: key, Value: value}
+		}
+		cm[r] = stroke
+	}
+
+	if keyCode != nil {
+		return nil, keyCode
+	}
+	if len(errs) > 0 {
+		sort.Slice(errs, func(i, j int) bool { return errs[i].Key < errs[j].Key })
+		return cm, errs
+	}
+	return cm, nil
+}
+
+// LoadCharMapFile reads a JSON file of character to key entries. The file is either a plain object of entries or a
+// config file with a "characterToKeyMap" object.
+func LoadCharMapFile(fileName string) (CharMap, error) {
+	var raw map[string]json.RawMessage
+	var m map[string]string
+
+	byteValue, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(byteValue, &raw); err != nil {
+		return nil, err
+	}
+	if entries, ok := raw["characterToKeyMap"]; ok {
+		err = json.Unmarshal(entries, &m)
+	} else {
+		err = json.Unmarshal(byteValue, &m)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cm, err := ParseCharMap(m)
+	if keyCodeErr, ok := err.(*KeyCodeMapError); ok {
+		keyCodeErr.File = fileName
+	}
+	return cm, err
+}
+
+// Merge returns a new CharMap with the entries of o added to, and overriding, those of c.
+func (c CharMap) Merge(o CharMap) CharMap {
+	var merged = CharMap{}
+
+	for r, s := range c {
+		merged[r] = s
+	}
+	for r, s := range o {
+		merged[r] = s
+	}
+	return merged
+}
+
+// ParseKeySpec parses a key written as modifiers and a usage joined by '+', e.g. "shift+U+0034".
+func ParseKeySpec(spec string) (Stroke, error) {
+	var stroke Stroke
+
+	i := strings.LastIndex(strings.ToUpper(spec), "U+")
+	if i < 0 {
+		return stroke, fmt.Errorf("expected a usage written as U+xxxx")
+	}
+	if i > 0 {
+		if spec[i-1] != '+' {
+			return stroke, fmt.Errorf("expected '+' between modifiers and usage")
+		}
+		for _, name := range strings.Split(spec[:i-1], "+") {
+			modifier, ok := ModifierByName(name)
+			if !ok {
+				return stroke, fmt.Errorf("unknown modifier '%s'", name)
+			}
+			stroke.Modifier |= modifier
+		}
+	}
+
+	usage, err := strconv.ParseUint(spec[i+2:], 16, 16)
+	if err != nil {
+		return stroke, fmt.Errorf("invalid usage '%s'", spec[i:])
+	}
+	switch {
+	case usage <= uint64(MAX_KEYCODE):
+		stroke.Keycode = byte(usage)
+	case usage <= 0xFF && isModifierKey(byte(usage)):
+		// Modifier keys are sent as bits in the modifier byte, not in the key array.
+		stroke.Modifier |= modifierBit(byte(usage))
+	default:
+		return stroke, fmt.Errorf("unknown usage '%s', the keyboard supports U+0000 to U+%04X and modifiers U+00E0 to U+00E7", spec[i:], MAX_KEYCODE)
+	}
+
+	return stroke, nil
+}
+
+// isKeyCodeEntry an uppercase letter typed with its letter key and no shift. A code-point map never has one, a keyCode
+// table always does: keyCodes name keys, and the A key is 0x41 whichever case it types.
+func isKeyCodeEntry(r rune, stroke Stroke) bool {
+	return r >= 'A' && r <= 'Z' && stroke.Keycode >= KEYCODE_A && stroke.Keycode <= KEYCODE_Z &&
+		stroke.Modifier&(MODIFIER_KEY_LEFT_SHIFT|MODIFIER_KEY_RIGHT_SHIFT) == 0
+}
+
+func parseCharMapKey(key string) (rune, error) {
+	if utf8.RuneCountInString(key) == 1 {
+		r, _ := utf8.DecodeRuneInString(key)
+		return r, nil
+	}
+	if !strings.HasPrefix(strings.ToUpper(key), "U+") {
+		return 0, fmt.Errorf("expected a single character or a code point written as U+xxxx")
+	}
+	code, err := strconv.ParseUint(key[2:], 16, 32)
+	if err != nil || !utf8.ValidRune(rune(code)) {
+		return 0, fmt.Errorf("invalid code point '%s'", key)
+	}
+	return rune(code), nil
+}
+
+type CharMapError struct {
+	Key    string
+	Value  string
+	Reason string
+}
+
+func (e *CharMapError) Error() string {
+	return fmt.Sprintf("'%s': '%s' %s", e.Key, e.Value, e.Reason)
+}
+
+func (e *CharMapError) String() string {
+	return e.Error()
+}
+
+// CharMapErrors every invalid entry found while parsing a character map.
+type CharMapErrors []*CharMapError
+
+func (e CharMapErrors) Error() string {
+	var msgs []string = make([]string, len(e))
+
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d invalid character map entries: %s", len(e), strings.Join(msgs, "; "))
+}
+
+func (e CharMapErrors) String() string {
+	return e.Error()
+}
+
+// KeyCodeMapError a character map keyed by JavaScript keyCodes rather than by the characters to type.
+type KeyCodeMapError struct {
+	File  string //File the map was read from, empty for an inline map.
+	Key   string //Key the entry that gave it away.
+	Value string
+}
+
+func (e *KeyCodeMapError) Error() string {
+	var msg = fmt.Sprintf("Not a code-point map: '%s' types an uppercase letter with '%s' and no shift, "+
+		"it looks like a table of JavaScript keyCodes. Key entries by the character typed, e.g. \"A\": \"shift+U+0004\"", e.Key, e.Value)
+
+	if e.File != "" {
+		return e.File + ": " + msg
+	}
+	return msg
+}
+
+func (e *KeyCodeMapError) String() string {
+	return e.Error()
+}
@@ -0,0 +1,167 @@
+package keyboard
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseKeySpec(t *testing.T) {
+	var tests = []struct {
+		spec string
+		want Stroke
+		err  string // part of the error, empty when the spec is valid
+	}{
+		{"U+0004", Stroke{0, KEYCODE_A}, ""},
+		{"u+0004", Stroke{0, KEYCODE_A}, ""},
+		{"U+0", Stroke{0, KEYCODE_NIL}, ""},
+		{"shift+U+0034", Stroke{MODIFIER_KEY_LEFT_SHIFT, 0x34}, ""},
+		{"ctrl+alt+U+004c", Stroke{MODIFIER_KEY_LEFT_CTRL | MODIFIER_KEY_LEFT_ALT, 0x4c}, ""},
+		{"AltGr+U+0008", Stroke{MODIFIER_KEY_RIGHT_ALT, KEYCODE_E}, ""},
+		{"U+0065", Stroke{0, MAX_KEYCODE}, ""},
+		{"U+00E1", Stroke{MODIFIER_KEY_LEFT_SHIFT, KEYCODE_NIL}, ""},
+		{"ctrl+U+00e7", Stroke{MODIFIER_KEY_LEFT_CTRL | MODIFIER_KEY_RIGHT_GUI, KEYCODE_NIL}, ""},
+
+		{"", Stroke{}, "expected a usage"},
+		{"0x04", Stroke{}, "expected a usage"},
+		{"shiftU+0004", Stroke{}, "expected '+'"},
+		{"hyper+U+0004", Stroke{}, "unknown modifier 'hyper'"},
+		{"shift++U+0004", Stroke{}, "unknown modifier ''"},
+		{"U+zz", Stroke{}, "invalid usage 'U+zz'"},
+		{"U+", Stroke{}, "invalid usage"},
+		{"U+10000", Stroke{}, "invalid usage"},
+		{"U+0066", Stroke{}, "unknown usage 'U+0066'"},
+		{"U+0090", Stroke{}, "unknown usage"},
+		{"U+00E8", Stroke{}, "unknown usage"},
+	}
+
+	for _, test := range tests {
+		got, err := ParseKeySpec(test.spec)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("ParseKeySpec(%q) error %v, want one containing %q", test.spec, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseKeySpec(%q): %v", test.spec, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("ParseKeySpec(%q) = %v, want %v", test.spec, got, test.want)
+		}
+	}
+}
+
+func TestParseCharMap(t *testing.T) {
+	var tests = []struct {
+		name string
+		m    map[string]string
+		want CharMap
+		errs []string // the keys of the invalid entries, in order
+	}{
+		{
+			name: "characters and code points",
+			m:    map[string]string{"é": "altgr+U+0008", "U+00A7": "shift+U+0020", "u+000a": "U+0028", "a": "U+0014"},
+			want: CharMap{'é': {MODIFIER_KEY_RIGHT_ALT, KEYCODE_E}, '§': {MODIFIER_KEY_LEFT_SHIFT, KEYCODE_3}, '\n': {0, KEYCODE_ENTER}, 'a': {0, KEYCODE_Q}},
+		},
+		{
+			name: "labels are skipped",
+			m:    map[string]string{"Enter": "", "U+000d": "U+0028"},
+			want: CharMap{'\r': {0, KEYCODE_ENTER}},
+		},
+		{
+			name: "shifted uppercase letters",
+			m:    map[string]string{"A": "shift+U+0004", "Z": "rshift+U+001d"},
+			want: CharMap{'A': {MODIFIER_KEY_LEFT_SHIFT, KEYCODE_A}, 'Z': {MODIFIER_KEY_RIGHT_SHIFT, KEYCODE_Z}},
+		},
+		{
+			name: "every invalid entry is reported",
+			m:    map[string]string{"ab": "U+0004", "U+D800": "U+0004", "U+zz": "U+0004", "x": "U+0066", "y": "U+001c"},
+			want: CharMap{'y': {0, KEYCODE_Y}},
+			errs: []string{"U+D800", "U+zz", "ab", "x"},
+		},
+	}
+
+	for _, test := range tests {
+		got, err := ParseCharMap(test.m)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+		if test.errs == nil {
+			if err != nil {
+				t.Errorf("%s: %v", test.name, err)
+			}
+			continue
+		}
+		errs, ok := err.(CharMapErrors)
+		if !ok {
+			t.Errorf("%s: error %v, want CharMapErrors", test.name, err)
+			continue
+		}
+		var keys []string
+		for _, e := range errs {
+			keys = append(keys, e.Key)
+		}
+		if !reflect.DeepEqual(keys, test.errs) {
+			t.Errorf("%s: invalid entries %v, want %v", test.name, keys, test.errs)
+		}
+	}
+}
+
+// A table keyed by JavaScript keyCodes types the A key for 0x41, a code-point map would type 'A' with shift.
+func TestParseCharMapKeyCodes(t *testing.T) {
+	var m = map[string]string{"U+0041": "U+0004", "U+0042": "U+0005", "U+0061": "U+0059", "U+0090": "U+0053"}
+
+	cm, err := ParseCharMap(m)
+	keyCodeErr, ok := err.(*KeyCodeMapError)
+	if !ok {
+		t.Fatalf("error %v, want a KeyCodeMapError", err)
+	}
+	if cm != nil {
+		t.Errorf("a keyCode table gave the map %v", cm)
+	}
+	if keyCodeErr.Key != "U+0041" || !strings.Contains(keyCodeErr.Error(), "Not a code-point map") {
+		t.Errorf("error %q for key %s", keyCodeErr, keyCodeErr.Key)
+	}
+}
+
+func TestLoadCharMapFile(t *testing.T) {
+	var dir = t.TempDir()
+	var plain = filepath.Join(dir, "plain.json")
+	var config = filepath.Join(dir, "config.json")
+	var want = CharMap{'ü': {MODIFIER_KEY_RIGHT_ALT, KEYCODE_Y}}
+
+	if err := ioutil.WriteFile(plain, []byte(`{"ü": "altgr+U+001c"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(config, []byte(`{"debug": true, "characterToKeyMap": {"ü": "altgr+U+001c"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{plain, config} {
+		cm, err := LoadCharMapFile(name)
+		if err != nil || !reflect.DeepEqual(cm, want) {
+			t.Errorf("%s: %v, %v, want %v", filepath.Base(name), cm, err, want)
+		}
+	}
+
+	if _, err := LoadCharMapFile(filepath.Join(dir, "missing.json")); !os.IsNotExist(err) {
+		t.Errorf("missing file: %v", err)
+	}
+}
+
+func TestLoadCharMapFileKeyCodes(t *testing.T) {
+	const bak = "../../configs/serverConfig.json.bak"
+
+	_, err := LoadCharMapFile(bak)
+	keyCodeErr, ok := err.(*KeyCodeMapError)
+	if !ok {
+		t.Fatalf("%s: error %v, want a KeyCodeMapError", bak, err)
+	}
+	if keyCodeErr.File != bak || !strings.HasPrefix(keyCodeErr.Error(), bak+": ") {
+		t.Errorf("error %q does not name the file", keyCodeErr)
+	}
+}
@@ -0,0 +1,194 @@
+package keyboard
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+Consumer control, media keys on the HID Consumer page (0x0C).
+
+	The boot keyboard report only carries Keyboard page usages, so media keys are sent by a second HID function with
+	ConsumerReportDescriptor, usually /dev/hidg2. Its report is one 16 bit usage, little endian, 0 when nothing is
+	pressed. See init/enable-rpi-hid.
+*/
+const CONSUMER_REPORT_SZ int = 2
+
+type ConsumerReport [CONSUMER_REPORT_SZ]byte
+
+// MAX_CONSUMER_USAGE the largest usage ConsumerReportDescriptor allows.
+const MAX_CONSUMER_USAGE uint16 = 0x03FF
+
+// NewConsumerReport a report with usage pressed, CONSUMER_NONE releases it.
+func NewConsumerReport(usage uint16) ConsumerReport {
+	return ConsumerReport{byte(usage), byte(usage >> 8)}
+}
+
+const (
+	CONSUMER_NONE            uint16 = 0x000
+	CONSUMER_BRIGHTNESS_UP   uint16 = 0x06F
+	CONSUMER_BRIGHTNESS_DOWN uint16 = 0x070
+	CONSUMER_NEXT_TRACK      uint16 = 0x0B5
+	CONSUMER_PREV_TRACK      uint16 = 0x0B6
+	CONSUMER_STOP            uint16 = 0x0B7
+	CONSUMER_EJECT           uint16 = 0x0B8
+	CONSUMER_PLAY_PAUSE      uint16 = 0x0CD
+	CONSUMER_MUTE            uint16 = 0x0E2
+	CONSUMER_VOLUME_UP       uint16 = 0x0E9
+	CONSUMER_VOLUME_DOWN     uint16 = 0x0EA
+	CONSUMER_EMAIL           uint16 = 0x18A
+	CONSUMER_CALCULATOR      uint16 = 0x192
+	CONSUMER_FILE_BROWSER    uint16 = 0x194
+	CONSUMER_BROWSER         uint16 = 0x196
+	CONSUMER_SEARCH          uint16 = 0x221
+	CONSUMER_BROWSER_HOME    uint16 = 0x223
+	CONSUMER_BROWSER_BACK    uint16 = 0x224
+	CONSUMER_BROWSER_FORWARD uint16 = 0x225
+	CONSUMER_BROWSER_STOP    uint16 = 0x226
+	CONSUMER_BROWSER_REFRESH uint16 = 0x227
+	CONSUMER_BOOKMARKS       uint16 = 0x22A
+)
+
+// consumerNames usage names without the CONSUMER_ prefix.
+var consumerNames = map[string]uint16{
+	"BRIGHTNESS_UP":   CONSUMER_BRIGHTNESS_UP,
+	"BRIGHTNESS_DOWN": CONSUMER_BRIGHTNESS_DOWN,
+	"NEXT_TRACK":      CONSUMER_NEXT_TRACK,
+	"PREV_TRACK":      CONSUMER_PREV_TRACK,
+	"STOP":            CONSUMER_STOP,
+	"EJECT":           CONSUMER_EJECT,
+	"PLAY_PAUSE":      CONSUMER_PLAY_PAUSE,
+	"MUTE":            CONSUMER_MUTE,
+	"VOLUME_UP":       CONSUMER_VOLUME_UP,
+	"VOLUME_DOWN":     CONSUMER_VOLUME_DOWN,
+	"EMAIL":           CONSUMER_EMAIL,
+	"CALCULATOR":      CONSUMER_CALCULATOR,
+	"FILE_BROWSER":    CONSUMER_FILE_BROWSER,
+	"BROWSER":         CONSUMER_BROWSER,
+	"SEARCH":          CONSUMER_SEARCH,
+	"BROWSER_HOME":    CONSUMER_BROWSER_HOME,
+	"BROWSER_BACK":    CONSUMER_BROWSER_BACK,
+	"BROWSER_FORWARD": CONSUMER_BROWSER_FORWARD,
+	"BROWSER_STOP":    CONSUMER_BROWSER_STOP,
+	"BROWSER_REFRESH": CONSUMER_BROWSER_REFRESH,
+	"BOOKMARKS":       CONSUMER_BOOKMARKS,
+}
+
+// consumerAliases other names media keys are known by, including the QMK keycode names.
+var consumerAliases = map[string]uint16{
+	"PLAY":          CONSUMER_PLAY_PAUSE,
+	"PAUSE":         CONSUMER_PLAY_PAUSE,
+	"NEXT":          CONSUMER_NEXT_TRACK,
+	"PREVIOUS":      CONSUMER_PREV_TRACK,
+	"PREV":          CONSUMER_PREV_TRACK,
+	"VOL_UP":        CONSUMER_VOLUME_UP,
+	"VOL_DOWN":      CONSUMER_VOLUME_DOWN,
+	"BACK":          CONSUMER_BROWSER_BACK,
+	"FORWARD":       CONSUMER_BROWSER_FORWARD,
+	"REFRESH":       CONSUMER_BROWSER_REFRESH,
+	"HOME":          CONSUMER_BROWSER_HOME,
+	"MEDIA_PLAY":    CONSUMER_PLAY_PAUSE,
+	"MEDIA_NEXT":    CONSUMER_NEXT_TRACK,
+	"MEDIA_PREV":    CONSUMER_PREV_TRACK,
+	"MEDIA_STOP":    CONSUMER_STOP,
+	"AUDIO_MUTE":    CONSUMER_MUTE,
+	"AUDIO_VOL_UP":  CONSUMER_VOLUME_UP,
+	"AUDIO_VOL_DN":  CONSUMER_VOLUME_DOWN,
+	"WWW_BACK":      CONSUMER_BROWSER_BACK,
+	"WWW_FORWARD":   CONSUMER_BROWSER_FORWARD,
+	"WWW_REFRESH":   CONSUMER_BROWSER_REFRESH,
+	"WWW_HOME":      CONSUMER_BROWSER_HOME,
+	"WWW_SEARCH":    CONSUMER_SEARCH,
+	"WWW_FAVORITES": CONSUMER_BOOKMARKS,
+}
+
+// ConsumerUsageByName find a consumer usage by name, e.g. "volume_up" or "play_pause". Names are case insensitive and
+// '-' or ' ' may be used for '_'. A usage can also be given by number as "U+00E9".
+func ConsumerUsageByName(name string) (uint16, error) {
+	var normalized = strings.ToUpper(strings.NewReplacer("-", "_", " ", "_").Replace(strings.TrimSpace(name)))
+
+	if usage, ok := consumerNames[normalized]; ok {
+		return usage, nil
+	}
+	if usage, ok := consumerAliases[normalized]; ok {
+		return usage, nil
+	}
+	if strings.HasPrefix(normalized, "U+") {
+		usage, err := strconv.ParseUint(normalized[2:], 16, 16)
+		if err == nil && uint16(usage) <= MAX_CONSUMER_USAGE {
+			return uint16(usage), nil
+		}
+	}
+	return CONSUMER_NONE, fmt.Errorf("Unknown media key '%s', expected one of: %s", name, strings.Join(ConsumerUsageNames(), ", "))
+}
+
+// ConsumerUsageNames the canonical usage names.
+func ConsumerUsageNames() []string {
+	var names []string = make([]string, 0, len(consumerNames))
+	for name := range consumerNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ConsumerFile represents the consumer control device file in user space /dev/hidg<#>
+type ConsumerFile struct {
+	os.File
+	StrokeDelay time.Duration //StrokeDelay how long a media key is held and the wait after releasing it.
+
+	mu sync.Mutex
+}
+
+// Tap presses and releases usage.
+func (f *ConsumerFile) Tap(usage uint16) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.tap(usage)
+}
+
+// TapAll taps each usage in turn.
+func (f *ConsumerFile) TapAll(usages ...uint16) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, usage := range usages {
+		if err := f.tap(usage); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *ConsumerFile) tap(usage uint16) error {
+	for _, r := range []ConsumerReport{NewConsumerReport(usage), NewConsumerReport(CONSUMER_NONE)} {
+		if _, err := f.File.Write(r[:]); err != nil {
+			return err
+		}
+		time.Sleep(f.StrokeDelay)
+	}
+	return nil
+}
+
+// ConsumerReportDescriptor the HID report descriptor for ConsumerReport, written to report_desc of a hid function with
+// protocol 0, subclass 0 and report_length 2.
+var ConsumerReportDescriptor = []byte{
+	0x05, 0x0C, // Usage Page (Consumer)
+	0x09, 0x01, // Usage (Consumer Control)
+	0xA1, 0x01, // Collection (Application)
+	0x15, 0x00, //   Logical Minimum (0)
+	0x26, 0xFF, 0x03, //   Logical Maximum (1023)
+	0x19, 0x00, //   Usage Minimum (Unassigned)
+	0x2A, 0xFF, 0x03, //   Usage Maximum (0x03FF)
+	0x75, 0x10, //   Report Size (16)
+	0x95, 0x01, //   Report Count (1)
+	0x81, 0x00, //   Input (Data,Array,Abs)
+	0xC0, // End Collection
+	// 23 bytes
+}
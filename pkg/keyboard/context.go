@@ -0,0 +1,222 @@
+package keyboard
+
+import (
+	"context"
+	"time"
+)
+
+// WriteStringContext types s like WriteStringDelayed but stops between strokes once ctx is done. runes is the number
+// of characters typed before it stopped.
+func (f *File) WriteStringContext(ctx context.Context, s string) (runes int, err error) {
+	return f.WriteStringContextWith(ctx, s, Options{})
+}
+
+// WriteStringContextWith types s with opts like WriteStringDelayedWith but stops between strokes once ctx is done,
+// returning ctx.Err(). A release report is always sent last so no key stays down, keys held with Press stay down.
+// runes counts the characters of text typed, escaped keys and waits are not counted. opts.Progress is called as it
+// grows.
+func (f *File) WriteStringContextWith(ctx context.Context, s string, opts Options) (runes int, err error) {
+	defer func() {
+		r := f.releaseReport()
+		if _, rerr := f.Device.Write(r[:]); err == nil {
+			err = rerr
+		}
+	}()
+
+	runes, _, err = f.writeContext(ctx, s, opts)
+	return runes, err
+}
+
+// writeContext types s one stroke at a time, waiting between reports as the timing profile says.
+func (f *File) writeContext(ctx context.Context, s string, opts Options) (runes int, n int, err error) {
+	tokens, err := tokenize(s, opts)
+	if err != nil {
+		return 0, 0, err
+	}
+	sess, restore, err := f.startSession(tokens, opts)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func() {
+		if rerr := restore(); err == nil {
+			err = rerr
+		}
+	}()
+	pace := newPacer()
+	defer func() { f.setPaceStats(pace.stats()) }()
+
+	for _, token := range tokens {
+		var written int
+
+		switch token.Kind {
+		case TokenText:
+			if f.OptimizeReports {
+				written, err = f.typePlanned(ctx, pace, sess, token.Text)
+			} else {
+				written, err = f.typeStrokes(ctx, pace, sess, token.Text)
+			}
+			n += written
+		case TokenChord:
+			written, err = f.strokeContext(ctx, pace, token.Modifiers, token.Keys, sess.rhythm.hold(), sess.rhythm.gap(0))
+			n += written
+		case TokenWait:
+			err = pace.wait(ctx, token.Wait)
+		}
+		if err != nil {
+			return sess.runes, n, err
+		}
+	}
+	return sess.runes, n, nil
+}
+
+// typeStrokes types text one stroke at a time, a press then a release.
+func (f *File) typeStrokes(ctx context.Context, pace *pacer, sess *session, text string) (n int, err error) {
+	for _, c := range text {
+		strokes := sess.strokes(c)
+		for i, stroke := range strokes {
+			// Only the last stroke finishes the character, dead keys before it get a plain gap.
+			var after rune
+			if i == len(strokes)-1 {
+				after = c
+			}
+			written, err := f.strokeKeepingContext(ctx, pace, stroke.Modifier, []byte{stroke.Keycode}, keptModifiers(strokes, i), sess.rhythm.hold(), sess.rhythm.gap(after))
+			n += written
+			if err != nil {
+				return n, err
+			}
+		}
+		sess.typed()
+	}
+	return n, nil
+}
+
+// typePlanned types text with the reports planned by planFrames. A key stays down until the next one replaces it when
+// the gap is no longer than the hold. Otherwise it is released after the hold, keeping the modifiers the next report
+// holds as well, so pauses after punctuation and newlines do not hold a key long enough for the host to repeat it.
+func (f *File) typePlanned(ctx context.Context, pace *pacer, sess *session, text string) (n int, err error) {
+	var strokes []Stroke
+	var ends []rune //ends the character each stroke finishes, 0 for dead keys.
+
+	for _, c := range text {
+		charStrokes := sess.strokes(c)
+		for i, stroke := range charStrokes {
+			strokes = append(strokes, stroke)
+			if i == len(charStrokes)-1 {
+				ends = append(ends, c)
+			} else {
+				ends = append(ends, 0)
+			}
+		}
+	}
+
+	var frames []frame = planFrames(strokes)
+	var released *Report // released the report sent after the last key's hold, nil while it is down
+	var counted int      // counted the strokes whose characters were passed to sess.typed
+	// typedUpTo counts the characters finished by strokes up to last, including those of strokes that were planned
+	// away, such as a rune no key types.
+	var typedUpTo = func(last int) {
+		for ; counted <= last; counted++ {
+			if ends[counted] != 0 {
+				sess.typed()
+			}
+		}
+	}
+
+	for i, fr := range frames {
+		if err = ctx.Err(); err != nil {
+			r := f.releaseReport()
+			written, _ := f.Device.Write(r[:])
+			return n + written, err
+		}
+		r, err := f.pressReport(fr.state.Modifier, fr.state.Keycode)
+		if err != nil {
+			return n, err
+		}
+		if fr.stroke < 0 && released != nil && r == *released {
+			// The key was already let go after its hold.
+			released = nil
+			continue
+		}
+		released = nil
+		written, err := f.Device.Write(r[:])
+		n += written
+		if err != nil {
+			return n, err
+		}
+		if fr.stroke < 0 {
+			pace.wait(ctx, sess.rhythm.hold())
+			continue
+		}
+		pace.strokes++
+		typedUpTo(fr.stroke)
+		hold, gap := sess.rhythm.hold(), sess.rhythm.gap(ends[fr.stroke])
+		if gap <= hold || fr.state.Keycode == KEYCODE_NIL {
+			pace.wait(ctx, gap)
+			continue
+		}
+		pace.wait(ctx, hold)
+		var keep byte
+		if i+1 < len(frames) {
+			keep = fr.state.Modifier & frames[i+1].state.Modifier
+		}
+		release := f.releaseReportKeeping(keep)
+		written, err = f.Device.Write(release[:])
+		n += written
+		if err != nil {
+			return n, err
+		}
+		released = &release
+		pace.wait(ctx, gap-hold)
+	}
+	typedUpTo(len(strokes) - 1)
+	return n, nil
+}
+
+// strokeContext presses modifiers and keys for hold then releases them and waits gap, keeping to pace's schedule. It
+// does not start if ctx is done, once pressed the keys are always released.
+func (f *File) strokeContext(ctx context.Context, pace *pacer, modifiers byte, keys []byte, hold, gap time.Duration) (n int, err error) {
+	return f.strokeKeepingContext(ctx, pace, modifiers, keys, MODIFIER_NOT_SET, hold, gap)
+}
+
+// strokeKeepingContext strokeContext that keeps the modifiers in keep down when it releases the keys.
+func (f *File) strokeKeepingContext(ctx context.Context, pace *pacer, modifiers byte, keys []byte, keep byte, hold, gap time.Duration) (n int, err error) {
+	var totalBytes int
+
+	if err = ctx.Err(); err != nil {
+		return 0, err
+	}
+	r, err := f.pressReport(modifiers, keys...)
+	if err != nil {
+		return 0, err
+	}
+	if n, err = f.Device.Write(r[:]); err != nil {
+		return totalBytes + n, err
+	}
+	totalBytes += n
+	// A cancelled wait cuts the delay short, the stroke still completes and the next one is not started.
+	pace.wait(ctx, hold)
+	r = f.releaseReportKeeping(keep)
+	if n, err = f.Device.Write(r[:]); err != nil {
+		return totalBytes + n, err
+	}
+	totalBytes += n
+	pace.strokes++
+	pace.wait(ctx, gap)
+	return totalBytes, nil
+}
+
+// sleepContext sleeps for d or until ctx is done, whichever is first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	var timer = time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
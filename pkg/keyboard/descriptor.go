@@ -0,0 +1,48 @@
+package keyboard
+
+import (
+	"github.com/scirelli/turkey-pi/pkg/hid/descriptor"
+)
+
+// ReportDescriptor the boot keyboard report descriptor for Report and the LED output report, written to report_desc of
+// a hid function with protocol 1 (keyboard) and report_length 8. Byte for byte the descriptor init/enable-rpi-hid echoes,
+// `hiddescriptor keyboard` prints what each item means.
+var ReportDescriptor = new(descriptor.Builder).
+	UsagePage(descriptor.PAGE_GENERIC_DESKTOP).
+	Usage(descriptor.USAGE_KEYBOARD).
+	Collection(descriptor.COLLECTION_APPLICATION).
+	// Modifier flags
+	UsagePage(descriptor.PAGE_KEYBOARD).
+	UsageMin(0xE0).
+	UsageMax(0xE7).
+	LogicalMin(0).
+	LogicalMax(1).
+	ReportSize(1).
+	ReportCount(8).
+	Input(descriptor.DATA | descriptor.VARIABLE | descriptor.ABSOLUTE).
+	// Reserved byte
+	ReportCount(1).
+	ReportSize(8).
+	Input(descriptor.CONSTANT | descriptor.VARIABLE | descriptor.ABSOLUTE).
+	// LEDs
+	ReportCount(5).
+	ReportSize(1).
+	UsagePage(descriptor.PAGE_LED).
+	UsageMin(0x01).
+	UsageMax(0x05).
+	Output(descriptor.DATA | descriptor.VARIABLE | descriptor.ABSOLUTE).
+	// LED padding
+	ReportCount(1).
+	ReportSize(3).
+	Output(descriptor.CONSTANT | descriptor.VARIABLE | descriptor.ABSOLUTE).
+	// Key presses
+	ReportCount(6).
+	ReportSize(8).
+	LogicalMin(0).
+	LogicalMax(0x65).
+	UsagePage(descriptor.PAGE_KEYBOARD).
+	UsageMin(0x00).
+	UsageMax(0x65).
+	Input(descriptor.DATA | descriptor.ARRAY | descriptor.ABSOLUTE).
+	EndCollection().
+	Bytes()
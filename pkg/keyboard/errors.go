@@ -0,0 +1,16 @@
+package keyboard
+
+import "fmt"
+
+// RolloverError too many keys pressed at once, the report only has room for ROLLOVER keycodes.
+type RolloverError struct {
+	Keys int
+}
+
+func (e *RolloverError) Error() string {
+	return fmt.Sprintf("Too many keys, %d pressed at once but the keyboard supports %d", e.Keys, ROLLOVER)
+}
+
+func (e *RolloverError) String() string {
+	return e.Error()
+}
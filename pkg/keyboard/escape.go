@@ -0,0 +1,151 @@
+package keyboard
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+/*
+Escape syntax
+	Special keys can be written inline in text by enabling Options.Escapes.
+
+	{ENTER}            taps a named key, see KeyByName
+	{CTRL+SHIFT+ESC}   presses modifiers and keys together as a chord, see ModifierByName
+	{WAIT 500}         pauses for 500ms
+	{{                 a literal '{'
+
+	A '}' outside of braces is typed as is.
+*/
+
+type TokenKind int
+
+const (
+	TokenText TokenKind = iota
+	TokenChord
+	TokenWait
+)
+
+// Token a piece of escaped text.
+type Token struct {
+	Kind   TokenKind
+	Offset int //Offset in bytes of the token in the input.
+
+	Text      string        //Text to type, TokenText only.
+	Modifiers byte          //Modifiers held for the chord, TokenChord only.
+	Keys      []byte        //Keys pressed for the chord, TokenChord only.
+	Wait      time.Duration //Wait pause length, TokenWait only.
+}
+
+// ParseEscapes splits s into text, chords and waits.
+func ParseEscapes(s string) ([]Token, error) {
+	var tokens []Token
+	var text strings.Builder
+	var textStart int
+
+	flush := func() {
+		if text.Len() > 0 {
+			tokens = append(tokens, Token{Kind: TokenText, Offset: textStart, Text: text.String()})
+			text.Reset()
+		}
+	}
+
+	for i := 0; i < len(s); {
+		if s[i] != '{' {
+			if text.Len() == 0 {
+				textStart = i
+			}
+			_, size := utf8.DecodeRuneInString(s[i:])
+			text.WriteString(s[i : i+size])
+			i += size
+			continue
+		}
+		if strings.HasPrefix(s[i:], "{{") {
+			if text.Len() == 0 {
+				textStart = i
+			}
+			text.WriteByte('{')
+			i += 2
+			continue
+		}
+
+		end := strings.IndexAny(s[i+1:], "{}\n")
+		if end < 0 || s[i+1+end] != '}' {
+			return tokens, newSyntaxError(s, i, "unclosed '{', use '{{' for a literal brace")
+		}
+		flush()
+		token, err := parseEscape(s, i+1, s[i+1:i+1+end])
+		if err != nil {
+			return tokens, err
+		}
+		token.Offset = i
+		tokens = append(tokens, token)
+		i += end + 2
+	}
+	flush()
+
+	return tokens, nil
+}
+
+// parseEscape parses the body of a {...} escape starting at offset in s.
+func parseEscape(s string, offset int, body string) (Token, error) {
+	if strings.HasPrefix(strings.ToUpper(body), "WAIT") {
+		arg := strings.TrimSpace(body[len("WAIT"):])
+		ms, err := strconv.Atoi(arg)
+		if err != nil || ms < 0 {
+			return Token{}, newSyntaxError(s, offset+len("WAIT"), fmt.Sprintf("WAIT expects milliseconds, got '%s'", arg))
+		}
+		return Token{Kind: TokenWait, Wait: time.Duration(ms) * time.Millisecond}, nil
+	}
+
+	var token = Token{Kind: TokenChord}
+	var pos int = offset
+	for _, name := range strings.Split(body, "+") {
+		if strings.TrimSpace(name) == "" {
+			return Token{}, newSyntaxError(s, pos, "missing key name")
+		}
+		if modifier, ok := ModifierByName(name); ok {
+			token.Modifiers |= modifier
+		} else if key, ok := KeyByName(name); ok {
+			token.Keys = append(token.Keys, key)
+		} else {
+			return Token{}, newSyntaxError(s, pos, fmt.Sprintf("unknown key '%s'", strings.TrimSpace(name)))
+		}
+		pos += len(name) + 1
+	}
+	if countKeys(token.Keys) > ROLLOVER {
+		return Token{}, newSyntaxError(s, offset, (&RolloverError{Keys: countKeys(token.Keys)}).Error())
+	}
+
+	return token, nil
+}
+
+// SyntaxError an invalid escape, Line and Column are 1 based and count characters, not bytes.
+type SyntaxError struct {
+	Offset int
+	Line   int
+	Column int
+	Msg    string
+}
+
+func newSyntaxError(s string, offset int, msg string) *SyntaxError {
+	var line = strings.Count(s[:offset], "\n") + 1
+	var lineStart = strings.LastIndex(s[:offset], "\n") + 1
+
+	return &SyntaxError{
+		Offset: offset,
+		Line:   line,
+		Column: utf8.RuneCountInString(s[lineStart:offset]) + 1,
+		Msg:    msg,
+	}
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("line %d column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+func (e *SyntaxError) String() string {
+	return e.Error()
+}
@@ -0,0 +1,4 @@
+package keyboard
+
+// RoundTripTexts the texts the planner tests type on each layout, for the round trips through hidtest.
+var RoundTripTexts = roundTripTexts
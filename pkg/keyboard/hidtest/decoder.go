@@ -0,0 +1,427 @@
+package hidtest
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/scirelli/turkey-pi/pkg/keyboard"
+)
+
+// MAX_KEYCODE the largest keycode the keyboard's report descriptor allows, a host ignores anything above it.
+const MAX_KEYCODE byte = 0x65
+
+// Key error codes a keyboard reports in every key slot when it can not tell which keys are down.
+const (
+	KEYCODE_ERROR_ROLLOVER  byte = 0x01
+	KEYCODE_POST_FAIL       byte = 0x02
+	KEYCODE_ERROR_UNDEFINED byte = 0x03
+)
+
+// EventKind whether a key went down or came up.
+type EventKind string
+
+const (
+	KEY_DOWN EventKind = "down"
+	KEY_UP   EventKind = "up"
+)
+
+// Event a key going down or coming up as the host sees it.
+type Event struct {
+	Time      time.Time
+	Kind      EventKind
+	Keycode   byte
+	Modifiers byte // Modifiers held when the key changed
+	Rune      rune // Rune what the key typed when it went down, 0 for nothing
+}
+
+// ProblemKind what went wrong with a report.
+type ProblemKind string
+
+const (
+	// PROBLEM_GHOST a key the host would see that nobody meant to press: an error code, a keycode past MAX_KEYCODE or
+	// the same key twice in one report. A report with an error code is ignored, the host keeps the keys it had down.
+	PROBLEM_GHOST ProblemKind = "ghost"
+	// PROBLEM_STUCK a key held longer than the host's repeat delay, so it repeats, or never released.
+	PROBLEM_STUCK ProblemKind = "stuck"
+	// PROBLEM_DROPPED a report holding the same keys and modifiers as the one before it, the host sees no new key go
+	// down so the stroke it was meant to type is lost.
+	PROBLEM_DROPPED ProblemKind = "dropped"
+)
+
+// Problem a report the host would not turn into what was meant.
+type Problem struct {
+	Kind    ProblemKind
+	Report  int // Report index of the report in the stream, the stream's length for keys left down at the end
+	Time    time.Time
+	Keycode byte
+}
+
+func (p Problem) String() string {
+	var name string = keyboard.KeyName(p.Keycode)
+
+	switch p.Keycode {
+	case KEYCODE_ERROR_ROLLOVER:
+		name = "ERROR_ROLLOVER"
+	case KEYCODE_POST_FAIL:
+		name = "POST_FAIL"
+	case KEYCODE_ERROR_UNDEFINED:
+		name = "ERROR_UNDEFINED"
+	}
+	return fmt.Sprintf("%s key %s in report %d", p.Kind, name, p.Report)
+}
+
+// Decoder plays reports back the way a host's keyboard driver does: it tracks which keys and modifiers are down and
+// types what each key going down means on the host's layout, including its Caps Lock and Num Lock state.
+type Decoder struct {
+	Layout   *keyboard.Layout // Layout the host's layout, US when nil
+	CapsLock bool
+	NumLock  bool
+	// RepeatDelay how long the host waits before repeating a held key, 0 never repeats. A key held longer is stuck.
+	RepeatDelay time.Duration
+	// InputMethod the host's Unicode input method, one of the keyboard.TARGET_ names or empty for none, see
+	// keyboard.UnicodeEntry.
+	InputMethod string
+
+	Events   []Event
+	Problems []Problem
+
+	text    []rune
+	prev    keyboard.Report
+	phantom bool // phantom the last report was ignored, the next one may repeat the keys still down
+	n       int
+	down    map[byte]time.Time
+	repeats map[byte]bool
+	dead    rune
+	last    time.Time
+	entry   *entry
+}
+
+// NewDecoder a Decoder for a host using layout, US when nil, with Num Lock on like most hosts.
+func NewDecoder(layout *keyboard.Layout) *Decoder {
+	return &Decoder{Layout: layout, NumLock: true}
+}
+
+// Decode decodes reports with a new Decoder and calls Finish.
+func Decode(layout *keyboard.Layout, reports []Report) *Decoder {
+	var d *Decoder = NewDecoder(layout)
+
+	for _, r := range reports {
+		d.Decode(r)
+	}
+	d.Finish()
+	return d
+}
+
+// Decode the host receives r.
+func (d *Decoder) Decode(r Report) {
+	if d.down == nil {
+		d.down, d.repeats = map[byte]time.Time{}, map[byte]bool{}
+	}
+	keys, ghosts := d.keys(r.Data)
+	for _, key := range ghosts {
+		d.problem(PROBLEM_GHOST, r.Time, key)
+	}
+	d.checkRepeats(r.Time)
+
+	// Hosts throw away a report in the phantom state, as if it never arrived.
+	if phantom(r.Data) {
+		d.phantom, d.last = true, r.Time
+		d.n++
+		return
+	}
+	if len(keys) > 0 && !d.phantom && r.Data[0] == d.prev[0] && sameKeys(keys, d.prev) {
+		d.problem(PROBLEM_DROPPED, r.Time, keys[0])
+	}
+	d.phantom = false
+	// Modifiers go down before the keys they change and come up after them.
+	for bit := 0; bit < 8; bit++ {
+		if r.Data[0]&^d.prev[0]&(1<<bit) != 0 {
+			d.Events = append(d.Events, Event{Time: r.Time, Kind: KEY_DOWN, Keycode: keyboard.KEYCODE_LEFT_CONTROL + byte(bit), Modifiers: r.Data[0]})
+		}
+	}
+	for _, key := range d.prev[2:] {
+		if key != keyboard.KEYCODE_NIL && !contains(keys, key) && d.isDown(key) {
+			d.up(r.Time, key, r.Data[0])
+		}
+	}
+	for _, key := range keys {
+		if !d.isDown(key) {
+			d.press(r.Time, key, r.Data[0])
+		}
+	}
+	for bit := 0; bit < 8; bit++ {
+		if d.prev[0]&^r.Data[0]&(1<<bit) != 0 {
+			d.Events = append(d.Events, Event{Time: r.Time, Kind: KEY_UP, Keycode: keyboard.KEYCODE_LEFT_CONTROL + byte(bit), Modifiers: r.Data[0]})
+		}
+	}
+	if d.entry != nil && d.entry.alt && r.Data[0]&keyboard.MODIFIER_KEY_LEFT_ALT == 0 {
+		d.finishEntry()
+	}
+	d.prev, d.last = r.Data, r.Time
+	d.n++
+}
+
+// SetLEDs the host changed its lock state, as sent in an LED output report.
+func (d *Decoder) SetLEDs(state keyboard.LEDState) {
+	d.CapsLock, d.NumLock = state.CapsLock(), state.NumLock()
+}
+
+// Finish the stream ended, keys still down are stuck.
+func (d *Decoder) Finish() {
+	for _, key := range d.prev[2:] {
+		if key != keyboard.KEYCODE_NIL && d.isDown(key) && !d.repeats[key] {
+			d.problem(PROBLEM_STUCK, d.last, key)
+		}
+	}
+	for bit := 0; bit < 8; bit++ {
+		if d.prev[0]&(1<<bit) != 0 {
+			d.problem(PROBLEM_STUCK, d.last, keyboard.KEYCODE_LEFT_CONTROL+byte(bit))
+		}
+	}
+	// Only report keys once however often Finish is called.
+	d.prev = keyboard.Report{}
+	d.down = nil
+}
+
+// Text what the host typed so far.
+func (d *Decoder) Text() string {
+	return string(d.text)
+}
+
+// Check the host typed want without problems.
+func (d *Decoder) Check(want string) error {
+	if got := d.Text(); got != want || len(d.Problems) > 0 {
+		return &MismatchError{Want: want, Got: got, Problems: d.Problems}
+	}
+	return nil
+}
+
+// keys the keys down in r, ghosts the ones the host would get wrong.
+func (d *Decoder) keys(r keyboard.Report) (keys []byte, ghosts []byte) {
+	for _, key := range r[2:] {
+		switch {
+		case key == keyboard.KEYCODE_NIL:
+		case key == KEYCODE_ERROR_ROLLOVER || key == KEYCODE_POST_FAIL || key == KEYCODE_ERROR_UNDEFINED,
+			key > MAX_KEYCODE && !(key >= keyboard.KEYCODE_LEFT_CONTROL && key <= keyboard.KEYCODE_RIGHT_GUI),
+			contains(keys, key):
+			if !contains(ghosts, key) {
+				ghosts = append(ghosts, key)
+			}
+		default:
+			keys = append(keys, key)
+		}
+	}
+	return keys, ghosts
+}
+
+// checkRepeats flags keys that have been down longer than RepeatDelay by now.
+func (d *Decoder) checkRepeats(now time.Time) {
+	if d.RepeatDelay <= 0 {
+		return
+	}
+	for key, since := range d.down {
+		if !d.repeats[key] && now.Sub(since) > d.RepeatDelay {
+			d.repeats[key] = true
+			d.problem(PROBLEM_STUCK, now, key)
+		}
+	}
+}
+
+func (d *Decoder) isDown(key byte) bool {
+	_, ok := d.down[key]
+	return ok
+}
+
+func (d *Decoder) up(now time.Time, key byte, modifiers byte) {
+	delete(d.down, key)
+	delete(d.repeats, key)
+	d.Events = append(d.Events, Event{Time: now, Kind: KEY_UP, Keycode: key, Modifiers: modifiers})
+}
+
+func (d *Decoder) press(now time.Time, key byte, modifiers byte) {
+	d.down[key] = now
+	d.Events = append(d.Events, Event{Time: now, Kind: KEY_DOWN, Keycode: key, Modifiers: modifiers, Rune: d.typeKey(key, modifiers)})
+}
+
+// typeKey what pressing key with modifiers types, 0 for nothing. Backspace and the lock keys change the state instead.
+func (d *Decoder) typeKey(key byte, modifiers byte) rune {
+	const shortcut byte = keyboard.MODIFIER_KEY_LEFT_CTRL | keyboard.MODIFIER_KEY_RIGHT_CTRL | keyboard.MODIFIER_KEY_LEFT_ALT |
+		keyboard.MODIFIER_KEY_LEFT_GUI | keyboard.MODIFIER_KEY_RIGHT_GUI
+
+	switch key {
+	case keyboard.KEYCODE_CAPS_LOCK:
+		d.CapsLock = !d.CapsLock
+		return 0
+	case keyboard.KEYCODE_NUM_LOCK:
+		d.NumLock = !d.NumLock
+		return 0
+	case keyboard.KEYCODE_BACKSPACE:
+		if modifiers&shortcut == 0 && len(d.text) > 0 {
+			d.text = d.text[:len(d.text)-1]
+		}
+		return 0
+	}
+	if r, ok := d.enter(key, modifiers); ok {
+		return r
+	}
+	if modifiers&shortcut != 0 {
+		return 0
+	}
+
+	var r rune
+	if kp, ok := keypad(key, d.NumLock); ok {
+		r = kp
+	} else {
+		var stroke = keyboard.Stroke{Modifier: keyboard.MODIFIER_NOT_SET, Keycode: key}
+		if modifiers&(keyboard.MODIFIER_KEY_LEFT_SHIFT|keyboard.MODIFIER_KEY_RIGHT_SHIFT) != 0 {
+			stroke.Modifier |= keyboard.MODIFIER_KEY_LEFT_SHIFT
+		}
+		if modifiers&keyboard.MODIFIER_KEY_RIGHT_ALT != 0 {
+			stroke.Modifier |= keyboard.MODIFIER_KEY_RIGHT_ALT
+		}
+		var layout *keyboard.Layout = d.Layout
+		if layout == nil {
+			layout, _ = keyboard.LookupLayout(keyboard.DEFAULT_LAYOUT)
+		}
+		c, dead, ok := layout.Rune(stroke)
+		if !ok {
+			return 0
+		}
+		if dead {
+			if d.dead != 0 {
+				d.text = append(d.text, d.dead)
+			}
+			d.dead = c
+			return c
+		}
+		r = c
+		if d.CapsLock && unicode.IsLetter(r) && unicode.ToUpper(r) != unicode.ToLower(r) {
+			if unicode.IsUpper(r) {
+				r = unicode.ToLower(r)
+			} else {
+				r = unicode.ToUpper(r)
+			}
+		}
+	}
+
+	if d.dead != 0 {
+		accent := d.dead
+		d.dead = 0
+		if r == ' ' {
+			d.text = append(d.text, accent)
+			return r
+		}
+		if composed, ok := keyboard.Compose(accent, r); ok {
+			d.text = append(d.text, composed)
+			return composed
+		}
+		d.text = append(d.text, accent)
+	}
+	d.text = append(d.text, r)
+	return r
+}
+
+// keypad what a keypad key types, ok is false for keys not on the keypad and for the digits and period while Num
+// Lock is off, those move the cursor.
+func keypad(key byte, numLock bool) (r rune, ok bool) {
+	switch key {
+	case keyboard.KEYCODE_KP_SLASH:
+		return '/', true
+	case keyboard.KEYCODE_KP_ASTERISK:
+		return '*', true
+	case keyboard.KEYCODE_KP_MINUS:
+		return '-', true
+	case keyboard.KEYCODE_KP_PLUS:
+		return '+', true
+	case keyboard.KEYCODE_KP_ENTER:
+		return '\n', true
+	}
+	if !numLock {
+		return 0, false
+	}
+	switch {
+	case key >= keyboard.KEYCODE_KP_1 && key <= keyboard.KEYCODE_KP_9:
+		return '1' + rune(key-keyboard.KEYCODE_KP_1), true
+	case key == keyboard.KEYCODE_KP_0:
+		return '0', true
+	case key == keyboard.KEYCODE_KP_PERIOD:
+		return '.', true
+	}
+	return 0, false
+}
+
+func (d *Decoder) problem(kind ProblemKind, now time.Time, key byte) {
+	d.Problems = append(d.Problems, Problem{Kind: kind, Report: d.n, Time: now, Keycode: key})
+}
+
+// phantom true if r reports an error code instead of the keys that are down.
+func phantom(r keyboard.Report) bool {
+	for _, key := range r[2:] {
+		if key == KEYCODE_ERROR_ROLLOVER || key == KEYCODE_POST_FAIL || key == KEYCODE_ERROR_UNDEFINED {
+			return true
+		}
+	}
+	return false
+}
+
+// sameKeys true if r holds exactly keys.
+func sameKeys(keys []byte, r keyboard.Report) bool {
+	var n int
+
+	for _, key := range r[2:] {
+		if key == keyboard.KEYCODE_NIL {
+			continue
+		}
+		if !contains(keys, key) {
+			return false
+		}
+		n++
+	}
+	return n == len(keys)
+}
+
+// MismatchError the host typed something other than what was wanted, or had problems with the reports.
+type MismatchError struct {
+	Want     string
+	Got      string
+	Problems []Problem
+}
+
+func (e *MismatchError) Error() string {
+	var sb strings.Builder
+
+	if e.Want != e.Got {
+		fmt.Fprintf(&sb, "Host typed %q, want %q", e.Got, e.Want)
+		if i := firstDifference([]rune(e.Want), []rune(e.Got)); i >= 0 {
+			fmt.Fprintf(&sb, ", first difference at rune %d", i)
+		}
+	} else {
+		fmt.Fprintf(&sb, "Host typed %q", e.Got)
+	}
+	for _, p := range e.Problems {
+		sb.WriteString("; ")
+		sb.WriteString(p.String())
+	}
+	return sb.String()
+}
+
+func (e *MismatchError) String() string {
+	return e.Error()
+}
+
+func firstDifference(a, b []rune) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	if len(a) != len(b) {
+		if len(a) < len(b) {
+			return len(a)
+		}
+		return len(b)
+	}
+	return -1
+}
@@ -0,0 +1,178 @@
+package hidtest
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/scirelli/turkey-pi/pkg/keyboard"
+)
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// reports builds a stream from modifiers and keys, one report every 8ms.
+func reports(states ...[]byte) []Report {
+	var rs []Report = make([]Report, len(states))
+
+	for i, state := range states {
+		rs[i].Time = start.Add(time.Duration(i) * 8 * time.Millisecond)
+		rs[i].Data[0] = state[0]
+		copy(rs[i].Data[2:], state[1:])
+	}
+	return rs
+}
+
+func kinds(problems []Problem) []ProblemKind {
+	var ks []ProblemKind
+
+	for _, p := range problems {
+		ks = append(ks, p.Kind)
+	}
+	return ks
+}
+
+const shift = keyboard.MODIFIER_KEY_LEFT_SHIFT
+
+var rollover = []byte{0, KEYCODE_ERROR_ROLLOVER, KEYCODE_ERROR_ROLLOVER, KEYCODE_ERROR_ROLLOVER, KEYCODE_ERROR_ROLLOVER, KEYCODE_ERROR_ROLLOVER, KEYCODE_ERROR_ROLLOVER}
+
+func TestDecode(t *testing.T) {
+	var tests = []struct {
+		name     string
+		layout   string
+		reports  []Report
+		text     string
+		problems []ProblemKind
+	}{
+		{
+			name:    "plain",
+			reports: reports([]byte{0, keyboard.KEYCODE_H}, []byte{0}, []byte{shift, keyboard.KEYCODE_I}, []byte{0}),
+			text:    "hI",
+		},
+		{
+			name:    "rolled",
+			reports: reports([]byte{0, keyboard.KEYCODE_A}, []byte{0, keyboard.KEYCODE_A, keyboard.KEYCODE_B}, []byte{0, keyboard.KEYCODE_B}, []byte{0}),
+			text:    "ab",
+		},
+		{
+			name:    "shift while a key is held types nothing",
+			reports: reports([]byte{0, keyboard.KEYCODE_A}, []byte{shift, keyboard.KEYCODE_A}, []byte{0}),
+			text:    "a",
+		},
+		{
+			name:     "repeated report",
+			reports:  reports([]byte{0, keyboard.KEYCODE_A}, []byte{0, keyboard.KEYCODE_A}, []byte{0}),
+			text:     "a",
+			problems: []ProblemKind{PROBLEM_DROPPED},
+		},
+		{
+			name:     "rollover keeps the keys down",
+			reports:  reports([]byte{0, keyboard.KEYCODE_A}, rollover, []byte{0, keyboard.KEYCODE_A}, []byte{0}),
+			text:     "a",
+			problems: []ProblemKind{PROBLEM_GHOST},
+		},
+		{
+			name:     "rollover then a new key",
+			reports:  reports([]byte{0, keyboard.KEYCODE_A}, rollover, []byte{0, keyboard.KEYCODE_A, keyboard.KEYCODE_B}, []byte{0}),
+			text:     "ab",
+			problems: []ProblemKind{PROBLEM_GHOST},
+		},
+		{
+			name:     "keycode past the descriptor",
+			reports:  reports([]byte{0, 0x90}, []byte{0}),
+			problems: []ProblemKind{PROBLEM_GHOST},
+		},
+		{
+			name:     "never released",
+			reports:  reports([]byte{shift, keyboard.KEYCODE_A}),
+			text:     "A",
+			problems: []ProblemKind{PROBLEM_STUCK, PROBLEM_STUCK},
+		},
+		{
+			name:    "dead key",
+			layout:  "de",
+			reports: reports([]byte{0, keyboard.KEYCODE_EQUAL}, []byte{0}, []byte{0, keyboard.KEYCODE_E}, []byte{0}, []byte{0, keyboard.KEYCODE_EQUAL}, []byte{0}, []byte{0, keyboard.KEYCODE_SPACE}, []byte{0}),
+			text:    "é´",
+		},
+		{
+			name:    "caps lock and backspace",
+			reports: reports([]byte{0, keyboard.KEYCODE_CAPS_LOCK}, []byte{0}, []byte{0, keyboard.KEYCODE_A}, []byte{0}, []byte{shift, keyboard.KEYCODE_B}, []byte{0}, []byte{0, keyboard.KEYCODE_BACKSPACE}, []byte{0}),
+			text:    "A",
+		},
+		{
+			name:    "keypad follows num lock",
+			reports: reports([]byte{0, keyboard.KEYCODE_KP_1}, []byte{0}, []byte{0, keyboard.KEYCODE_NUM_LOCK}, []byte{0}, []byte{0, keyboard.KEYCODE_KP_2}, []byte{0}),
+			text:    "1",
+		},
+	}
+
+	for _, test := range tests {
+		var layout *keyboard.Layout
+		if test.layout != "" {
+			layout, _ = keyboard.LookupLayout(test.layout)
+		}
+		d := Decode(layout, test.reports)
+		if got := d.Text(); got != test.text {
+			t.Errorf("%s: Text() = %q, want %q", test.name, got, test.text)
+		}
+		if got := kinds(d.Problems); !reflect.DeepEqual(got, test.problems) {
+			t.Errorf("%s: problems %v, want %v", test.name, d.Problems, test.problems)
+		}
+	}
+}
+
+func TestDecodeRepeatDelay(t *testing.T) {
+	var d = NewDecoder(nil)
+	d.RepeatDelay = 500 * time.Millisecond
+
+	for _, r := range []Report{
+		{Time: start, Data: keyboard.Report{0, 0, keyboard.KEYCODE_ENTER}},
+		{Time: start.Add(100 * time.Millisecond)},
+		{Time: start.Add(200 * time.Millisecond), Data: keyboard.Report{0, 0, keyboard.KEYCODE_ENTER}},
+		{Time: start.Add(800 * time.Millisecond)},
+	} {
+		d.Decode(r)
+	}
+	d.Finish()
+
+	if len(d.Problems) != 1 || d.Problems[0].Kind != PROBLEM_STUCK || d.Problems[0].Report != 3 {
+		t.Errorf("problems %v, want Enter stuck in report 3", d.Problems)
+	}
+	if d.Text() != "\n\n" {
+		t.Errorf("Text() = %q", d.Text())
+	}
+}
+
+func TestDecodeEvents(t *testing.T) {
+	var d = Decode(nil, reports([]byte{shift}, []byte{shift, keyboard.KEYCODE_A}, []byte{0}))
+	var want = []Event{
+		{Kind: KEY_DOWN, Keycode: keyboard.KEYCODE_LEFT_SHIFT, Modifiers: shift},
+		{Kind: KEY_DOWN, Keycode: keyboard.KEYCODE_A, Modifiers: shift, Rune: 'A'},
+		{Kind: KEY_UP, Keycode: keyboard.KEYCODE_A},
+		{Kind: KEY_UP, Keycode: keyboard.KEYCODE_LEFT_SHIFT},
+	}
+
+	if len(d.Events) != len(want) {
+		t.Fatalf("events %v, want %v", d.Events, want)
+	}
+	for i, e := range d.Events {
+		e.Time = time.Time{}
+		if e != want[i] {
+			t.Errorf("event %d = %+v, want %+v", i, e, want[i])
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	var d = Decode(nil, reports([]byte{0, keyboard.KEYCODE_A}, []byte{0}, []byte{0, keyboard.KEYCODE_C}, []byte{0}))
+
+	if err := d.Check("ac"); err != nil {
+		t.Errorf("Check(ac) = %v", err)
+	}
+	err := d.Check("ab")
+	if err == nil {
+		t.Fatal("Check(ab) = nil")
+	}
+	if want := `Host typed "ac", want "ab", first difference at rune 1`; err.Error() != want {
+		t.Errorf("Check(ab) = %q, want %q", err, want)
+	}
+}
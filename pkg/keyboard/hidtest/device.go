@@ -0,0 +1,203 @@
+// Package hidtest stands in for the keyboard's hidg device so typing can be checked without a host. Device records
+// every report a keyboard.File writes, Decoder plays them back the way a host's keyboard driver would and
+// reconstructs the text typed:
+//
+//	var dev = hidtest.NewDevice()
+//	var kb = keyboard.File{Device: dev}
+//	kb.WriteString("Hello")
+//	if err := hidtest.Decode(nil, dev.Reports()).Check("Hello"); err != nil {
+//		...
+//	}
+package hidtest
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/scirelli/turkey-pi/pkg/keyboard"
+)
+
+// Report a report written to a Device and when it was written.
+type Report struct {
+	Time time.Time
+	Data keyboard.Report
+}
+
+// Device an in memory keyboard.Device. Writes are split into reports and recorded, reads return the LED reports
+// sent with SetLEDs. The zero value is not usable, use NewDevice.
+type Device struct {
+	// HostLocks answer Caps Lock, Num Lock and Scroll Lock presses with an LED report, like a host does.
+	HostLocks bool
+	// WriteErr when set every write fails with it, like a device whose host went away.
+	WriteErr error
+	// Clock the time reports are stamped with, time.Now when nil.
+	Clock func() time.Time
+
+	mu      sync.Mutex
+	reports []Report
+	partial []byte
+	leds    keyboard.LEDState
+	pending chan keyboard.LEDState
+	closed  chan struct{}
+	close   sync.Once
+}
+
+// NewDevice a Device with no reports and every LED off.
+func NewDevice() *Device {
+	return &Device{
+		pending: make(chan keyboard.LEDState, 16),
+		closed:  make(chan struct{}),
+	}
+}
+
+// Write records each whole report in p. A report split across writes is recorded once its last byte is written.
+func (d *Device) Write(p []byte) (n int, err error) {
+	select {
+	case <-d.closed:
+		return 0, io.ErrClosedPipe
+	default:
+	}
+	if d.WriteErr != nil {
+		return 0, d.WriteErr
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var now time.Time = d.now()
+	data := append(d.partial, p...)
+	for len(data) >= keyboard.ReportSz {
+		var r Report = Report{Time: now}
+		copy(r.Data[:], data[:keyboard.ReportSz])
+		data = data[keyboard.ReportSz:]
+		d.record(r)
+	}
+	d.partial = append([]byte{}, data...)
+	return len(p), nil
+}
+
+// record adds r, toggling the lock LEDs for lock keys it presses when HostLocks is set.
+func (d *Device) record(r Report) {
+	var prev keyboard.Report
+	if len(d.reports) > 0 {
+		prev = d.reports[len(d.reports)-1].Data
+	}
+	d.reports = append(d.reports, r)
+
+	if !d.HostLocks {
+		return
+	}
+	var leds keyboard.LEDState = d.leds
+	for _, key := range pressed(prev, r.Data) {
+		switch key {
+		case keyboard.KEYCODE_CAPS_LOCK:
+			leds ^= keyboard.LED_CAPS_LOCK
+		case keyboard.KEYCODE_NUM_LOCK:
+			leds ^= keyboard.LED_NUM_LOCK
+		case keyboard.KEYCODE_SCROLL_LOCK:
+			leds ^= keyboard.LED_SCROLL_LOCK
+		}
+	}
+	if leds != d.leds {
+		d.setLEDs(leds)
+	}
+}
+
+// Read blocks until the host sends an LED report, it returns io.EOF once the Device is closed.
+func (d *Device) Read(p []byte) (n int, err error) {
+	select {
+	case state := <-d.pending:
+		if len(p) < keyboard.LED_REPORT_SZ {
+			return 0, io.ErrShortBuffer
+		}
+		p[0] = byte(state)
+		return keyboard.LED_REPORT_SZ, nil
+	case <-d.closed:
+		return 0, io.EOF
+	}
+}
+
+// Close ends any Read, later writes fail.
+func (d *Device) Close() error {
+	d.close.Do(func() {
+		close(d.closed)
+	})
+	return nil
+}
+
+// SetLEDs the host sends an LED report, as when Caps Lock is pressed on another keyboard.
+func (d *Device) SetLEDs(state keyboard.LEDState) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.setLEDs(state)
+}
+
+func (d *Device) setLEDs(state keyboard.LEDState) {
+	d.leds = state
+	// Keep the latest state when nobody is reading, a host only cares about the current one too.
+	for {
+		select {
+		case d.pending <- state:
+			return
+		default:
+		}
+		select {
+		case <-d.pending:
+		default:
+		}
+	}
+}
+
+// LEDs the LED state the host last sent.
+func (d *Device) LEDs() keyboard.LEDState {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.leds
+}
+
+// Reports a copy of the reports written so far.
+func (d *Device) Reports() []Report {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return append([]Report{}, d.reports...)
+}
+
+// Reset forgets the reports written so far.
+func (d *Device) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.reports, d.partial = nil, nil
+}
+
+func (d *Device) now() time.Time {
+	if d.Clock != nil {
+		return d.Clock()
+	}
+	return time.Now()
+}
+
+// pressed the keys in r that are not in prev.
+func pressed(prev, r keyboard.Report) []byte {
+	var keys []byte
+
+	for _, key := range r[2:] {
+		if key != keyboard.KEYCODE_NIL && !contains(prev[2:], key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func contains(keys []byte, key byte) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
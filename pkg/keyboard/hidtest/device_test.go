@@ -0,0 +1,90 @@
+package hidtest
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/scirelli/turkey-pi/pkg/keyboard"
+)
+
+func TestDeviceSplitsWrites(t *testing.T) {
+	var dev = NewDevice()
+	var now = start
+	dev.Clock = func() time.Time { return now }
+
+	r1, _ := keyboard.NewReport(keyboard.MODIFIER_KEY_LEFT_SHIFT, keyboard.KEYCODE_A)
+	r2, _ := keyboard.NewReport(keyboard.MODIFIER_NOT_SET)
+	// A whole report and the first half of the next, then the rest of it.
+	if n, err := dev.Write(append(r1[:], r2[:4]...)); n != 12 || err != nil {
+		t.Fatalf("Write = %d, %v", n, err)
+	}
+	if got := len(dev.Reports()); got != 1 {
+		t.Fatalf("%d reports after a report and a half, want 1", got)
+	}
+	now = now.Add(time.Millisecond)
+	dev.Write(r2[4:])
+
+	reports := dev.Reports()
+	if len(reports) != 2 || reports[0].Data != r1 || reports[1].Data != r2 {
+		t.Fatalf("Reports() = %v", reports)
+	}
+	if !reports[0].Time.Equal(start) || !reports[1].Time.Equal(now) {
+		t.Errorf("reports stamped %v and %v", reports[0].Time, reports[1].Time)
+	}
+
+	dev.Reset()
+	if len(dev.Reports()) != 0 {
+		t.Error("Reset kept reports")
+	}
+}
+
+func TestDeviceHostLocks(t *testing.T) {
+	var dev = NewDevice()
+	var buf = make([]byte, keyboard.ReportSz)
+	dev.HostLocks = true
+
+	caps, _ := keyboard.NewReport(keyboard.MODIFIER_NOT_SET, keyboard.KEYCODE_CAPS_LOCK)
+	dev.Write(caps[:])
+	dev.Write(make([]byte, keyboard.ReportSz))
+
+	n, err := dev.Read(buf)
+	if n != keyboard.LED_REPORT_SZ || err != nil || keyboard.LEDState(buf[0]) != keyboard.LED_CAPS_LOCK {
+		t.Fatalf("Read = %d, %v, %08b, want Caps Lock on", n, err, buf[0])
+	}
+	if dev.LEDs() != keyboard.LED_CAPS_LOCK {
+		t.Errorf("LEDs() = %s", dev.LEDs())
+	}
+
+	// Only a key going down toggles, holding it does not.
+	dev.Write(caps[:])
+	dev.Write(caps[:])
+	if dev.LEDs() != 0 {
+		t.Errorf("LEDs() = %s after a second press, want none", dev.LEDs())
+	}
+}
+
+func TestDeviceErrors(t *testing.T) {
+	var dev = NewDevice()
+	var hostGone = errors.New("host gone")
+
+	dev.WriteErr = hostGone
+	if _, err := dev.Write(make([]byte, keyboard.ReportSz)); err != hostGone {
+		t.Errorf("Write = %v, want %v", err, hostGone)
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := dev.Read(make([]byte, keyboard.ReportSz))
+		done <- err
+	}()
+	dev.Close()
+	if err := <-done; err != io.EOF {
+		t.Errorf("Read after Close = %v, want EOF", err)
+	}
+	dev.WriteErr = nil
+	if _, err := dev.Write(make([]byte, keyboard.ReportSz)); err == nil {
+		t.Error("Write after Close succeeded")
+	}
+}
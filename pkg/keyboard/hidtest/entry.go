@@ -0,0 +1,173 @@
+package hidtest
+
+import (
+	"strconv"
+	"unicode/utf16"
+
+	"github.com/scirelli/turkey-pi/pkg/keyboard"
+)
+
+// entry a Unicode code being typed with the host's input method.
+type entry struct {
+	digits string
+	base   int
+	alt    bool // alt the code is finished by letting go of Alt
+}
+
+// enter handles key as part of the host's Unicode input method. ok is false if the input method does not use the key,
+// it is typed as usual.
+func (d *Decoder) enter(key byte, modifiers byte) (r rune, ok bool) {
+	const ctrlShift byte = keyboard.MODIFIER_KEY_LEFT_CTRL | keyboard.MODIFIER_KEY_LEFT_SHIFT
+	var alt bool = modifiers&(keyboard.MODIFIER_KEY_LEFT_ALT|keyboard.MODIFIER_KEY_LEFT_CTRL|keyboard.MODIFIER_KEY_RIGHT_CTRL) == keyboard.MODIFIER_KEY_LEFT_ALT
+
+	switch d.InputMethod {
+	case keyboard.TARGET_LINUX:
+		if key == keyboard.KEYCODE_U && modifiers&ctrlShift == ctrlShift {
+			d.entry = &entry{base: 16}
+			return 0, true
+		}
+		if d.entry == nil {
+			return 0, false
+		}
+		if key == keyboard.KEYCODE_SPACE || key == keyboard.KEYCODE_ENTER {
+			return d.finishEntry(), true
+		}
+		if c, ok := d.hexDigit(key, modifiers); ok {
+			d.entry.digits += string(c)
+			return 0, true
+		}
+		// Any other key cancels the entry.
+		d.entry = nil
+		return 0, true
+	case keyboard.TARGET_WINDOWS:
+		digit, isDigit := keypad(key, d.NumLock)
+		if !alt || !isDigit || digit < '0' || digit > '9' {
+			return 0, false
+		}
+		if d.entry == nil {
+			d.entry = &entry{base: 10, alt: true}
+		}
+		d.entry.digits += string(digit)
+		return 0, true
+	case keyboard.TARGET_WINDOWS_HEX:
+		if !alt {
+			return 0, false
+		}
+		if key == keyboard.KEYCODE_KP_PLUS {
+			d.entry = &entry{base: 16, alt: true}
+			return 0, true
+		}
+		if d.entry == nil {
+			return 0, false
+		}
+		if digit, ok := keypad(key, d.NumLock); ok && digit >= '0' && digit <= '9' {
+			d.entry.digits += string(digit)
+		} else if c, ok := d.hexDigit(key, keyboard.MODIFIER_NOT_SET); ok {
+			d.entry.digits += string(c)
+		}
+		return 0, true
+	case keyboard.TARGET_MACOS:
+		// Unicode Hex Input has the hex digits where US QWERTY does.
+		if !alt {
+			return 0, false
+		}
+		c, ok := usHexDigit(key)
+		if !ok {
+			return 0, false
+		}
+		if d.entry == nil {
+			d.entry = &entry{base: 16, alt: true}
+		}
+		d.entry.digits += string(c)
+		return 0, true
+	}
+	return 0, false
+}
+
+// finishEntry types the rune entered, if the code is one.
+func (d *Decoder) finishEntry() rune {
+	var e *entry = d.entry
+	var r rune
+
+	d.entry = nil
+	switch {
+	case d.InputMethod == keyboard.TARGET_MACOS:
+		// Each group of 4 digits is a UTF-16 code unit.
+		var units []uint16
+		for i := 0; i+4 <= len(e.digits); i += 4 {
+			unit, err := strconv.ParseUint(e.digits[i:i+4], 16, 16)
+			if err != nil {
+				return 0
+			}
+			units = append(units, uint16(unit))
+		}
+		if runes := utf16.Decode(units); len(runes) == 1 {
+			r = runes[0]
+		}
+	case d.InputMethod == keyboard.TARGET_WINDOWS:
+		code, err := strconv.ParseUint(e.digits, 10, 32)
+		if err != nil {
+			return 0
+		}
+		// Only a leading 0 selects Windows-1252, other codes are taken modulo 256 in the OEM code page.
+		if e.digits[0] != '0' || code > 0xFF {
+			return 0
+		}
+		r = windows1252[code]
+	default:
+		code, err := strconv.ParseUint(e.digits, e.base, 32)
+		if err != nil {
+			return 0
+		}
+		r = rune(code)
+	}
+	if r != 0 {
+		d.text = append(d.text, r)
+	}
+	return r
+}
+
+// hexDigit the hex digit key types on the host's layout.
+func (d *Decoder) hexDigit(key byte, modifiers byte) (rune, bool) {
+	var layout *keyboard.Layout = d.Layout
+	if layout == nil {
+		layout, _ = keyboard.LookupLayout(keyboard.DEFAULT_LAYOUT)
+	}
+	var stroke = keyboard.Stroke{Modifier: modifiers & keyboard.MODIFIER_KEY_LEFT_SHIFT, Keycode: key}
+	if modifiers&keyboard.MODIFIER_KEY_RIGHT_SHIFT != 0 {
+		stroke.Modifier |= keyboard.MODIFIER_KEY_LEFT_SHIFT
+	}
+	c, dead, ok := layout.Rune(stroke)
+	if !ok || dead || !isHex(c) {
+		return 0, false
+	}
+	return c, true
+}
+
+// usHexDigit the hex digit key types on a US layout.
+func usHexDigit(key byte) (rune, bool) {
+	switch {
+	case key >= keyboard.KEYCODE_A && key <= keyboard.KEYCODE_F:
+		return 'a' + rune(key-keyboard.KEYCODE_A), true
+	case key >= keyboard.KEYCODE_1 && key <= keyboard.KEYCODE_9:
+		return '1' + rune(key-keyboard.KEYCODE_1), true
+	case key == keyboard.KEYCODE_0:
+		return '0', true
+	}
+	return 0, false
+}
+
+func isHex(c rune) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+// windows1252 the runes of the Windows-1252 code page, 0 where a code is unused.
+var windows1252 = func() (table [256]rune) {
+	var high = []rune("€\x00‚ƒ„…†‡ˆ‰Š‹Œ\x00Ž\x00\x00‘’“”•–—˜™š›œ\x00žŸ")
+
+	for i := range table {
+		table[i] = rune(i)
+	}
+	copy(table[0x80:0xA0], high)
+	return table
+}()
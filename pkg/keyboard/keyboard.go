@@ -2,14 +2,47 @@ package keyboard
 
 import (
 	"bytes"
-	"os"
+	"context"
+	"io"
+	"sync"
 	"time"
 )
 
+// Device where a File writes its reports and reads the host's LED reports from. In use it is an *os.File opened on
+// /dev/hidg<#>, hidtest.Device records the reports in memory instead.
+type Device interface {
+	io.ReadWriteCloser
+}
+
 //File represents the keyboard device file in user space /dev/hidg<#>
 type File struct {
-	os.File
+	Device
 	StrokeDelay time.Duration
+	Layout      *Layout //Layout the host is configured with, defaults to US.
+	CharMap     CharMap //CharMap user defined mappings, these take precedence over the layout.
+	//LockStrategy how to type when the host has Caps Lock on or Num Lock off, defaults to LOCKS_IGNORE.
+	LockStrategy LockStrategy
+	//Timing how long keys are held and the gaps between them when typing with delays, StrokeDelay is used when nil.
+	Timing *TimingProfile
+	//OptimizeReports type text with fewer reports, see PlanReports.
+	OptimizeReports bool
+	//UnicodeEntry how to type runes the layout has no key for, see Unicode entry. They are sent as KEYCODE_NIL when nil.
+	UnicodeEntry UnicodeEntry
+
+	mu   sync.Mutex
+	held keyState
+	leds ledListener
+	pace PaceStats
+}
+
+// Options override the File's settings for a single write. Zero values fall back to the File's settings.
+type Options struct {
+	Layout       *Layout
+	Escapes      bool            //Escapes enables the {KEY} escape syntax, see ParseEscapes.
+	LockStrategy LockStrategy    //LockStrategy how to type around the host's lock keys, see LockStrategy.
+	Progress     func(runes int) //Progress called with the running count of characters typed, WriteStringContext only.
+	Timing       *TimingProfile  //Timing overrides the File's timing profile, see TimingProfile.
+	UnicodeEntry UnicodeEntry    //UnicodeEntry overrides how runes the layout has no key for are typed.
 }
 
 /* Keyboard HID Report Descriptor
@@ -48,43 +81,244 @@ Byte
 //     Reports seem to allow from 1 to 6 keycodes. I was able to send a report of size 3 (bytes), one scan code, and it still worked.
 const ReportSz int = 8
 
+// ROLLOVER the number of keycodes a report can hold, i.e. keys that can be down at the same time not counting modifiers.
+const ROLLOVER int = 6
+
 type Report [ReportSz]byte
 
+// NewReport a report with the modifiers and keys pressed. Modifier keys (KEYCODE_LEFT_CONTROL to KEYCODE_RIGHT_GUI) are
+// folded into the modifier byte. A RolloverError is returned if more than ROLLOVER other keys are given.
+func NewReport(modifiers byte, keys ...byte) (Report, error) {
+	var r Report
+	var i int = 2
+
+	for _, key := range keys {
+		if key == KEYCODE_NIL {
+			continue
+		}
+		if isModifierKey(key) {
+			modifiers |= modifierBit(key)
+			continue
+		}
+		if i == ReportSz {
+			return r, &RolloverError{Keys: countKeys(keys)}
+		}
+		r[i] = key
+		i++
+	}
+	r[0] = modifiers
+	return r, nil
+}
+
+// PressChord presses the modifiers and up to ROLLOVER keys at the same time then releases them all, e.g. Ctrl+Alt+Del
+//
+//	f.PressChord(MODIFIER_KEY_LEFT_CTRL|MODIFIER_KEY_LEFT_ALT, KEYCODE_DELETE)
+//
+// Keys held with Press stay down.
+func (f *File) PressChord(modifiers byte, keys ...byte) error {
+	_, err := f.pressChord(modifiers, keys)
+	return err
+}
+
+func (f *File) pressChord(modifiers byte, keys []byte) (n int, err error) {
+	return f.strokeContext(context.Background(), newPacer(), modifiers, keys, f.StrokeDelay, f.StrokeDelay)
+}
+
+// pressReport the report with modifiers and keys pressed on top of the held keys.
+func (f *File) pressReport(modifiers byte, keys ...byte) (Report, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.held.report(modifiers, keys...)
+}
+
+// releaseReport the report that releases everything but the held keys.
+func (f *File) releaseReport() Report {
+	return f.releaseReportKeeping(MODIFIER_NOT_SET)
+}
+
+// releaseReportKeeping the report that releases everything but the held keys and modifiers.
+func (f *File) releaseReportKeeping(modifiers byte) Report {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// The held state was already sent once so it always fits.
+	r, _ := f.held.report(modifiers)
+	return r
+}
+
+// keptModifiers the modifiers to keep down after stroke i of a character, those the next stroke presses as well.
+// Entering a rune with Alt held on the keypad only works if Alt stays down between the digits.
+func keptModifiers(strokes []Stroke, i int) byte {
+	if i+1 >= len(strokes) {
+		return MODIFIER_NOT_SET
+	}
+	return strokes[i].Modifier & strokes[i+1].Modifier
+}
+
+func isModifierKey(key byte) bool {
+	return key >= KEYCODE_LEFT_CONTROL && key <= KEYCODE_RIGHT_GUI
+}
+
+// modifierBit the modifier byte bit for a modifier key, KEYCODE_LEFT_CONTROL is bit 0 through KEYCODE_RIGHT_GUI bit 7.
+func modifierBit(key byte) byte {
+	return 1 << (key - KEYCODE_LEFT_CONTROL)
+}
+
+// countKeys number of keys that need a slot in the report.
+func countKeys(keys []byte) int {
+	var n int
+	for _, key := range keys {
+		if !isModifierKey(key) {
+			n++
+		}
+	}
+	return n
+}
+
 func (f *File) WriteString(s string) (n int, err error) {
+	return f.WriteStringWith(s, Options{})
+}
+
+func (f *File) WriteStringWith(s string, opts Options) (n int, err error) {
+	tokens, err := tokenize(s, opts)
+	if err != nil {
+		return 0, err
+	}
+	sess, restore, err := f.startSession(tokens, opts)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if rerr := restore(); err == nil {
+			err = rerr
+		}
+	}()
+
+	return f.writeTokens(tokens, sess, f.writeText, func(modifiers byte, keys []byte) (int, error) {
+		var buf bytes.Buffer = bytes.Buffer{}
+		r, err := f.pressReport(modifiers, keys...)
+		if err != nil {
+			return 0, err
+		}
+		buf.Write(r[:])
+		r = f.releaseReport()
+		buf.Write(r[:])
+		return f.Device.Write(buf.Bytes())
+	})
+}
+
+func (f *File) writeText(s string, sess *session) (n int, err error) {
+	if f.OptimizeReports {
+		return f.writePlanned(s, sess)
+	}
 	var buf bytes.Buffer = bytes.Buffer{}
 	for _, c := range s {
-		modifier, keycode := ASCII_to_keycode(byte(c))
-		r := Report{modifier, 0, keycode, 0, 0, 0, 0, 0}
-		buf.Write(r[:])
-		r = Report{0, 0, 0, 0, 0, 0, 0, 0}
+		strokes := sess.strokes(c)
+		for i, stroke := range strokes {
+			r, err := f.pressReport(stroke.Modifier, stroke.Keycode)
+			if err != nil {
+				return 0, err
+			}
+			buf.Write(r[:])
+			r = f.releaseReportKeeping(keptModifiers(strokes, i))
+			buf.Write(r[:])
+		}
+	}
+	return f.Device.Write(buf.Bytes())
+}
+
+func (f *File) writePlanned(s string, sess *session) (n int, err error) {
+	var buf bytes.Buffer = bytes.Buffer{}
+	var strokes []Stroke
+
+	for _, c := range s {
+		strokes = append(strokes, sess.strokes(c)...)
+	}
+	for _, fr := range planFrames(strokes) {
+		r, err := f.pressReport(fr.state.Modifier, fr.state.Keycode)
+		if err != nil {
+			return 0, err
+		}
 		buf.Write(r[:])
 	}
-	return f.File.Write(buf.Bytes())
+	return f.Device.Write(buf.Bytes())
 }
 
 func (f *File) WriteStringDelayed(s string) (n int, err error) {
+	return f.WriteStringDelayedWith(s, Options{})
+}
+
+func (f *File) WriteStringDelayedWith(s string, opts Options) (n int, err error) {
+	_, n, err = f.writeContext(context.Background(), s, opts)
+	return n, err
+}
+
+// tokenize splits s into tokens when escapes are enabled, otherwise it is all text.
+func tokenize(s string, opts Options) ([]Token, error) {
+	if opts.Escapes {
+		return ParseEscapes(s)
+	}
+	return []Token{{Kind: TokenText, Text: s}}, nil
+}
+
+// writeTokens writes each token.
+func (f *File) writeTokens(tokens []Token, sess *session, text func(string, *session) (int, error), chord func(byte, []byte) (int, error)) (n int, err error) {
 	var totalBytes int
 
-	for _, c := range s {
-		modifier, keycode := ASCII_to_keycode(byte(c))
-		r := Report{modifier, 0, keycode, 0, 0, 0, 0, 0}
-		if n, err = f.File.Write(r[:]); err != nil {
-			totalBytes += n
-			return totalBytes, err
+	for _, token := range tokens {
+		switch token.Kind {
+		case TokenText:
+			n, err = text(token.Text, sess)
+		case TokenChord:
+			n, err = chord(token.Modifiers, token.Keys)
+		case TokenWait:
+			n = 0
+			time.Sleep(token.Wait)
 		}
 		totalBytes += n
-		time.Sleep(f.StrokeDelay)
-		r = Report{0, 0, 0, 0, 0, 0, 0, 0}
-		if n, err = f.File.Write(r[:]); err != nil {
-			totalBytes += n
+		if err != nil {
 			return totalBytes, err
 		}
-		totalBytes += n
-		time.Sleep(f.StrokeDelay)
 	}
 	return totalBytes, nil
 }
 
+// Strokes the strokes that type c. Runes the layout can not produce are entered with the UnicodeEntry, without one or
+// if it can not enter them either they are sent as a single KEYCODE_NIL stroke.
+func (f *File) Strokes(c rune, opts Options) []Stroke {
+	strokes, _ := f.strokes(c, opts)
+	return strokes
+}
+
+// strokes the strokes that type c, entered is true when they are a UnicodeEntry sequence rather than keys that type c.
+func (f *File) strokes(c rune, opts Options) (strokes []Stroke, entered bool) {
+	var layout *Layout = opts.Layout
+	var entry UnicodeEntry = opts.UnicodeEntry
+
+	if stroke, ok := f.CharMap[c]; ok {
+		return []Stroke{stroke}, false
+	}
+	if layout == nil {
+		layout = f.Layout
+	}
+	if layout == nil {
+		layout = layouts[DEFAULT_LAYOUT]
+	}
+	if strokes, ok := layout.Strokes(c); ok {
+		return strokes, false
+	}
+	if entry == nil {
+		entry = f.UnicodeEntry
+	}
+	if entry != nil {
+		if strokes, ok := entry.Strokes(c, layout); ok {
+			return strokes, true
+		}
+	}
+	return []Stroke{{MODIFIER_NOT_SET, KEYCODE_NIL}}, false
+}
+
 // void pressKey(uint8_t modifiers, uint8_t keycode1, uint8_t keycode2, uint8_t keycode3, uint8_t keycode4, uint8_t keycode5, uint8_t keycode6);
 // void pressKey(uint8_t modifiers, uint8_t keycode1, uint8_t keycode2, uint8_t keycode3, uint8_t keycode4, uint8_t keycode5);
 // void pressKey(uint8_t modifiers, uint8_t keycode1, uint8_t keycode2, uint8_t keycode3, uint8_t keycode4);
@@ -324,3 +558,37 @@ const (
 	KEYCODE_ARROW_DOWN   byte = 0x51
 	KEYCODE_ARROW_UP     byte = 0x52
 )
+
+const (
+	// keys only found on ISO keyboards, the one next to enter and the one next to left shift
+	KEYCODE_NON_US_HASH      byte = 0x32
+	KEYCODE_NON_US_BACKSLASH byte = 0x64
+)
+
+const (
+	// keypad keys, the digits and period only type when Num Lock is on
+	KEYCODE_KP_SLASH    byte = 0x54
+	KEYCODE_KP_ASTERISK byte = 0x55
+	KEYCODE_KP_MINUS    byte = 0x56
+	KEYCODE_KP_PLUS     byte = 0x57
+	KEYCODE_KP_ENTER    byte = 0x58
+	KEYCODE_KP_1        byte = 0x59
+	KEYCODE_KP_2        byte = 0x5A
+	KEYCODE_KP_3        byte = 0x5B
+	KEYCODE_KP_4        byte = 0x5C
+	KEYCODE_KP_5        byte = 0x5D
+	KEYCODE_KP_6        byte = 0x5E
+	KEYCODE_KP_7        byte = 0x5F
+	KEYCODE_KP_8        byte = 0x60
+	KEYCODE_KP_9        byte = 0x61
+	KEYCODE_KP_0        byte = 0x62
+	KEYCODE_KP_PERIOD   byte = 0x63
+)
+
+const (
+	// lock keys
+	KEYCODE_CAPS_LOCK   byte = 0x39
+	KEYCODE_SCROLL_LOCK byte = 0x47
+	KEYCODE_PAUSE       byte = 0x48
+	KEYCODE_NUM_LOCK    byte = 0x53
+)
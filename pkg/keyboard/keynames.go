@@ -0,0 +1,210 @@
+package keyboard
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// KeyByName the keycode for a key name. Every KEYCODE_* constant is named without its prefix, e.g. "F5", "PAGE_UP" or
+// "ARROW_LEFT", and a few common aliases such as "UP" and "ESCAPE" are accepted. Names are case insensitive.
+// Keys without a name can be given by usage, e.g. "U+0068".
+func KeyByName(name string) (byte, bool) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if key, ok := keyNames[name]; ok {
+		return key, true
+	}
+	if strings.HasPrefix(name, "U+") {
+		if usage, err := strconv.ParseUint(name[2:], 16, 8); err == nil {
+			return byte(usage), true
+		}
+	}
+	return 0, false
+}
+
+// KeyName the name of a keycode, or its usage written as U+xxxx if it has no name.
+func KeyName(key byte) string {
+	if name, ok := keycodeNames[key]; ok {
+		return name
+	}
+	return fmt.Sprintf("U+%04X", key)
+}
+
+// ModifierByName the modifier bit for a name such as "ctrl", "shift", "alt", "gui" or "altgr". Left and right variants
+// are prefixed with 'l' or 'r', e.g. "rctrl". Names are case insensitive.
+func ModifierByName(name string) (byte, bool) {
+	modifier, ok := modifierNames[strings.ToLower(strings.TrimSpace(name))]
+	return modifier, ok
+}
+
+var modifierNames = map[string]byte{
+	"ctrl":    MODIFIER_KEY_LEFT_CTRL,
+	"control": MODIFIER_KEY_LEFT_CTRL,
+	"lctrl":   MODIFIER_KEY_LEFT_CTRL,
+	"rctrl":   MODIFIER_KEY_RIGHT_CTRL,
+	"shift":   MODIFIER_KEY_LEFT_SHIFT,
+	"lshift":  MODIFIER_KEY_LEFT_SHIFT,
+	"rshift":  MODIFIER_KEY_RIGHT_SHIFT,
+	"alt":     MODIFIER_KEY_LEFT_ALT,
+	"lalt":    MODIFIER_KEY_LEFT_ALT,
+	"ralt":    MODIFIER_KEY_RIGHT_ALT,
+	"altgr":   MODIFIER_KEY_RIGHT_ALT,
+	"gui":     MODIFIER_KEY_LEFT_GUI,
+	"win":     MODIFIER_KEY_LEFT_GUI,
+	"windows": MODIFIER_KEY_LEFT_GUI,
+	"meta":    MODIFIER_KEY_LEFT_GUI,
+	"super":   MODIFIER_KEY_LEFT_GUI,
+	"command": MODIFIER_KEY_LEFT_GUI,
+	"cmd":     MODIFIER_KEY_LEFT_GUI,
+	"lgui":    MODIFIER_KEY_LEFT_GUI,
+	"rgui":    MODIFIER_KEY_RIGHT_GUI,
+
+	"mod_left_control":  KEYCODE_MOD_LEFT_CONTROL,
+	"mod_left_shift":    KEYCODE_MOD_LEFT_SHIFT,
+	"mod_left_alt":      KEYCODE_MOD_LEFT_ALT,
+	"mod_left_gui":      KEYCODE_MOD_LEFT_GUI,
+	"mod_right_control": KEYCODE_MOD_RIGHT_CONTROL,
+	"mod_right_shift":   KEYCODE_MOD_RIGHT_SHIFT,
+	"mod_right_alt":     KEYCODE_MOD_RIGHT_ALT,
+	"mod_right_gui":     KEYCODE_MOD_RIGHT_GUI,
+}
+
+var keyNames = map[string]byte{
+	"NIL":              KEYCODE_NIL,
+	"LEFT_CONTROL":     KEYCODE_LEFT_CONTROL,
+	"LEFT_SHIFT":       KEYCODE_LEFT_SHIFT,
+	"LEFT_ALT":         KEYCODE_LEFT_ALT,
+	"LEFT_GUI":         KEYCODE_LEFT_GUI,
+	"RIGHT_CONTROL":    KEYCODE_RIGHT_CONTRO,
+	"RIGHT_SHIFT":      KEYCODE_RIGHT_SHIFT,
+	"RIGHT_ALT":        KEYCODE_RIGHT_ALT,
+	"RIGHT_GUI":        KEYCODE_RIGHT_GUI,
+	"1":                KEYCODE_1,
+	"2":                KEYCODE_2,
+	"3":                KEYCODE_3,
+	"4":                KEYCODE_4,
+	"5":                KEYCODE_5,
+	"6":                KEYCODE_6,
+	"7":                KEYCODE_7,
+	"8":                KEYCODE_8,
+	"9":                KEYCODE_9,
+	"0":                KEYCODE_0,
+	"A":                KEYCODE_A,
+	"B":                KEYCODE_B,
+	"C":                KEYCODE_C,
+	"D":                KEYCODE_D,
+	"E":                KEYCODE_E,
+	"F":                KEYCODE_F,
+	"G":                KEYCODE_G,
+	"H":                KEYCODE_H,
+	"I":                KEYCODE_I,
+	"J":                KEYCODE_J,
+	"K":                KEYCODE_K,
+	"L":                KEYCODE_L,
+	"M":                KEYCODE_M,
+	"N":                KEYCODE_N,
+	"O":                KEYCODE_O,
+	"P":                KEYCODE_P,
+	"Q":                KEYCODE_Q,
+	"R":                KEYCODE_R,
+	"S":                KEYCODE_S,
+	"T":                KEYCODE_T,
+	"U":                KEYCODE_U,
+	"V":                KEYCODE_V,
+	"W":                KEYCODE_W,
+	"X":                KEYCODE_X,
+	"Y":                KEYCODE_Y,
+	"Z":                KEYCODE_Z,
+	"COMMA":            KEYCODE_COMMA,
+	"PERIOD":           KEYCODE_PERIOD,
+	"MINUS":            KEYCODE_MINUS,
+	"EQUAL":            KEYCODE_EQUAL,
+	"BACKSLASH":        KEYCODE_BACKSLASH,
+	"SQBRAK_LEFT":      KEYCODE_SQBRAK_LEFT,
+	"SQBRAK_RIGHT":     KEYCODE_SQBRAK_RIGHT,
+	"SEMICOLON":        KEYCODE_SEMICOLON,
+	"SINGLE_QUOTE":     KEYCODE_SINGLE_QUOTE,
+	"BACK_TICK":        KEYCODE_BACK_TICK,
+	"SLASH":            KEYCODE_SLASH,
+	"F1":               KEYCODE_F1,
+	"F2":               KEYCODE_F2,
+	"F3":               KEYCODE_F3,
+	"F4":               KEYCODE_F4,
+	"F5":               KEYCODE_F5,
+	"F6":               KEYCODE_F6,
+	"F7":               KEYCODE_F7,
+	"F8":               KEYCODE_F8,
+	"F9":               KEYCODE_F9,
+	"F10":              KEYCODE_F10,
+	"F11":              KEYCODE_F11,
+	"F12":              KEYCODE_F12,
+	"APP":              KEYCODE_APP,
+	"ENTER":            KEYCODE_ENTER,
+	"BACKSPACE":        KEYCODE_BACKSPACE,
+	"ESC":              KEYCODE_ESC,
+	"TAB":              KEYCODE_TAB,
+	"SPACE":            KEYCODE_SPACE,
+	"INSERT":           KEYCODE_INSERT,
+	"HOME":             KEYCODE_HOME,
+	"PAGE_UP":          KEYCODE_PAGE_UP,
+	"DELETE":           KEYCODE_DELETE,
+	"END":              KEYCODE_END,
+	"PAGE_DOWN":        KEYCODE_PAGE_DOWN,
+	"PRINTSCREEN":      KEYCODE_PRINTSCREEN,
+	"ARROW_RIGHT":      KEYCODE_ARROW_RIGHT,
+	"ARROW_LEFT":       KEYCODE_ARROW_LEFT,
+	"ARROW_DOWN":       KEYCODE_ARROW_DOWN,
+	"ARROW_UP":         KEYCODE_ARROW_UP,
+	"NON_US_HASH":      KEYCODE_NON_US_HASH,
+	"NON_US_BACKSLASH": KEYCODE_NON_US_BACKSLASH,
+	"CAPS_LOCK":        KEYCODE_CAPS_LOCK,
+	"SCROLL_LOCK":      KEYCODE_SCROLL_LOCK,
+	"PAUSE":            KEYCODE_PAUSE,
+	"NUM_LOCK":         KEYCODE_NUM_LOCK,
+	"KP_SLASH":         KEYCODE_KP_SLASH,
+	"KP_ASTERISK":      KEYCODE_KP_ASTERISK,
+	"KP_MINUS":         KEYCODE_KP_MINUS,
+	"KP_PLUS":          KEYCODE_KP_PLUS,
+	"KP_ENTER":         KEYCODE_KP_ENTER,
+	"KP_1":             KEYCODE_KP_1,
+	"KP_2":             KEYCODE_KP_2,
+	"KP_3":             KEYCODE_KP_3,
+	"KP_4":             KEYCODE_KP_4,
+	"KP_5":             KEYCODE_KP_5,
+	"KP_6":             KEYCODE_KP_6,
+	"KP_7":             KEYCODE_KP_7,
+	"KP_8":             KEYCODE_KP_8,
+	"KP_9":             KEYCODE_KP_9,
+	"KP_0":             KEYCODE_KP_0,
+	"KP_PERIOD":        KEYCODE_KP_PERIOD,
+}
+
+// keyAliases other accepted names, including the KEYCODE_* constants that share a keycode with one above.
+var keyAliases = map[string]byte{
+	"RIGHT_CONTRO": KEYCODE_RIGHT_CONTRO,
+	"COLON":        KEYCODE_COLON,
+	"DOUBLE_QUOTE": KEYCODE_DOUBLE_QUOTE,
+	"TILDA":        KEYCODE_TILDA,
+	"MENU":         KEYCODE_APP,
+	"RETURN":       KEYCODE_ENTER,
+	"ESCAPE":       KEYCODE_ESC,
+	"PGUP":         KEYCODE_PAGE_UP,
+	"DEL":          KEYCODE_DELETE,
+	"PGDN":         KEYCODE_PAGE_DOWN,
+	"RIGHT":        KEYCODE_ARROW_RIGHT,
+	"LEFT":         KEYCODE_ARROW_LEFT,
+	"DOWN":         KEYCODE_ARROW_DOWN,
+	"UP":           KEYCODE_ARROW_UP,
+}
+
+// keycodeNames reverse of keyNames.
+var keycodeNames = map[byte]string{}
+
+func init() {
+	for name, key := range keyNames {
+		keycodeNames[key] = name
+	}
+	for name, key := range keyAliases {
+		keyNames[name] = key
+	}
+}
@@ -0,0 +1,210 @@
+package keyboard
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+const DEFAULT_LAYOUT string = "us"
+
+// Stroke a single key press as seen by the host: the modifiers held down and the key pressed.
+type Stroke struct {
+	Modifier byte
+	Keycode  byte
+}
+
+// Layout maps runes to the strokes needed to produce them on a host configured with a given keyboard layout.
+//
+//	Runes that have no key of their own are composed from a dead key followed by the base letter, e.g. on a German layout 'é' is '´' then 'e'.
+type Layout struct {
+	Name string
+	keys map[rune]Stroke
+	dead map[rune]Stroke
+
+	reverse     sync.Once
+	runes       map[Stroke]rune
+	deadStrokes map[Stroke]rune
+}
+
+// Strokes returns the sequence of strokes that types r. ok is false if the layout can not produce r.
+func (l *Layout) Strokes(r rune) (strokes []Stroke, ok bool) {
+	if s, ok := l.keys[r]; ok {
+		return []Stroke{s}, true
+	}
+	// A dead key on its own is typed by following it with a space.
+	if s, ok := l.dead[r]; ok {
+		return []Stroke{s, l.keys[' ']}, true
+	}
+	if c, ok := compositions[r]; ok {
+		accent, ok := l.dead[c.accent]
+		if !ok {
+			return nil, false
+		}
+		base, ok := l.keys[c.base]
+		if !ok {
+			return nil, false
+		}
+		return []Stroke{accent, base}, true
+	}
+	return nil, false
+}
+
+// Rune the rune s types on a host using this layout, the reverse of Strokes. dead is true when s is a dead key, the
+// host waits for the next stroke to know what to type. ok is false if the layout does not type anything with s.
+func (l *Layout) Rune(s Stroke) (r rune, dead bool, ok bool) {
+	l.reverse.Do(func() {
+		l.runes = make(map[Stroke]rune, len(l.keys))
+		l.deadStrokes = make(map[Stroke]rune, len(l.dead))
+		for r, s := range l.keys {
+			l.runes[s] = r
+		}
+		for r, s := range l.dead {
+			l.deadStrokes[s] = r
+		}
+	})
+	if r, ok := l.deadStrokes[s]; ok {
+		return r, true, true
+	}
+	r, ok = l.runes[s]
+	return r, false, ok
+}
+
+func (l *Layout) String() string {
+	return l.Name
+}
+
+// LookupLayout find a built-in layout by name, names are case insensitive.
+func LookupLayout(name string) (*Layout, error) {
+	if l, ok := layouts[strings.ToLower(name)]; ok {
+		return l, nil
+	}
+	return nil, &UnknownLayoutError{Name: name}
+}
+
+// LayoutNames the names of all built-in layouts.
+func LayoutNames() []string {
+	var names []string = make([]string, 0, len(layouts))
+	for name := range layouts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type UnknownLayoutError struct {
+	Name string
+}
+
+func (e *UnknownLayoutError) Error() string {
+	return "Unknown keyboard layout '" + e.Name + "', expected one of: " + strings.Join(LayoutNames(), ", ")
+}
+
+func (e *UnknownLayoutError) String() string {
+	return e.Error()
+}
+
+// keyDef describes one physical key and the runes it produces unshifted, shifted and with AltGr (right alt) held.
+//
+//	A zero rune means the combination produces nothing useful.
+type keyDef struct {
+	keycode byte
+	normal  rune
+	shift   rune
+	altGr   rune
+}
+
+// newLayout builds a layout from its key definitions. Runes listed in deadKeys are dead keys on this layout.
+func newLayout(name string, deadKeys string, defs ...[]keyDef) *Layout {
+	var l = Layout{
+		Name: name,
+		keys: map[rune]Stroke{},
+		dead: map[rune]Stroke{},
+	}
+
+	add := func(r rune, s Stroke) {
+		if r == 0 {
+			return
+		}
+		if strings.ContainsRune(deadKeys, r) {
+			if _, ok := l.dead[r]; !ok {
+				l.dead[r] = s
+			}
+			return
+		}
+		if _, ok := l.keys[r]; !ok {
+			l.keys[r] = s
+		}
+	}
+
+	for _, keys := range defs {
+		for _, d := range keys {
+			add(d.normal, Stroke{MODIFIER_NOT_SET, d.keycode})
+			add(d.shift, Stroke{MODIFIER_KEY_LEFT_SHIFT, d.keycode})
+			add(d.altGr, Stroke{MODIFIER_KEY_RIGHT_ALT, d.keycode})
+		}
+	}
+
+	return &l
+}
+
+// letterKeys key definitions for the letters a-z where each letter sits on the key of the same name.
+//
+//	swap lists pairs of letters whose keys are exchanged, e.g. "yz" for QWERTZ.
+func letterKeys(swap ...string) []keyDef {
+	var defs []keyDef
+	var moved = map[rune]rune{}
+
+	for _, pair := range swap {
+		p := []rune(pair)
+		moved[p[0]], moved[p[1]] = p[1], p[0]
+	}
+	for c := 'a'; c <= 'z'; c++ {
+		letter := c
+		if m, ok := moved[c]; ok {
+			letter = m
+		}
+		defs = append(defs, keyDef{KEYCODE_A + byte(c-'a'), letter, letter - 'a' + 'A', 0})
+	}
+	return defs
+}
+
+// whitespaceKeys keys that produce the same thing on every layout.
+var whitespaceKeys = []keyDef{
+	{KEYCODE_SPACE, ' ', 0, 0},
+	{KEYCODE_TAB, '\t', 0, 0},
+	{KEYCODE_ENTER, '\n', 0, 0},
+}
+
+type composition struct {
+	accent rune
+	base   rune
+}
+
+// Compose the rune a dead key accent followed by base types, ok is false if the pair does not compose.
+func Compose(accent, base rune) (r rune, ok bool) {
+	for r, c := range compositions {
+		if c.accent == accent && c.base == base {
+			return r, true
+		}
+	}
+	return 0, false
+}
+
+// compositions the runes that can be typed as a dead key accent followed by a base letter.
+var compositions = map[rune]composition{}
+
+func init() {
+	for accent, pairs := range map[rune][2]string{
+		'`': {"aeiouAEIOU", "àèìòùÀÈÌÒÙ"},
+		'´': {"aeiouyAEIOUY", "áéíóúýÁÉÍÓÚÝ"},
+		'^': {"aeiouAEIOU", "âêîôûÂÊÎÔÛ"},
+		'~': {"anoANO", "ãñõÃÑÕ"},
+		'¨': {"aeiouyAEIOU", "äëïöüÿÄËÏÖÜ"},
+	} {
+		base, composed := []rune(pairs[0]), []rune(pairs[1])
+		for i := range base {
+			compositions[composed[i]] = composition{accent, base[i]}
+		}
+	}
+}
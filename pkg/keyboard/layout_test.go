@@ -0,0 +1,61 @@
+package keyboard
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLayoutStrokes(t *testing.T) {
+	var tests = []struct {
+		layout string
+		r      rune
+		want   []Stroke
+	}{
+		{"us", 'a', []Stroke{{MODIFIER_NOT_SET, KEYCODE_A}}},
+		{"us", 'A', []Stroke{{MODIFIER_KEY_LEFT_SHIFT, KEYCODE_A}}},
+		{"de", 'z', []Stroke{{MODIFIER_NOT_SET, KEYCODE_Y}}},
+		{"de", 'é', []Stroke{{MODIFIER_NOT_SET, KEYCODE_EQUAL}, {MODIFIER_NOT_SET, KEYCODE_E}}},
+		{"fr", 'a', []Stroke{{MODIFIER_NOT_SET, KEYCODE_Q}}},
+		{"fr", '1', []Stroke{{MODIFIER_KEY_LEFT_SHIFT, KEYCODE_1}}},
+		{"fr", 'ê', []Stroke{{MODIFIER_NOT_SET, KEYCODE_SQBRAK_LEFT}, {MODIFIER_NOT_SET, KEYCODE_E}}},
+		{"fr", 'â', []Stroke{{MODIFIER_NOT_SET, KEYCODE_SQBRAK_LEFT}, {MODIFIER_NOT_SET, KEYCODE_Q}}},
+		{"fr", 'ë', []Stroke{{MODIFIER_KEY_LEFT_SHIFT, KEYCODE_SQBRAK_LEFT}, {MODIFIER_NOT_SET, KEYCODE_E}}},
+		{"fr", '^', []Stroke{{MODIFIER_NOT_SET, KEYCODE_SQBRAK_LEFT}, {MODIFIER_NOT_SET, KEYCODE_SPACE}}},
+	}
+
+	for _, test := range tests {
+		layout, err := LookupLayout(test.layout)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, ok := layout.Strokes(test.r)
+		if !ok {
+			t.Errorf("%s: Strokes(%q) not ok", test.layout, test.r)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: Strokes(%q) = %v, want %v", test.layout, test.r, got, test.want)
+		}
+	}
+}
+
+func TestLayoutDeadKeysCompose(t *testing.T) {
+	for _, name := range LayoutNames() {
+		layout, _ := LookupLayout(name)
+		for accent, stroke := range layout.dead {
+			r, dead, ok := layout.Rune(stroke)
+			if !ok || !dead || r != accent {
+				t.Errorf("%s: Rune(%v) = %q, %v, %v, want dead %q", name, stroke, r, dead, ok, accent)
+			}
+		}
+	}
+}
+
+func TestLookupLayoutUnknown(t *testing.T) {
+	if _, err := LookupLayout("klingon"); err == nil {
+		t.Error("LookupLayout(klingon) returned no error")
+	}
+	if l, err := LookupLayout("FR"); err != nil || l.Name != "fr" {
+		t.Errorf("LookupLayout(FR) = %v, %v", l, err)
+	}
+}
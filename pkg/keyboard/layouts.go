@@ -0,0 +1,166 @@
+package keyboard
+
+/*
+Built-in layouts.
+
+	Key definitions are listed by the US key that sits in the same physical position, e.g. on the German layout KEYCODE_Y types 'z'.
+	When two keys produce the same rune the first definition wins, so layout specific keys are listed before the letters.
+	References:
+	  https://kbdlayout.info/
+	  https://learn.microsoft.com/en-us/globalization/keyboards/
+*/
+var layouts = map[string]*Layout{
+	"us":     usLayout(),
+	"uk":     newLayout("uk", "", ukKeys, letterKeys(), whitespaceKeys),
+	"de":     newLayout("de", "´`^", deKeys, letterKeys("yz"), whitespaceKeys),
+	"fr":     newLayout("fr", "^¨~`", frKeys, letterKeys("aq", "zw"), whitespaceKeys),
+	"es":     newLayout("es", "´`^¨~", esKeys, letterKeys(), whitespaceKeys),
+	"nordic": newLayout("nordic", "´`¨^~", nordicKeys, letterKeys(), whitespaceKeys),
+}
+
+// usLayout US QWERTY, the mapping ASCII_to_keycode has always used.
+func usLayout() *Layout {
+	var l *Layout = newLayout("us", "")
+
+	for c := 0; c < 128; c++ {
+		if modifier, keycode := ASCII_to_keycode(byte(c)); keycode != KEYCODE_NIL {
+			l.keys[rune(c)] = Stroke{modifier, keycode}
+		}
+	}
+	return l
+}
+
+var ukKeys = []keyDef{
+	{KEYCODE_1, '1', '!', 0},
+	{KEYCODE_2, '2', '"', 0},
+	{KEYCODE_3, '3', '£', 0},
+	{KEYCODE_4, '4', '$', '€'},
+	{KEYCODE_5, '5', '%', 0},
+	{KEYCODE_6, '6', '^', 0},
+	{KEYCODE_7, '7', '&', 0},
+	{KEYCODE_8, '8', '*', 0},
+	{KEYCODE_9, '9', '(', 0},
+	{KEYCODE_0, '0', ')', 0},
+	{KEYCODE_MINUS, '-', '_', 0},
+	{KEYCODE_EQUAL, '=', '+', 0},
+	{KEYCODE_SQBRAK_LEFT, '[', '{', 0},
+	{KEYCODE_SQBRAK_RIGHT, ']', '}', 0},
+	{KEYCODE_NON_US_HASH, '#', '~', 0},
+	{KEYCODE_SEMICOLON, ';', ':', 0},
+	{KEYCODE_SINGLE_QUOTE, '\'', '@', 0},
+	{KEYCODE_BACK_TICK, '`', '¬', '¦'},
+	{KEYCODE_COMMA, ',', '<', 0},
+	{KEYCODE_PERIOD, '.', '>', 0},
+	{KEYCODE_SLASH, '/', '?', 0},
+	{KEYCODE_NON_US_BACKSLASH, '\\', '|', 0},
+}
+
+// deKeys German QWERTZ (T1).
+var deKeys = []keyDef{
+	{KEYCODE_1, '1', '!', 0},
+	{KEYCODE_2, '2', '"', '²'},
+	{KEYCODE_3, '3', '§', '³'},
+	{KEYCODE_4, '4', '$', 0},
+	{KEYCODE_5, '5', '%', 0},
+	{KEYCODE_6, '6', '&', 0},
+	{KEYCODE_7, '7', '/', '{'},
+	{KEYCODE_8, '8', '(', '['},
+	{KEYCODE_9, '9', ')', ']'},
+	{KEYCODE_0, '0', '=', '}'},
+	{KEYCODE_MINUS, 'ß', '?', '\\'},
+	{KEYCODE_EQUAL, '´', '`', 0},
+	{KEYCODE_SQBRAK_LEFT, 'ü', 'Ü', 0},
+	{KEYCODE_SQBRAK_RIGHT, '+', '*', '~'},
+	{KEYCODE_NON_US_HASH, '#', '\'', 0},
+	{KEYCODE_SEMICOLON, 'ö', 'Ö', 0},
+	{KEYCODE_SINGLE_QUOTE, 'ä', 'Ä', 0},
+	{KEYCODE_BACK_TICK, '^', '°', 0},
+	{KEYCODE_COMMA, ',', ';', 0},
+	{KEYCODE_PERIOD, '.', ':', 0},
+	{KEYCODE_SLASH, '-', '_', 0},
+	{KEYCODE_NON_US_BACKSLASH, '<', '>', '|'},
+	{KEYCODE_Q, 0, 0, '@'},
+	{KEYCODE_E, 0, 0, '€'},
+	{KEYCODE_M, 0, 0, 'µ'},
+}
+
+// frKeys French AZERTY. Digits need shift.
+var frKeys = []keyDef{
+	{KEYCODE_1, '&', '1', 0},
+	{KEYCODE_2, 'é', '2', '~'},
+	{KEYCODE_3, '"', '3', '#'},
+	{KEYCODE_4, '\'', '4', '{'},
+	{KEYCODE_5, '(', '5', '['},
+	{KEYCODE_6, '-', '6', '|'},
+	{KEYCODE_7, 'è', '7', '`'},
+	{KEYCODE_8, '_', '8', '\\'},
+	// AltGr+9 is a plain '^', it is left out so '^' maps to the dead key on SQBRAK_LEFT that composes ê, â, î, ô and û.
+	{KEYCODE_9, 'ç', '9', 0},
+	{KEYCODE_0, 'à', '0', '@'},
+	{KEYCODE_MINUS, ')', '°', ']'},
+	{KEYCODE_EQUAL, '=', '+', '}'},
+	{KEYCODE_SQBRAK_LEFT, '^', '¨', 0},
+	{KEYCODE_SQBRAK_RIGHT, '$', '£', '¤'},
+	{KEYCODE_NON_US_HASH, '*', 'µ', 0},
+	{KEYCODE_SEMICOLON, 'm', 'M', 0},
+	{KEYCODE_SINGLE_QUOTE, 'ù', '%', 0},
+	{KEYCODE_BACK_TICK, '²', 0, 0},
+	{KEYCODE_M, ',', '?', 0},
+	{KEYCODE_COMMA, ';', '.', 0},
+	{KEYCODE_PERIOD, ':', '/', 0},
+	{KEYCODE_SLASH, '!', '§', 0},
+	{KEYCODE_NON_US_BACKSLASH, '<', '>', 0},
+	{KEYCODE_E, 0, 0, '€'},
+}
+
+// esKeys Spanish (Spain).
+var esKeys = []keyDef{
+	{KEYCODE_1, '1', '!', '|'},
+	{KEYCODE_2, '2', '"', '@'},
+	{KEYCODE_3, '3', '·', '#'},
+	{KEYCODE_4, '4', '$', '~'},
+	{KEYCODE_5, '5', '%', '€'},
+	{KEYCODE_6, '6', '&', '¬'},
+	{KEYCODE_7, '7', '/', 0},
+	{KEYCODE_8, '8', '(', 0},
+	{KEYCODE_9, '9', ')', 0},
+	{KEYCODE_0, '0', '=', 0},
+	{KEYCODE_MINUS, '\'', '?', 0},
+	{KEYCODE_EQUAL, '¡', '¿', 0},
+	{KEYCODE_SQBRAK_LEFT, '`', '^', '['},
+	{KEYCODE_SQBRAK_RIGHT, '+', '*', ']'},
+	{KEYCODE_NON_US_HASH, 'ç', 'Ç', '}'},
+	{KEYCODE_SEMICOLON, 'ñ', 'Ñ', 0},
+	{KEYCODE_SINGLE_QUOTE, '´', '¨', '{'},
+	{KEYCODE_BACK_TICK, 'º', 'ª', '\\'},
+	{KEYCODE_COMMA, ',', ';', 0},
+	{KEYCODE_PERIOD, '.', ':', 0},
+	{KEYCODE_SLASH, '-', '_', 0},
+	{KEYCODE_NON_US_BACKSLASH, '<', '>', 0},
+}
+
+// nordicKeys Swedish / Finnish.
+var nordicKeys = []keyDef{
+	{KEYCODE_1, '1', '!', 0},
+	{KEYCODE_2, '2', '"', '@'},
+	{KEYCODE_3, '3', '#', '£'},
+	{KEYCODE_4, '4', '¤', '$'},
+	{KEYCODE_5, '5', '%', '€'},
+	{KEYCODE_6, '6', '&', 0},
+	{KEYCODE_7, '7', '/', '{'},
+	{KEYCODE_8, '8', '(', '['},
+	{KEYCODE_9, '9', ')', ']'},
+	{KEYCODE_0, '0', '=', '}'},
+	{KEYCODE_MINUS, '+', '?', '\\'},
+	{KEYCODE_EQUAL, '´', '`', 0},
+	{KEYCODE_SQBRAK_LEFT, 'å', 'Å', 0},
+	{KEYCODE_SQBRAK_RIGHT, '¨', '^', '~'},
+	{KEYCODE_NON_US_HASH, '\'', '*', 0},
+	{KEYCODE_SEMICOLON, 'ö', 'Ö', 0},
+	{KEYCODE_SINGLE_QUOTE, 'ä', 'Ä', 0},
+	{KEYCODE_BACK_TICK, '§', '½', 0},
+	{KEYCODE_COMMA, ',', ';', 0},
+	{KEYCODE_PERIOD, '.', ':', 0},
+	{KEYCODE_SLASH, '-', '_', 0},
+	{KEYCODE_NON_US_BACKSLASH, '<', '>', '|'},
+}
@@ -0,0 +1,130 @@
+package keyboard
+
+import (
+	"io"
+	"strings"
+	"sync"
+)
+
+/*
+LED output report, sent by the host to the keyboard whenever a lock key changes state.
+
+	Bit 7 - 5   Bit 4   Bit 3     Bit 2     Bit 1     Bit 0
+	┏━━━━━━━━━┯━━━━━━━┯━━━━━━━━━┯━━━━━━━━━┯━━━━━━━━━┯━━━━━━━━━┓
+	┃ Padding │ Kana  │ Compose │ Scroll  │ Caps    │ Num     ┃
+	┃         │       │         │ Lock    │ Lock    │ Lock    ┃
+	┗━━━━━━━━━┷━━━━━━━┷━━━━━━━━━┷━━━━━━━━━┷━━━━━━━━━┷━━━━━━━━━┛
+*/
+type LEDState byte
+
+const (
+	LED_NUM_LOCK    LEDState = 0b0000_0001
+	LED_CAPS_LOCK   LEDState = 0b0000_0010
+	LED_SCROLL_LOCK LEDState = 0b0000_0100
+	LED_COMPOSE     LEDState = 0b0000_1000
+	LED_KANA        LEDState = 0b0001_0000
+)
+
+// LED_REPORT_SZ size of the LED output report in bytes.
+const LED_REPORT_SZ int = 1
+
+func (l LEDState) NumLock() bool    { return l&LED_NUM_LOCK != 0 }
+func (l LEDState) CapsLock() bool   { return l&LED_CAPS_LOCK != 0 }
+func (l LEDState) ScrollLock() bool { return l&LED_SCROLL_LOCK != 0 }
+func (l LEDState) Compose() bool    { return l&LED_COMPOSE != 0 }
+func (l LEDState) Kana() bool       { return l&LED_KANA != 0 }
+
+// String the LEDs that are on, e.g. "Num Lock, Caps Lock", or "none".
+func (l LEDState) String() string {
+	var on []string
+
+	for _, led := range []struct {
+		bit  LEDState
+		name string
+	}{{LED_NUM_LOCK, "Num Lock"}, {LED_CAPS_LOCK, "Caps Lock"}, {LED_SCROLL_LOCK, "Scroll Lock"}, {LED_COMPOSE, "Compose"}, {LED_KANA, "Kana"}} {
+		if l&led.bit != 0 {
+			on = append(on, led.name)
+		}
+	}
+	if len(on) == 0 {
+		return "none"
+	}
+	return strings.Join(on, ", ")
+}
+
+// ledListener the last LED state the host sent and who to tell when it changes.
+type ledListener struct {
+	mu          sync.Mutex
+	state       LEDState
+	known       bool
+	subscribers map[chan LEDState]struct{}
+}
+
+// ReadLEDs reads LED output reports from the device until it is closed or a read fails. Run it in its own goroutine.
+// It returns nil when the device reaches EOF, which is what a regular file standing in for the device does.
+func (f *File) ReadLEDs() error {
+	var buf []byte = make([]byte, ReportSz)
+
+	for {
+		n, err := f.Device.Read(buf)
+		if n >= LED_REPORT_SZ {
+			f.setLEDs(LEDState(buf[0]))
+		}
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// LEDs the last LED state sent by the host. known is false until the host has sent one.
+func (f *File) LEDs() (state LEDState, known bool) {
+	f.leds.mu.Lock()
+	defer f.leds.mu.Unlock()
+
+	return f.leds.state, f.leds.known
+}
+
+// SubscribeLEDs returns a channel that receives the LED state each time it changes. Slow readers only see the latest
+// state. Call cancel to stop receiving, the channel is closed.
+func (f *File) SubscribeLEDs() (changes <-chan LEDState, cancel func()) {
+	var ch = make(chan LEDState, 1)
+
+	f.leds.mu.Lock()
+	defer f.leds.mu.Unlock()
+
+	if f.leds.subscribers == nil {
+		f.leds.subscribers = map[chan LEDState]struct{}{}
+	}
+	f.leds.subscribers[ch] = struct{}{}
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			f.leds.mu.Lock()
+			defer f.leds.mu.Unlock()
+
+			delete(f.leds.subscribers, ch)
+			close(ch)
+		})
+	}
+}
+
+func (f *File) setLEDs(state LEDState) {
+	f.leds.mu.Lock()
+	defer f.leds.mu.Unlock()
+
+	if f.leds.known && f.leds.state == state {
+		return
+	}
+	f.leds.state, f.leds.known = state, true
+	for ch := range f.leds.subscribers {
+		// Replace a state the subscriber has not read yet rather than block the reader.
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- state
+	}
+}
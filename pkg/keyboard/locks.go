@@ -0,0 +1,151 @@
+package keyboard
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+/*
+Lock keys
+
+	The host applies its own Caps Lock and Num Lock state to the reports it receives, so with Caps Lock on "Hello"
+	arrives as "hELLO" and keypad digits move the cursor when Num Lock is off. A LockStrategy decides what to do about it
+	using the LED state from ReadLEDs. Nothing is changed until the host has reported its LEDs.
+
+	LOCKS_IGNORE   type as if every lock is off.
+	LOCKS_INVERT   flip shift on letters while Caps Lock is on.
+	LOCKS_TOGGLE   turn Caps Lock off before typing and back on afterwards.
+
+	Both LOCKS_INVERT and LOCKS_TOGGLE turn Num Lock on while typing text that uses the keypad, then turn it back off.
+*/
+type LockStrategy string
+
+const (
+	LOCKS_IGNORE LockStrategy = "ignore"
+	LOCKS_INVERT LockStrategy = "invert"
+	LOCKS_TOGGLE LockStrategy = "toggle"
+)
+
+// ParseLockStrategy find a strategy by name, names are case insensitive. An empty name is LOCKS_IGNORE.
+func ParseLockStrategy(name string) (LockStrategy, error) {
+	switch s := LockStrategy(strings.ToLower(strings.TrimSpace(name))); s {
+	case "":
+		return LOCKS_IGNORE, nil
+	case LOCKS_IGNORE, LOCKS_INVERT, LOCKS_TOGGLE:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown lock strategy '%s', expected one of: %s, %s, %s", name, LOCKS_IGNORE, LOCKS_INVERT, LOCKS_TOGGLE)
+}
+
+// session the settings for a single write.
+type session struct {
+	opts       Options
+	f          *File
+	invertCaps bool //invertCaps flip shift on letters, the host has Caps Lock on.
+	rhythm     *rhythm
+	runes      int //runes characters typed so far.
+}
+
+// typed counts a character as typed.
+func (s *session) typed() {
+	s.runes++
+	if s.opts.Progress != nil {
+		s.opts.Progress(s.runes)
+	}
+}
+
+// strokes the strokes that type c on the host.
+func (s *session) strokes(c rune) []Stroke {
+	strokes, entered := s.f.strokes(c, s.opts)
+
+	// An entered rune is typed by its code, Caps Lock does not change it.
+	if !s.invertCaps || !isCased(c) || entered {
+		return strokes
+	}
+	// Only the last stroke types the letter, the ones before it are dead keys.
+	inverted := append([]Stroke{}, strokes...)
+	last := &inverted[len(inverted)-1]
+	if last.Modifier&^MODIFIER_KEY_LEFT_SHIFT == MODIFIER_NOT_SET {
+		last.Modifier ^= MODIFIER_KEY_LEFT_SHIFT
+	}
+	return inverted
+}
+
+// isCased Caps Lock only affects letters that have an upper and lower case.
+func isCased(c rune) bool {
+	return unicode.IsLetter(c) && unicode.ToUpper(c) != unicode.ToLower(c)
+}
+
+// startSession prepares the host's lock keys for typing tokens. Call restore when done to put them back.
+func (f *File) startSession(tokens []Token, opts Options) (sess *session, restore func() error, err error) {
+	var strategy LockStrategy = opts.LockStrategy
+	var tapped []byte
+
+	sess = &session{opts: opts, f: f, rhythm: f.newRhythm(opts)}
+	restore = func() error {
+		// Tap the lock keys in reverse so the host ends up where it started.
+		for i := len(tapped) - 1; i >= 0; i-- {
+			if _, err := f.pressChord(MODIFIER_NOT_SET, []byte{tapped[i]}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if strategy == "" {
+		strategy = f.LockStrategy
+	}
+	leds, known := f.LEDs()
+	if strategy == "" || strategy == LOCKS_IGNORE || !known {
+		return sess, restore, nil
+	}
+
+	if !leds.NumLock() && usesKeypad(tokens, sess) {
+		if _, err = f.pressChord(MODIFIER_NOT_SET, []byte{KEYCODE_NUM_LOCK}); err != nil {
+			return nil, nil, err
+		}
+		tapped = append(tapped, KEYCODE_NUM_LOCK)
+	}
+	if leds.CapsLock() {
+		switch strategy {
+		case LOCKS_INVERT:
+			sess.invertCaps = true
+		case LOCKS_TOGGLE:
+			if _, err = f.pressChord(MODIFIER_NOT_SET, []byte{KEYCODE_CAPS_LOCK}); err != nil {
+				restore()
+				return nil, nil, err
+			}
+			tapped = append(tapped, KEYCODE_CAPS_LOCK)
+		}
+	}
+
+	return sess, restore, nil
+}
+
+// usesKeypad true if any token types a keypad digit or period, those depend on Num Lock.
+func usesKeypad(tokens []Token, sess *session) bool {
+	for _, token := range tokens {
+		switch token.Kind {
+		case TokenText:
+			for _, c := range token.Text {
+				for _, stroke := range sess.strokes(c) {
+					if isNumLockKey(stroke.Keycode) {
+						return true
+					}
+				}
+			}
+		case TokenChord:
+			for _, key := range token.Keys {
+				if isNumLockKey(key) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func isNumLockKey(key byte) bool {
+	return key >= KEYCODE_KP_1 && key <= KEYCODE_KP_PERIOD
+}
@@ -0,0 +1,139 @@
+package keyboard
+
+/*
+Report planning
+
+	Typing each stroke as a press report followed by a release report costs two reports per character. The host only
+	needs to see each key go down with the right modifiers held, so the planner:
+
+	  - moves straight from one key to the next, the report that presses a key also releases the one before it
+	  - keeps the modifiers of the next stroke, so shift stays down across a run of capitals
+	  - inserts a release only when the same key is pressed twice in a row, otherwise the host would not see it go down
+	  - releases everything for a stroke with no key and no modifiers, Unicode entry ends with one to let go of Alt
+	  - ends with everything released
+
+	"Hello" is 10 reports typed one stroke at a time and 7 planned. DecodeReports turns either stream back into the
+	strokes the host sees, so a plan can be checked against the strokes it was made from.
+*/
+
+// frame one planned report. stroke is the index of the stroke whose key it presses or that it releases for, -1 if it
+// only releases keys.
+type frame struct {
+	state  Stroke
+	stroke int
+}
+
+var releaseFrame = frame{state: Stroke{MODIFIER_NOT_SET, KEYCODE_NIL}, stroke: -1}
+
+// planFrames plans the reports that type strokes. Strokes without a key or modifiers type nothing, they only release
+// what is down.
+func planFrames(strokes []Stroke) []frame {
+	var frames []frame = make([]frame, 0, len(strokes)+1)
+	var down Stroke = releaseFrame.state
+
+	for i, s := range strokes {
+		switch {
+		case s.Keycode == KEYCODE_NIL && s.Modifier == MODIFIER_NOT_SET:
+			if down != releaseFrame.state {
+				frames = append(frames, frame{releaseFrame.state, i})
+				down = releaseFrame.state
+			}
+			continue
+		case s.Keycode == KEYCODE_NIL:
+			// Modifiers pressed on their own are tapped from a clean state so the host sees them go down and up.
+			if down != releaseFrame.state {
+				frames = append(frames, releaseFrame)
+			}
+			frames = append(frames, frame{s, i}, releaseFrame)
+			down = releaseFrame.state
+			continue
+		case s.Keycode == down.Keycode:
+			frames = append(frames, frame{Stroke{s.Modifier, KEYCODE_NIL}, -1})
+		}
+		frames = append(frames, frame{s, i})
+		down = s
+	}
+	if down != releaseFrame.state {
+		frames = append(frames, releaseFrame)
+	}
+	return frames
+}
+
+// PlanReports the reports that type strokes with as few reports as possible, see Report planning.
+func PlanReports(strokes []Stroke) ([]Report, error) {
+	var frames []frame = planFrames(strokes)
+	var reports []Report = make([]Report, len(frames))
+
+	for i, fr := range frames {
+		r, err := NewReport(fr.state.Modifier, fr.state.Keycode)
+		if err != nil {
+			return nil, err
+		}
+		reports[i] = r
+	}
+	return reports, nil
+}
+
+// StrokeReports the reports that type strokes one at a time, a press then a release for each.
+func StrokeReports(strokes []Stroke) ([]Report, error) {
+	var reports []Report = make([]Report, 0, 2*len(strokes))
+
+	for _, s := range strokes {
+		r, err := NewReport(s.Modifier, s.Keycode)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, r, Report{})
+	}
+	return reports, nil
+}
+
+// DecodeReports the strokes a host sees in a stream of reports, starting with everything released. A stroke is
+// recorded each time a key goes down, with the modifiers held in that report. Modifiers that go down and back up
+// without a key being pressed are recorded as a stroke with KEYCODE_NIL.
+func DecodeReports(reports []Report) []Stroke {
+	var strokes []Stroke
+	var previous Report
+	var tapped byte // modifiers that went down and have not had a key pressed with them yet
+
+	for _, r := range reports {
+		modifiers := r[0]
+		if released := previous[0] &^ modifiers; released&tapped != 0 {
+			strokes = append(strokes, Stroke{released & tapped, KEYCODE_NIL})
+		}
+		tapped = (tapped | modifiers&^previous[0]) & modifiers
+
+		for _, key := range r[2:] {
+			if key != KEYCODE_NIL && !reportHasKey(previous, key) {
+				strokes = append(strokes, Stroke{modifiers, key})
+				tapped = 0
+			}
+		}
+		previous = r
+	}
+	return strokes
+}
+
+func reportHasKey(r Report, key byte) bool {
+	for _, k := range r[2:] {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// EquivalentReports true if both streams type the same strokes.
+func EquivalentReports(a, b []Report) bool {
+	var sa, sb []Stroke = DecodeReports(a), DecodeReports(b)
+
+	if len(sa) != len(sb) {
+		return false
+	}
+	for i := range sa {
+		if sa[i] != sb[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,89 @@
+package keyboard
+
+import "testing"
+
+// roundTripTexts text each layout types with its own keys and dead keys.
+var roundTripTexts = map[string]string{
+	"us": "Hello, World! AAA aa 123 ~`{}\n\tEOF",
+	"de": "Grüße, Jürgen! Ärger über Öl, café à 100 € ~ ^\n",
+	"fr": "Être à côté, ça coûte 12 € ? Où ça ! ê â î ô û\n",
+	"uk": "£5 @home #1 \"quoted\" ~ ¬ AB ba\n",
+}
+
+func layoutStrokes(t *testing.T, layout *Layout, text string) []Stroke {
+	var strokes []Stroke
+
+	for _, c := range text {
+		s, ok := layout.Strokes(c)
+		if !ok {
+			t.Fatalf("%s can not type %q", layout, c)
+		}
+		strokes = append(strokes, s...)
+	}
+	return strokes
+}
+
+// Planned reports type exactly the strokes that typing one stroke at a time does, in fewer reports.
+func TestPlanReportsEquivalent(t *testing.T) {
+	for name, text := range roundTripTexts {
+		layout, _ := LookupLayout(name)
+		strokes := layoutStrokes(t, layout, text)
+
+		planned, err := PlanReports(strokes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		single, err := StrokeReports(strokes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !EquivalentReports(planned, single) {
+			t.Errorf("%s: planned reports type %v, want %v", name, DecodeReports(planned), DecodeReports(single))
+		}
+		if len(planned) >= len(single) {
+			t.Errorf("%s: %d planned reports, %d one stroke at a time", name, len(planned), len(single))
+		}
+		if planned[len(planned)-1] != (Report{}) {
+			t.Errorf("%s: planned reports end with %v, not everything released", name, planned[len(planned)-1])
+		}
+	}
+}
+
+func TestPlanReportsHello(t *testing.T) {
+	var strokes = layoutStrokes(t, layouts[DEFAULT_LAYOUT], "Hello")
+
+	planned, _ := PlanReports(strokes)
+	single, _ := StrokeReports(strokes)
+	if len(single) != 10 || len(planned) != 7 {
+		t.Errorf("Hello is %d reports one stroke at a time and %d planned, want 10 and 7", len(single), len(planned))
+	}
+}
+
+// A stroke that presses nothing releases what is down, Unicode entry relies on it to let go of Alt.
+func TestPlanFramesEmptyStroke(t *testing.T) {
+	var strokes = []Stroke{
+		{MODIFIER_KEY_LEFT_ALT, KEYCODE_KP_1},
+		{MODIFIER_KEY_LEFT_ALT, KEYCODE_KP_2},
+		releaseStroke,
+		{MODIFIER_KEY_LEFT_ALT, KEYCODE_KP_3},
+		releaseStroke,
+		releaseStroke,
+	}
+	var want = []Report{
+		{MODIFIER_KEY_LEFT_ALT, 0, KEYCODE_KP_1},
+		{MODIFIER_KEY_LEFT_ALT, 0, KEYCODE_KP_2},
+		{},
+		{MODIFIER_KEY_LEFT_ALT, 0, KEYCODE_KP_3},
+		{},
+	}
+
+	got, _ := PlanReports(strokes)
+	if len(got) != len(want) {
+		t.Fatalf("PlanReports = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("report %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
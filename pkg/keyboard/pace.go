@@ -0,0 +1,89 @@
+package keyboard
+
+import (
+	"context"
+	"time"
+)
+
+// MAX_PACE_LAG how far behind schedule a write may fall before the schedule restarts from now. Up to this the following
+// waits are shortened to catch up, past it catching up would send a burst of keys the host may drop.
+const MAX_PACE_LAG time.Duration = 100 * time.Millisecond
+
+// pacer schedules reports against deadlines measured from the start of a write rather than sleeping after each one,
+// so the time spent writing and timer slack do not add up over a long write.
+type pacer struct {
+	now   func() time.Time                                 //now the clock the schedule keeps to, time.Now outside tests.
+	sleep func(ctx context.Context, d time.Duration) error //sleep waits d or until ctx is done.
+
+	start    time.Time
+	deadline time.Time
+	target   time.Duration
+	strokes  int
+	maxLate  time.Duration
+}
+
+func newPacer() *pacer {
+	return newPacerClock(time.Now, sleepContext)
+}
+
+func newPacerClock(now func() time.Time, sleep func(context.Context, time.Duration) error) *pacer {
+	var start = now()
+	return &pacer{now: now, sleep: sleep, start: start, deadline: start}
+}
+
+// wait moves the deadline d on and sleeps until it is reached or ctx is done.
+func (p *pacer) wait(ctx context.Context, d time.Duration) error {
+	// time.Now carries a monotonic reading, so wall clock changes do not move deadlines.
+	late := p.now().Sub(p.deadline)
+	if late > p.maxLate {
+		p.maxLate = late
+	}
+	if late > MAX_PACE_LAG {
+		p.deadline = p.now()
+	}
+	p.deadline = p.deadline.Add(d)
+	p.target += d
+	return p.sleep(ctx, p.deadline.Sub(p.now()))
+}
+
+// stats how the write kept to its schedule so far.
+func (p *pacer) stats() PaceStats {
+	var stats = PaceStats{
+		Strokes: p.strokes,
+		Target:  p.target,
+		Elapsed: p.now().Sub(p.start),
+		MaxLate: p.maxLate,
+	}
+	if stats.Target > 0 {
+		stats.TargetRate = float64(stats.Strokes) / stats.Target.Seconds()
+	}
+	if stats.Elapsed > 0 {
+		stats.AchievedRate = float64(stats.Strokes) / stats.Elapsed.Seconds()
+	}
+	return stats
+}
+
+// PaceStats how closely a delayed write kept to its timing. A stroke is one press and release.
+type PaceStats struct {
+	Strokes      int
+	Target       time.Duration //Target the time the write was scheduled to take.
+	Elapsed      time.Duration //Elapsed the time it took.
+	TargetRate   float64       //TargetRate strokes per second the schedule asked for.
+	AchievedRate float64       //AchievedRate strokes per second actually sent.
+	MaxLate      time.Duration //MaxLate the furthest a report fell behind its deadline.
+}
+
+// PaceStats the stats of the last delayed write to finish.
+func (f *File) PaceStats() PaceStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.pace
+}
+
+func (f *File) setPaceStats(stats PaceStats) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.pace = stats
+}
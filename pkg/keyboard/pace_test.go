@@ -0,0 +1,143 @@
+package keyboard
+
+import (
+	"context"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock time that only moves when a pacer sleeps or a test says a write took a while.
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+func (c *fakeClock) sleep(ctx context.Context, d time.Duration) error {
+	if d > 0 {
+		c.t = c.t.Add(d)
+	}
+	return ctx.Err()
+}
+
+// paceStrokes paces n strokes of hold and gap. Each report takes write to send, stroke i is held up by stall[i] more.
+func paceStrokes(n int, hold, gap, write time.Duration, stall map[int]time.Duration) PaceStats {
+	var clock = &fakeClock{t: time.Unix(0, 0)}
+	var pace = newPacerClock(clock.now, clock.sleep)
+
+	for i := 0; i < n; i++ {
+		clock.t = clock.t.Add(write + stall[i])
+		pace.wait(context.Background(), hold)
+		clock.t = clock.t.Add(write)
+		pace.strokes++
+		pace.wait(context.Background(), gap)
+	}
+	return pace.stats()
+}
+
+func TestPacerAbsorbsWriteTime(t *testing.T) {
+	stats := paceStrokes(50, 5*time.Millisecond, 5*time.Millisecond, time.Millisecond, nil)
+
+	if stats.Strokes != 50 || stats.Target != 500*time.Millisecond {
+		t.Errorf("%d strokes over %s, want 50 over 500ms", stats.Strokes, stats.Target)
+	}
+	if stats.Elapsed != stats.Target {
+		t.Errorf("elapsed %s, want the target %s", stats.Elapsed, stats.Target)
+	}
+	if stats.TargetRate != 100 || stats.AchievedRate != 100 {
+		t.Errorf("rate %.2f/s of %.2f/s, want 100/s", stats.AchievedRate, stats.TargetRate)
+	}
+	if stats.MaxLate != time.Millisecond {
+		t.Errorf("max late %s, want the 1ms a write takes", stats.MaxLate)
+	}
+}
+
+// A stall shorter than MAX_PACE_LAG is made up by shortening the waits after it.
+func TestPacerCatchesUp(t *testing.T) {
+	stats := paceStrokes(20, 5*time.Millisecond, 5*time.Millisecond, 0, map[int]time.Duration{3: 30 * time.Millisecond})
+
+	if stats.Elapsed != stats.Target {
+		t.Errorf("elapsed %s, want the target %s", stats.Elapsed, stats.Target)
+	}
+	if stats.MaxLate != 30*time.Millisecond {
+		t.Errorf("max late %s, want 30ms", stats.MaxLate)
+	}
+}
+
+// A stall past MAX_PACE_LAG restarts the schedule instead of sending a burst of keys.
+func TestPacerRestartsPastMaxLag(t *testing.T) {
+	const stall = MAX_PACE_LAG + 50*time.Millisecond
+	stats := paceStrokes(20, 5*time.Millisecond, 5*time.Millisecond, 0, map[int]time.Duration{3: stall})
+
+	if stats.Elapsed != stats.Target+stall {
+		t.Errorf("elapsed %s, want the target %s and the %s stall", stats.Elapsed, stats.Target, stall)
+	}
+	if stats.MaxLate != stall {
+		t.Errorf("max late %s, want %s", stats.MaxLate, stall)
+	}
+}
+
+func TestPacerCancelled(t *testing.T) {
+	var clock = &fakeClock{t: time.Unix(0, 0)}
+	var pace = newPacerClock(clock.now, clock.sleep)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	cancel()
+	if err := pace.wait(ctx, time.Millisecond); err != context.Canceled {
+		t.Errorf("wait after cancel = %v, want context.Canceled", err)
+	}
+}
+
+// BenchmarkPace types at 250 strokes per second into a pipe and reports the rate the keys went down at. It fails when
+// that is more than 5% off, a test would flake on a loaded machine.
+func BenchmarkPace(b *testing.B) {
+	const rate = 250.0
+	var text = strings.Repeat("The Quick brown FOX jumps, 1100 times.\n", 2)
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer r.Close()
+	presses := make(chan []time.Time)
+	go func() {
+		var times []time.Time
+		var report Report
+		for {
+			if _, err := io.ReadFull(r, report[:]); err != nil {
+				break
+			}
+			if report[2] != KEYCODE_NIL {
+				times = append(times, time.Now())
+			}
+		}
+		presses <- times
+	}()
+
+	var kb = File{Device: w, StrokeDelay: time.Duration(float64(time.Second) / rate / 2)}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := kb.WriteStringContext(context.Background(), text); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+	w.Close()
+
+	times := <-presses
+	if len(times) < 2 {
+		b.Fatalf("%d keys went down", len(times))
+	}
+	got := float64(len(times)-1) / times[len(times)-1].Sub(times[0]).Seconds()
+	b.ReportMetric(got, "strokes/s")
+	b.ReportMetric(float64(kb.PaceStats().MaxLate.Microseconds()), "max-late-µs")
+	if math.Abs(got-rate) > rate*0.05 {
+		b.Errorf("keys went down at %.2f/s, want %.2f/s ±5%%", got, rate)
+	}
+}
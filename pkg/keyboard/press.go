@@ -0,0 +1,148 @@
+package keyboard
+
+import "time"
+
+// keyState the modifiers and keys currently held down with Press.
+type keyState struct {
+	modifiers byte
+	keys      []byte
+}
+
+// report the report for the held keys with modifiers and keys pressed on top of them.
+func (k keyState) report(modifiers byte, keys ...byte) (Report, error) {
+	var all []byte = make([]byte, 0, len(k.keys)+len(keys))
+
+	all = append(all, k.keys...)
+	for _, key := range keys {
+		if !k.isDown(key) {
+			all = append(all, key)
+		}
+	}
+	return NewReport(k.modifiers|modifiers, all...)
+}
+
+func (k keyState) isDown(key byte) bool {
+	if isModifierKey(key) {
+		return k.modifiers&modifierBit(key) != 0
+	}
+	for _, held := range k.keys {
+		if held == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Press presses key and keeps it down until it is released with Release or ReleaseAll. Modifier keys such as
+// KEYCODE_LEFT_SHIFT are held as modifiers. Pressing a key that is already down does nothing.
+func (f *File) Press(key byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if key == KEYCODE_NIL || f.held.isDown(key) {
+		return nil
+	}
+	next := keyState{modifiers: f.held.modifiers, keys: append([]byte{}, f.held.keys...)}
+	if isModifierKey(key) {
+		next.modifiers |= modifierBit(key)
+	} else {
+		next.keys = append(next.keys, key)
+	}
+	return f.transition(next)
+}
+
+// Release lets go of a key held with Press. Releasing a key that is not down does nothing.
+func (f *File) Release(key byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.held.isDown(key) {
+		return nil
+	}
+	next := keyState{modifiers: f.held.modifiers}
+	if isModifierKey(key) {
+		next.modifiers &^= modifierBit(key)
+	}
+	for _, held := range f.held.keys {
+		if held != key {
+			next.keys = append(next.keys, held)
+		}
+	}
+	return f.transition(next)
+}
+
+// ReleaseAll lets go of every held key. The all zero report is always sent, so it can be used to clear stuck keys.
+func (f *File) ReleaseAll() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.transition(keyState{})
+}
+
+// Tap presses and releases key, waiting StrokeDelay after each. Held keys stay down, including key itself if it was
+// held with Press: the held state is sent again after the tap rather than a report without key.
+func (f *File) Tap(key byte) error {
+	if key == KEYCODE_NIL {
+		return nil
+	}
+	if err := f.sendHeld(key); err != nil {
+		return err
+	}
+	time.Sleep(f.StrokeDelay)
+	if err := f.sendHeld(KEYCODE_NIL); err != nil {
+		return err
+	}
+	time.Sleep(f.StrokeDelay)
+	return nil
+}
+
+// sendHeld sends the held keys with key pressed on top of them, without changing the held state.
+func (f *File) sendHeld(key byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var r Report
+	var err error
+	switch {
+	case key == KEYCODE_NIL:
+		r, err = f.held.report(MODIFIER_NOT_SET)
+	case isModifierKey(key):
+		r, err = f.held.report(modifierBit(key))
+	default:
+		r, err = f.held.report(MODIFIER_NOT_SET, key)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = f.Device.Write(r[:])
+	return err
+}
+
+// Held the modifiers and keys currently held down.
+func (f *File) Held() (modifiers byte, keys []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.held.modifiers, append([]byte{}, f.held.keys...)
+}
+
+// IsHeld reports whether key is held down with Press.
+func (f *File) IsHeld(key byte) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.held.isDown(key)
+}
+
+// transition sends the report for next and makes it the held state. f.mu must be held.
+func (f *File) transition(next keyState) error {
+	r, err := next.report(MODIFIER_NOT_SET)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Device.Write(r[:]); err != nil {
+		return err
+	}
+	f.held = next
+	return nil
+}
@@ -0,0 +1,95 @@
+package keyboard_test
+
+import (
+	"testing"
+
+	"github.com/scirelli/turkey-pi/pkg/keyboard"
+	"github.com/scirelli/turkey-pi/pkg/keyboard/hidtest"
+)
+
+// reportsOf the report data dev received, in order.
+func reportsOf(dev *hidtest.Device) []keyboard.Report {
+	var reports []keyboard.Report
+	for _, r := range dev.Reports() {
+		reports = append(reports, r.Data)
+	}
+	return reports
+}
+
+// report a report with at most ROLLOVER keys, which NewReport never rejects.
+func report(modifiers byte, keys ...byte) keyboard.Report {
+	r, _ := keyboard.NewReport(modifiers, keys...)
+	return r
+}
+
+func TestTap(t *testing.T) {
+	var tests = []struct {
+		name  string
+		press []byte // keys held with Press before the tap
+		tap   byte
+		want  []keyboard.Report
+	}{
+		{
+			name: "nothing held",
+			tap:  keyboard.KEYCODE_A,
+			want: []keyboard.Report{report(0, keyboard.KEYCODE_A), report(0)},
+		},
+		{
+			name:  "another key held",
+			press: []byte{keyboard.KEYCODE_LEFT_SHIFT, keyboard.KEYCODE_B},
+			tap:   keyboard.KEYCODE_A,
+			want: []keyboard.Report{
+				report(keyboard.MODIFIER_KEY_LEFT_SHIFT, keyboard.KEYCODE_B, keyboard.KEYCODE_A),
+				report(keyboard.MODIFIER_KEY_LEFT_SHIFT, keyboard.KEYCODE_B),
+			},
+		},
+		{
+			name:  "the key itself held",
+			press: []byte{keyboard.KEYCODE_A},
+			tap:   keyboard.KEYCODE_A,
+			want:  []keyboard.Report{report(0, keyboard.KEYCODE_A), report(0, keyboard.KEYCODE_A)},
+		},
+		{
+			name:  "a held modifier",
+			press: []byte{keyboard.KEYCODE_LEFT_CONTROL},
+			tap:   keyboard.KEYCODE_LEFT_CONTROL,
+			want: []keyboard.Report{
+				report(keyboard.MODIFIER_KEY_LEFT_CTRL),
+				report(keyboard.MODIFIER_KEY_LEFT_CTRL),
+			},
+		},
+		{
+			name: "a modifier",
+			tap:  keyboard.KEYCODE_RIGHT_ALT,
+			want: []keyboard.Report{report(keyboard.MODIFIER_KEY_RIGHT_ALT), report(0)},
+		},
+	}
+
+	for _, test := range tests {
+		dev := hidtest.NewDevice()
+		kb := keyboard.File{Device: dev}
+		for _, key := range test.press {
+			if err := kb.Press(key); err != nil {
+				t.Fatal(err)
+			}
+		}
+		heldModifiers, heldKeys := kb.Held()
+		dev.Reset()
+
+		if err := kb.Tap(test.tap); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		got, want := reportsOf(dev), test.want
+		if len(got) != len(want) {
+			t.Fatalf("%s: sent %v, want %v", test.name, got, want)
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Errorf("%s: report %d is %v, want %v", test.name, i, got[i], want[i])
+			}
+		}
+		if modifiers, keys := kb.Held(); modifiers != heldModifiers || string(keys) != string(heldKeys) {
+			t.Errorf("%s: held %08b %v after the tap, want %08b %v", test.name, modifiers, keys, heldModifiers, heldKeys)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package keyboard
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+/*
+Timing profiles
+
+	A TimingProfile sets how long each key is held down and the gap before the next key is pressed. Perfectly regular
+	input is dropped or throttled by some apps and games, so both can vary by a random jitter and longer pauses can
+	follow punctuation and newlines. Without a profile every wait is the File's StrokeDelay.
+
+	Built-in profiles:
+	  fast    10ms hold, 10ms gap, a little uniform jitter
+	  human   typist like, normally distributed hold and gap with pauses after punctuation and newlines, quicker
+	          repeated digits
+	  slow    for hosts that drop keys, 50ms hold, 150ms gap
+*/
+type TimingProfile struct {
+	Name string
+
+	Hold       time.Duration //Hold how long a key is held down.
+	HoldJitter Jitter
+	Gap        time.Duration //Gap time between releasing a key and pressing the next one.
+	GapJitter  Jitter
+
+	PunctuationPause time.Duration //PunctuationPause added to the gap after a punctuation character.
+	NewlinePause     time.Duration //NewlinePause added to the gap after a newline.
+	Rules            []DelayRule   //Rules adjust the gap after particular characters, every matching rule applies in order.
+
+	//Seed for the jitter, each write starts from it so the same text is typed with the same rhythm. 0 seeds from the clock.
+	Seed int64
+}
+
+func (p *TimingProfile) String() string {
+	return p.Name
+}
+
+// DelayRule changes the gap after the characters it matches, e.g. slower after Enter or faster for repeated digits:
+//
+//	DelayRule{Chars: "\n", Add: 300 * time.Millisecond}
+//	DelayRule{Chars: "0123456789", Repeat: true, Scale: 0.5}
+type DelayRule struct {
+	Chars  string        //Chars the rule applies to, empty matches every character.
+	Repeat bool          //Repeat only match a character that is the same as the one before it.
+	Scale  float64       //Scale multiplies the gap, 0 leaves it as is.
+	Add    time.Duration //Add is added to the gap after scaling, it may be negative.
+}
+
+func (d DelayRule) matches(c, previous rune) bool {
+	if d.Chars != "" && !strings.ContainsRune(d.Chars, c) {
+		return false
+	}
+	return !d.Repeat || c == previous
+}
+
+type Distribution string
+
+const (
+	JITTER_NONE    Distribution = "none"
+	JITTER_UNIFORM Distribution = "uniform" //JITTER_UNIFORM anywhere within ±Amount.
+	JITTER_NORMAL  Distribution = "normal"  //JITTER_NORMAL normally distributed with a standard deviation of Amount, capped at ±3 Amount.
+)
+
+// Jitter a random amount added to a delay. Delays never go below zero.
+type Jitter struct {
+	Distribution Distribution
+	Amount       time.Duration
+}
+
+// ParseDistribution find a jitter distribution by name, names are case insensitive. An empty name is JITTER_NONE.
+func ParseDistribution(name string) (Distribution, error) {
+	switch d := Distribution(strings.ToLower(strings.TrimSpace(name))); d {
+	case "":
+		return JITTER_NONE, nil
+	case JITTER_NONE, JITTER_UNIFORM, JITTER_NORMAL:
+		return d, nil
+	}
+	return "", fmt.Errorf("unknown jitter distribution '%s', expected one of: %s, %s, %s", name, JITTER_NONE, JITTER_UNIFORM, JITTER_NORMAL)
+}
+
+func (j Jitter) apply(d time.Duration, rng *rand.Rand) time.Duration {
+	var offset float64
+
+	switch j.Distribution {
+	case JITTER_UNIFORM:
+		offset = (rng.Float64()*2 - 1) * float64(j.Amount)
+	case JITTER_NORMAL:
+		offset = rng.NormFloat64() * float64(j.Amount)
+		if max := 3 * float64(j.Amount); offset > max {
+			offset = max
+		} else if offset < -max {
+			offset = -max
+		}
+	}
+	if d += time.Duration(offset); d < 0 {
+		return 0
+	}
+	return d
+}
+
+var timingProfiles = map[string]*TimingProfile{
+	"fast": {
+		Name:       "fast",
+		Hold:       10 * time.Millisecond,
+		HoldJitter: Jitter{JITTER_UNIFORM, 2 * time.Millisecond},
+		Gap:        10 * time.Millisecond,
+		GapJitter:  Jitter{JITTER_UNIFORM, 5 * time.Millisecond},
+	},
+	"human": {
+		Name:             "human",
+		Hold:             70 * time.Millisecond,
+		HoldJitter:       Jitter{JITTER_NORMAL, 15 * time.Millisecond},
+		Gap:              110 * time.Millisecond,
+		GapJitter:        Jitter{JITTER_NORMAL, 40 * time.Millisecond},
+		PunctuationPause: 150 * time.Millisecond,
+		NewlinePause:     400 * time.Millisecond,
+		Rules:            []DelayRule{{Chars: "0123456789", Repeat: true, Scale: 0.6}},
+	},
+	"slow": {
+		Name:             "slow",
+		Hold:             50 * time.Millisecond,
+		HoldJitter:       Jitter{JITTER_UNIFORM, 10 * time.Millisecond},
+		Gap:              150 * time.Millisecond,
+		GapJitter:        Jitter{JITTER_UNIFORM, 30 * time.Millisecond},
+		PunctuationPause: 100 * time.Millisecond,
+		NewlinePause:     300 * time.Millisecond,
+	},
+}
+var timingProfilesMu sync.RWMutex
+
+// LookupTimingProfile find a built-in or registered profile by name, names are case insensitive.
+func LookupTimingProfile(name string) (*TimingProfile, error) {
+	timingProfilesMu.RLock()
+	defer timingProfilesMu.RUnlock()
+
+	if p, ok := timingProfiles[strings.ToLower(name)]; ok {
+		return p, nil
+	}
+	return nil, &UnknownTimingProfileError{Name: name}
+}
+
+// RegisterTimingProfile adds p so LookupTimingProfile can find it, replacing any profile with the same name.
+func RegisterTimingProfile(p *TimingProfile) error {
+	if p.Name == "" {
+		return fmt.Errorf("a timing profile needs a name")
+	}
+	if p.Hold < 0 || p.Gap < 0 || p.PunctuationPause < 0 || p.NewlinePause < 0 {
+		return fmt.Errorf("timing profile '%s' has a negative delay", p.Name)
+	}
+	for i, rule := range p.Rules {
+		if rule.Scale < 0 {
+			return fmt.Errorf("timing profile '%s' rule %d has a negative scale", p.Name, i+1)
+		}
+	}
+
+	timingProfilesMu.Lock()
+	defer timingProfilesMu.Unlock()
+
+	timingProfiles[strings.ToLower(p.Name)] = p
+	return nil
+}
+
+// TimingProfileNames the names of all built-in and registered profiles.
+func TimingProfileNames() []string {
+	timingProfilesMu.RLock()
+	defer timingProfilesMu.RUnlock()
+
+	var names []string = make([]string, 0, len(timingProfiles))
+	for name := range timingProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type UnknownTimingProfileError struct {
+	Name string
+}
+
+func (e *UnknownTimingProfileError) Error() string {
+	return "Unknown timing profile '" + e.Name + "', expected one of: " + strings.Join(TimingProfileNames(), ", ")
+}
+
+func (e *UnknownTimingProfileError) String() string {
+	return e.Error()
+}
+
+// rhythm the delays for one write, drawn from a profile.
+type rhythm struct {
+	profile *TimingProfile
+	delay   time.Duration //delay used for everything without a profile.
+	rng     *rand.Rand
+	last    rune //last character typed, for DelayRule.Repeat.
+}
+
+// newRhythm the rhythm for a write with opts, the profile in opts wins over the File's.
+func (f *File) newRhythm(opts Options) *rhythm {
+	var profile *TimingProfile = opts.Timing
+
+	if profile == nil {
+		profile = f.Timing
+	}
+	if profile == nil {
+		return &rhythm{delay: f.StrokeDelay}
+	}
+	var seed int64 = profile.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &rhythm{profile: profile, rng: rand.New(rand.NewSource(seed))}
+}
+
+// hold how long to hold the next key down.
+func (r *rhythm) hold() time.Duration {
+	if r.profile == nil {
+		return r.delay
+	}
+	return r.profile.HoldJitter.apply(r.profile.Hold, r.rng)
+}
+
+// gap how long to wait after releasing a key, after is the character the key finished typing or 0 for none.
+func (r *rhythm) gap(after rune) time.Duration {
+	if r.profile == nil {
+		return r.delay
+	}
+	var d time.Duration = r.profile.GapJitter.apply(r.profile.Gap, r.rng)
+	switch {
+	case after == '\n':
+		d += r.profile.NewlinePause
+	case unicode.IsPunct(after):
+		d += r.profile.PunctuationPause
+	}
+	if after != 0 {
+		for _, rule := range r.profile.Rules {
+			if !rule.matches(after, r.last) {
+				continue
+			}
+			if rule.Scale != 0 {
+				d = time.Duration(float64(d) * rule.Scale)
+			}
+			d += rule.Add
+		}
+		r.last = after
+	}
+	if d < 0 {
+		return 0
+	}
+	return d
+}
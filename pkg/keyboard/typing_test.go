@@ -0,0 +1,281 @@
+package keyboard_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/scirelli/turkey-pi/pkg/keyboard"
+	"github.com/scirelli/turkey-pi/pkg/keyboard/hidtest"
+)
+
+// quickTiming a profile fast enough for tests whose pauses are still longer than the hold.
+var quickTiming = &keyboard.TimingProfile{
+	Name:             "quick",
+	Hold:             2 * time.Millisecond,
+	Gap:              time.Millisecond,
+	PunctuationPause: 60 * time.Millisecond,
+	NewlinePause:     60 * time.Millisecond,
+}
+
+// checkTyped decodes what dev received on a host using layout and checks it typed want.
+func checkTyped(t *testing.T, name string, dev *hidtest.Device, layout *keyboard.Layout, repeatDelay time.Duration, want string) {
+	t.Helper()
+	var d = hidtest.NewDecoder(layout)
+	d.RepeatDelay = repeatDelay
+
+	for _, r := range dev.Reports() {
+		d.Decode(r)
+	}
+	d.Finish()
+	if err := d.Check(want); err != nil {
+		t.Errorf("%s: %v", name, err)
+	}
+}
+
+func TestPlannedRoundTrip(t *testing.T) {
+	for name, text := range keyboard.RoundTripTexts {
+		layout, _ := keyboard.LookupLayout(name)
+		dev := hidtest.NewDevice()
+		kb := keyboard.File{Device: dev, Layout: layout, OptimizeReports: true}
+
+		if _, err := kb.WriteString(text); err != nil {
+			t.Fatal(err)
+		}
+		checkTyped(t, name, dev, layout, 0, text)
+	}
+}
+
+// Pauses after punctuation and newlines are longer than the host's repeat delay, no key may be down through them.
+func TestPlannedDelayedReleasesBeforePauses(t *testing.T) {
+	for name, text := range keyboard.RoundTripTexts {
+		layout, _ := keyboard.LookupLayout(name)
+		dev := hidtest.NewDevice()
+		kb := keyboard.File{Device: dev, Layout: layout, OptimizeReports: true, Timing: quickTiming}
+
+		var typed int
+		opts := keyboard.Options{Progress: func(runes int) { typed = runes }}
+		if _, err := kb.WriteStringDelayedWith(text, opts); err != nil {
+			t.Fatal(err)
+		}
+		checkTyped(t, name, dev, layout, 40*time.Millisecond, text)
+		if want := len([]rune(text)); typed != want {
+			t.Errorf("%s: progress ended at %d, want %d", name, typed, want)
+		}
+	}
+}
+
+// A rune no key types sends nothing but still counts as handled, planned or not.
+func TestUntypableRunesCounted(t *testing.T) {
+	const text = "☕a☕☕b\n☕"
+
+	for _, optimize := range []bool{false, true} {
+		kb := keyboard.File{Device: hidtest.NewDevice(), OptimizeReports: optimize, Timing: quickTiming}
+
+		var typed int
+		opts := keyboard.Options{Progress: func(runes int) { typed = runes }}
+		runes, err := kb.WriteStringContextWith(context.Background(), text, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := len([]rune(text)); runes != want || typed != want {
+			t.Errorf("optimize %v: %d runes typed and progress ended at %d, want %d", optimize, runes, typed, want)
+		}
+	}
+}
+
+func TestWriteStringRoundTrip(t *testing.T) {
+	for name, text := range keyboard.RoundTripTexts {
+		layout, _ := keyboard.LookupLayout(name)
+		dev := hidtest.NewDevice()
+		kb := keyboard.File{Device: dev, Layout: layout}
+
+		if _, err := kb.WriteString(text); err != nil {
+			t.Fatal(err)
+		}
+		checkTyped(t, name, dev, layout, 0, text)
+	}
+}
+
+func TestWriteStringDelayedRoundTrip(t *testing.T) {
+	for name, text := range keyboard.RoundTripTexts {
+		layout, _ := keyboard.LookupLayout(name)
+		dev := hidtest.NewDevice()
+		kb := keyboard.File{Device: dev, Layout: layout, Timing: quickTiming}
+
+		var typed int
+		opts := keyboard.Options{Progress: func(runes int) { typed = runes }}
+		if _, err := kb.WriteStringDelayedWith(text, opts); err != nil {
+			t.Fatal(err)
+		}
+		checkTyped(t, name, dev, layout, 40*time.Millisecond, text)
+		if want := len([]rune(text)); typed != want {
+			t.Errorf("%s: progress ended at %d, want %d", name, typed, want)
+		}
+	}
+}
+
+func TestEscapesRoundTrip(t *testing.T) {
+	const text = "Hi{ENTER}x{BACKSPACE}y{SHIFT+A}{{} {WAIT 1}!"
+	const want = "Hi\nyA{} !"
+
+	for _, optimize := range []bool{false, true} {
+		for _, delayed := range []bool{false, true} {
+			dev := hidtest.NewDevice()
+			kb := keyboard.File{Device: dev, OptimizeReports: optimize, Timing: quickTiming}
+			opts := keyboard.Options{Escapes: true}
+
+			var err error
+			if delayed {
+				_, err = kb.WriteStringDelayedWith(text, opts)
+			} else {
+				_, err = kb.WriteStringWith(text, opts)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkTyped(t, fmt.Sprintf("optimize %v delayed %v", optimize, delayed), dev, nil, 0, want)
+		}
+	}
+}
+
+// startHost a device whose host has leds on, read by kb until the test ends.
+func startHost(t *testing.T, kb *keyboard.File, leds keyboard.LEDState) *hidtest.Device {
+	t.Helper()
+	var dev = hidtest.NewDevice()
+	dev.HostLocks = true
+	kb.Device = dev
+
+	go kb.ReadLEDs()
+	t.Cleanup(func() { dev.Close() })
+	dev.SetLEDs(leds)
+	for deadline := time.Now().Add(time.Second); ; {
+		if state, known := kb.LEDs(); known && state == leds {
+			return dev
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("LED state never arrived")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestLockStrategies(t *testing.T) {
+	var tests = []struct {
+		strategy keyboard.LockStrategy
+		leds     keyboard.LEDState
+		text     string
+		want     string
+	}{
+		{keyboard.LOCKS_IGNORE, keyboard.LED_CAPS_LOCK, "Hello 1", "hELLO 1"},
+		{keyboard.LOCKS_INVERT, keyboard.LED_CAPS_LOCK, "Hello 1", "Hello 1"},
+		{keyboard.LOCKS_TOGGLE, keyboard.LED_CAPS_LOCK, "Hello 1", "Hello 1"},
+		{keyboard.LOCKS_IGNORE, 0, "{KP_1}{KP_2}", ""},
+		{keyboard.LOCKS_INVERT, keyboard.LED_CAPS_LOCK, "a{KP_1}{KP_2}", "a12"},
+		{keyboard.LOCKS_TOGGLE, 0, "{KP_1}{KP_2}", "12"},
+	}
+
+	for _, test := range tests {
+		for _, optimize := range []bool{false, true} {
+			kb := keyboard.File{LockStrategy: test.strategy, OptimizeReports: optimize}
+			dev := startHost(t, &kb, test.leds)
+
+			if _, err := kb.WriteStringWith(test.text, keyboard.Options{Escapes: true}); err != nil {
+				t.Fatal(err)
+			}
+			d := hidtest.NewDecoder(nil)
+			d.SetLEDs(test.leds)
+			for _, r := range dev.Reports() {
+				d.Decode(r)
+			}
+			d.Finish()
+			if err := d.Check(test.want); err != nil {
+				t.Errorf("%s %q optimize %v: %v", test.strategy, test.text, optimize, err)
+			}
+			// The host's locks are put back as they were.
+			if dev.LEDs() != test.leds {
+				t.Errorf("%s %q: host LEDs %s afterwards, want %s", test.strategy, test.text, dev.LEDs(), test.leds)
+			}
+		}
+	}
+}
+
+func TestUnicodeEntryRoundTrip(t *testing.T) {
+	var tests = []struct {
+		target string
+		layout string
+		text   string
+	}{
+		{keyboard.TARGET_LINUX, "us", "naïve ☕ 😀!"},
+		{keyboard.TARGET_LINUX, "fr", "1 ☕ ñ ø"},
+		{keyboard.TARGET_WINDOWS, "us", "naïve café € ½"},
+		{keyboard.TARGET_WINDOWS_HEX, "us", "naïve ☕ ŝ"},
+		{keyboard.TARGET_WINDOWS_HEX, "fr", "ª ☕ ā"},
+		{keyboard.TARGET_MACOS, "us", "naïve ☕ 😀!"},
+		{keyboard.TARGET_MACOS, "de", "zä ☕ 😀"},
+	}
+
+	for _, test := range tests {
+		entry, err := keyboard.LookupUnicodeEntry(test.target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		layout, _ := keyboard.LookupLayout(test.layout)
+		for _, optimize := range []bool{false, true} {
+			for _, delayed := range []bool{false, true} {
+				dev := hidtest.NewDevice()
+				kb := keyboard.File{Device: dev, Layout: layout, UnicodeEntry: entry, OptimizeReports: optimize, Timing: quickTiming}
+				if delayed {
+					_, err = kb.WriteStringDelayed(test.text)
+				} else {
+					_, err = kb.WriteString(test.text)
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				d := hidtest.NewDecoder(layout)
+				d.InputMethod = test.target
+				for _, r := range dev.Reports() {
+					d.Decode(r)
+				}
+				d.Finish()
+				if err := d.Check(test.text); err != nil {
+					t.Errorf("%s %s optimize %v delayed %v: %v", test.target, test.layout, optimize, delayed, err)
+				}
+			}
+		}
+	}
+}
+
+// The entry set per write wins over the File's, a rune neither can enter is lost.
+func TestUnicodeEntryOptions(t *testing.T) {
+	var windows, _ = keyboard.LookupUnicodeEntry(keyboard.TARGET_WINDOWS)
+	var hex, _ = keyboard.LookupUnicodeEntry(keyboard.TARGET_WINDOWS_HEX)
+	var dev = hidtest.NewDevice()
+	var kb = keyboard.File{Device: dev, UnicodeEntry: windows}
+
+	kb.WriteString("a☕b")
+	d := hidtest.NewDecoder(nil)
+	d.InputMethod = keyboard.TARGET_WINDOWS
+	for _, r := range dev.Reports() {
+		d.Decode(r)
+	}
+	d.Finish()
+	if err := d.Check("ab"); err != nil {
+		t.Error(err)
+	}
+
+	dev.Reset()
+	kb.WriteStringWith("a☕b", keyboard.Options{UnicodeEntry: hex})
+	d = hidtest.NewDecoder(nil)
+	d.InputMethod = keyboard.TARGET_WINDOWS_HEX
+	for _, r := range dev.Reports() {
+		d.Decode(r)
+	}
+	d.Finish()
+	if err := d.Check("a☕b"); err != nil {
+		t.Error(err)
+	}
+}
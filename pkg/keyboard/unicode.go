@@ -0,0 +1,189 @@
+package keyboard
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+/*
+Unicode entry
+
+	Runes the layout has no key for are typed with the host's own Unicode input method when a UnicodeEntry is set, so
+	"naïve ☕" still arrives intact on a US layout. Which method works depends on the host's operating system:
+
+	linux        Ctrl+Shift+U, the code point in hex, then Space. GTK and Qt apps under IBus or Fcitx.
+	windows      Alt held while typing 0 and the Windows-1252 code on the keypad, e.g. Alt+0233 for 'é'. Only runes
+	             in Windows-1252, outside RichEdit controls a larger code is taken modulo 256 in the OEM code page
+	             and types the wrong character. Use windows-hex for the rest.
+	windows-hex  Alt held, keypad +, then the code point in hex. Needs the registry value
+	             HKCU\Control Panel\Input Method\EnableHexNumpad set to "1" and a sign in again. Up to U+FFFF.
+	macos        Option held while typing each UTF-16 code unit as 4 hex digits. Needs the Unicode Hex Input source
+	             selected on the host.
+
+	The Windows methods type on the keypad so they need Num Lock on, a LockStrategy other than LOCKS_IGNORE turns it on
+	while typing. Strategies that hold a modifier end with a stroke that presses nothing, so it is released between
+	runes even when OptimizeReports would otherwise keep it down.
+*/
+type UnicodeEntry interface {
+	// Strokes the strokes that enter r on a host using layout. ok is false if the method can not enter r.
+	Strokes(r rune, layout *Layout) (strokes []Stroke, ok bool)
+}
+
+// UnicodeEntryFunc lets a function be a UnicodeEntry.
+type UnicodeEntryFunc func(r rune, layout *Layout) ([]Stroke, bool)
+
+func (fn UnicodeEntryFunc) Strokes(r rune, layout *Layout) ([]Stroke, bool) {
+	return fn(r, layout)
+}
+
+const (
+	TARGET_LINUX       string = "linux"
+	TARGET_WINDOWS     string = "windows"
+	TARGET_WINDOWS_HEX string = "windows-hex"
+	TARGET_MACOS       string = "macos"
+)
+
+var unicodeEntries = map[string]UnicodeEntry{
+	TARGET_LINUX:       UnicodeEntryFunc(linuxEntry),
+	TARGET_WINDOWS:     UnicodeEntryFunc(windowsAltCodeEntry),
+	TARGET_WINDOWS_HEX: UnicodeEntryFunc(windowsHexEntry),
+	TARGET_MACOS:       UnicodeEntryFunc(macEntry),
+}
+
+// LookupUnicodeEntry the entry method for a host operating system, see Unicode entry. Names are case insensitive, an
+// empty name is no method and returns nil.
+func LookupUnicodeEntry(target string) (UnicodeEntry, error) {
+	target = strings.ToLower(strings.TrimSpace(target))
+	if target == "" {
+		return nil, nil
+	}
+	if e, ok := unicodeEntries[target]; ok {
+		return e, nil
+	}
+	return nil, fmt.Errorf("unknown target OS '%s', expected one of: %s", target, strings.Join(UnicodeEntryNames(), ", "))
+}
+
+// UnicodeEntryNames the target operating systems LookupUnicodeEntry knows.
+func UnicodeEntryNames() []string {
+	var names []string = make([]string, 0, len(unicodeEntries))
+	for name := range unicodeEntries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// releaseStroke presses nothing, it ends a sequence that holds a modifier.
+var releaseStroke = Stroke{MODIFIER_NOT_SET, KEYCODE_NIL}
+
+// linuxEntry Ctrl+Shift+U, the hex digits as the layout types them, Space.
+func linuxEntry(r rune, layout *Layout) ([]Stroke, bool) {
+	if !enterable(r) {
+		return nil, false
+	}
+	var strokes []Stroke = []Stroke{{MODIFIER_KEY_LEFT_CTRL | MODIFIER_KEY_LEFT_SHIFT, KEYCODE_U}}
+
+	for _, digit := range strconv.FormatInt(int64(r), 16) {
+		s, ok := layout.Strokes(digit)
+		if !ok || len(s) != 1 {
+			return nil, false
+		}
+		strokes = append(strokes, s[0])
+	}
+	return append(strokes, Stroke{MODIFIER_NOT_SET, KEYCODE_SPACE}), true
+}
+
+// windowsAltCodeEntry Alt held while typing 0 and the Windows-1252 code on the keypad.
+func windowsAltCodeEntry(r rune, layout *Layout) ([]Stroke, bool) {
+	if !enterable(r) {
+		return nil, false
+	}
+	b, ok := windows1252(r)
+	if !ok {
+		return nil, false
+	}
+
+	var strokes []Stroke
+	for _, digit := range "0" + strconv.Itoa(int(b)) {
+		strokes = append(strokes, Stroke{MODIFIER_KEY_LEFT_ALT, keypadDigit(digit)})
+	}
+	return append(strokes, releaseStroke), true
+}
+
+// windowsHexEntry Alt held, keypad +, then the hex digits, numbers on the keypad and letters on the keys of the layout.
+func windowsHexEntry(r rune, layout *Layout) ([]Stroke, bool) {
+	if !enterable(r) || r > 0xFFFF {
+		return nil, false
+	}
+	var strokes []Stroke = []Stroke{{MODIFIER_KEY_LEFT_ALT, KEYCODE_KP_PLUS}}
+
+	for _, digit := range strconv.FormatInt(int64(r), 16) {
+		if digit >= '0' && digit <= '9' {
+			strokes = append(strokes, Stroke{MODIFIER_KEY_LEFT_ALT, keypadDigit(digit)})
+			continue
+		}
+		// Windows wants the key labelled with the letter, wherever the layout puts it.
+		s, ok := layout.Strokes(digit)
+		if !ok || len(s) != 1 {
+			return nil, false
+		}
+		strokes = append(strokes, Stroke{MODIFIER_KEY_LEFT_ALT, s[0].Keycode})
+	}
+	return append(strokes, releaseStroke), true
+}
+
+// macEntry Option held while typing the UTF-16 code units in hex. Unicode Hex Input is a layout of its own with the
+// digits and letters where US QWERTY has them, so the host's usual layout does not matter.
+func macEntry(r rune, layout *Layout) ([]Stroke, bool) {
+	if !enterable(r) {
+		return nil, false
+	}
+	var units []uint16 = []uint16{uint16(r)}
+	if r > 0xFFFF {
+		hi, lo := utf16.EncodeRune(r)
+		units = []uint16{uint16(hi), uint16(lo)}
+	}
+
+	var strokes []Stroke
+	for _, unit := range units {
+		for _, digit := range fmt.Sprintf("%04x", unit) {
+			_, keycode := ASCII_to_keycode(byte(digit))
+			strokes = append(strokes, Stroke{MODIFIER_KEY_LEFT_ALT, keycode})
+		}
+	}
+	return append(strokes, releaseStroke), true
+}
+
+// enterable control characters and invalid runes are never entered.
+func enterable(r rune) bool {
+	return r >= 0x20 && r != 0x7F && r <= 0x10FFFF && (r < 0xD800 || r > 0xDFFF)
+}
+
+func keypadDigit(digit rune) byte {
+	if digit == '0' {
+		return KEYCODE_KP_0
+	}
+	return KEYCODE_KP_1 + byte(digit-'1')
+}
+
+// windows1252 the Windows-1252 code of r, the code page Alt+0nnn types in. It matches Latin-1 except for 0x80-0x9F.
+func windows1252(r rune) (byte, bool) {
+	if r < 0x80 || (r >= 0xA0 && r <= 0xFF) {
+		return byte(r), true
+	}
+	for i, c := range windows1252High {
+		if c == r && c != 0 {
+			return byte(0x80 + i), true
+		}
+	}
+	return 0, false
+}
+
+// windows1252High the runes of Windows-1252 codes 0x80 to 0x9F, 0 where the code is unused.
+var windows1252High = [32]rune{
+	'€', 0, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0, 'Ž', 0,
+	0, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0, 'ž', 'Ÿ',
+}
@@ -0,0 +1,153 @@
+package keyboard
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnicodeEntryStrokes(t *testing.T) {
+	const (
+		alt       = MODIFIER_KEY_LEFT_ALT
+		ctrlShift = MODIFIER_KEY_LEFT_CTRL | MODIFIER_KEY_LEFT_SHIFT
+		shift     = MODIFIER_KEY_LEFT_SHIFT
+	)
+	var tests = []struct {
+		target string
+		layout string
+		r      rune
+		want   []Stroke // nil when the method can not enter r
+	}{
+		{TARGET_LINUX, "us", 'é', []Stroke{{ctrlShift, KEYCODE_U}, {0, KEYCODE_E}, {0, KEYCODE_9}, {0, KEYCODE_SPACE}}},
+		{TARGET_LINUX, "fr", 'é', []Stroke{{ctrlShift, KEYCODE_U}, {0, KEYCODE_E}, {shift, KEYCODE_9}, {0, KEYCODE_SPACE}}},
+		{TARGET_LINUX, "us", '😀', []Stroke{{ctrlShift, KEYCODE_U}, {0, KEYCODE_1}, {0, KEYCODE_F}, {0, KEYCODE_6}, {0, KEYCODE_0}, {0, KEYCODE_0}, {0, KEYCODE_SPACE}}},
+		{TARGET_LINUX, "us", '\t', nil},
+
+		{TARGET_WINDOWS, "us", 'é', []Stroke{{alt, KEYCODE_KP_0}, {alt, KEYCODE_KP_2}, {alt, KEYCODE_KP_3}, {alt, KEYCODE_KP_3}, releaseStroke}},
+		{TARGET_WINDOWS, "us", '€', []Stroke{{alt, KEYCODE_KP_0}, {alt, KEYCODE_KP_1}, {alt, KEYCODE_KP_2}, {alt, KEYCODE_KP_8}, releaseStroke}},
+		{TARGET_WINDOWS, "us", '☕', nil},
+		{TARGET_WINDOWS, "us", '😀', nil},
+
+		{TARGET_WINDOWS_HEX, "us", '☕', []Stroke{{alt, KEYCODE_KP_PLUS}, {alt, KEYCODE_KP_2}, {alt, KEYCODE_KP_6}, {alt, KEYCODE_KP_1}, {alt, KEYCODE_KP_5}, releaseStroke}},
+		{TARGET_WINDOWS_HEX, "us", 'é', []Stroke{{alt, KEYCODE_KP_PLUS}, {alt, KEYCODE_E}, {alt, KEYCODE_KP_9}, releaseStroke}},
+		{TARGET_WINDOWS_HEX, "fr", 'ª', []Stroke{{alt, KEYCODE_KP_PLUS}, {alt, KEYCODE_Q}, {alt, KEYCODE_Q}, releaseStroke}},
+		{TARGET_WINDOWS_HEX, "us", '😀', nil},
+
+		{TARGET_MACOS, "us", 'é', []Stroke{{alt, KEYCODE_0}, {alt, KEYCODE_0}, {alt, KEYCODE_E}, {alt, KEYCODE_9}, releaseStroke}},
+		{TARGET_MACOS, "de", 'z', []Stroke{{alt, KEYCODE_0}, {alt, KEYCODE_0}, {alt, KEYCODE_7}, {alt, KEYCODE_A}, releaseStroke}},
+		{TARGET_MACOS, "us", '😀', []Stroke{
+			{alt, KEYCODE_D}, {alt, KEYCODE_8}, {alt, KEYCODE_3}, {alt, KEYCODE_D},
+			{alt, KEYCODE_D}, {alt, KEYCODE_E}, {alt, KEYCODE_0}, {alt, KEYCODE_0}, releaseStroke,
+		}},
+	}
+
+	for _, test := range tests {
+		entry, err := LookupUnicodeEntry(test.target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		layout, _ := LookupLayout(test.layout)
+		got, ok := entry.Strokes(test.r, layout)
+		if ok != (test.want != nil) {
+			t.Errorf("%s %s: Strokes(%q) ok = %v, want %v", test.target, test.layout, test.r, ok, test.want != nil)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s %s: Strokes(%q) = %v, want %v", test.target, test.layout, test.r, got, test.want)
+		}
+	}
+}
+
+func TestLookupUnicodeEntry(t *testing.T) {
+	if entry, err := LookupUnicodeEntry(""); entry != nil || err != nil {
+		t.Errorf("LookupUnicodeEntry(\"\") = %v, %v, want nil, nil", entry, err)
+	}
+	if entry, err := LookupUnicodeEntry(" Windows-Hex "); entry == nil || err != nil {
+		t.Errorf("LookupUnicodeEntry(Windows-Hex) = %v, %v", entry, err)
+	}
+	if _, err := LookupUnicodeEntry("amiga"); err == nil {
+		t.Error("LookupUnicodeEntry(amiga) returned no error")
+	}
+}
+
+func TestFileStrokesUnicodeEntry(t *testing.T) {
+	var windows, _ = LookupUnicodeEntry(TARGET_WINDOWS)
+	var linux, _ = LookupUnicodeEntry(TARGET_LINUX)
+	var f = File{UnicodeEntry: windows}
+	var nilStroke = []Stroke{{MODIFIER_NOT_SET, KEYCODE_NIL}}
+
+	// Runes the layout has keys for are never entered.
+	if got, entered := f.strokes('a', Options{}); entered || !reflect.DeepEqual(got, []Stroke{{0, KEYCODE_A}}) {
+		t.Errorf("strokes('a') = %v, %v", got, entered)
+	}
+	if _, entered := f.strokes('é', Options{}); !entered {
+		t.Error("strokes('é') was not entered")
+	}
+	// The File's method can not enter ☕, the per call one can.
+	if got := f.Strokes('☕', Options{}); !reflect.DeepEqual(got, nilStroke) {
+		t.Errorf("Strokes('☕') = %v, want %v", got, nilStroke)
+	}
+	if got := f.Strokes('☕', Options{UnicodeEntry: linux}); got[0] != (Stroke{MODIFIER_KEY_LEFT_CTRL | MODIFIER_KEY_LEFT_SHIFT, KEYCODE_U}) {
+		t.Errorf("Strokes('☕') with linux entry = %v", got)
+	}
+	if got := (&File{}).Strokes('é', Options{}); !reflect.DeepEqual(got, nilStroke) {
+		t.Errorf("Strokes('é') without entry = %v, want %v", got, nilStroke)
+	}
+}
+
+func TestKeptModifiers(t *testing.T) {
+	var strokes = []Stroke{
+		{MODIFIER_KEY_LEFT_ALT, KEYCODE_KP_0},
+		{MODIFIER_KEY_LEFT_ALT | MODIFIER_KEY_LEFT_SHIFT, KEYCODE_KP_2},
+		{MODIFIER_KEY_LEFT_SHIFT, KEYCODE_KP_3},
+		releaseStroke,
+	}
+	var want = []byte{MODIFIER_KEY_LEFT_ALT, MODIFIER_KEY_LEFT_SHIFT, MODIFIER_NOT_SET, MODIFIER_NOT_SET}
+
+	for i := range strokes {
+		if got := keptModifiers(strokes, i); got != want[i] {
+			t.Errorf("keptModifiers(%d) = %08b, want %08b", i, got, want[i])
+		}
+	}
+}
+
+// An Alt code is typed with Alt held from the first digit to the last, with or without OptimizeReports.
+func TestAltHeldAcrossDigits(t *testing.T) {
+	var windows, _ = LookupUnicodeEntry(TARGET_WINDOWS)
+	var strokes, _ = windows.Strokes('é', layouts[DEFAULT_LAYOUT])
+
+	planned, err := PlanReports(strokes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, reports := range [][]Report{planned, altCodeReports(t, strokes)} {
+		var lastKey int
+		for i, r := range reports {
+			if r[2] != KEYCODE_NIL {
+				lastKey = i
+			}
+		}
+		for i, r := range reports {
+			if i <= lastKey && r[0] != MODIFIER_KEY_LEFT_ALT {
+				t.Errorf("report %d of %v lets go of Alt before the last digit", i, reports)
+			}
+			if i > lastKey && r != (Report{}) {
+				t.Errorf("report %d of %v does not release everything after the last digit", i, reports)
+			}
+		}
+	}
+}
+
+// altCodeReports the reports writeText sends for strokes when OptimizeReports is off.
+func altCodeReports(t *testing.T, strokes []Stroke) []Report {
+	var f File
+	var reports []Report
+
+	for i, s := range strokes {
+		r, err := f.pressReport(s.Modifier, s.Keycode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reports = append(reports, r, f.releaseReportKeeping(keptModifiers(strokes, i)))
+	}
+	return reports
+}
@@ -0,0 +1,95 @@
+// Package macro records key presses and releases with their timing and plays them back on a keyboard.File.
+package macro
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/scirelli/turkey-pi/pkg/keyboard"
+)
+
+type EventType string
+
+const (
+	Press   EventType = "press"
+	Release EventType = "release"
+)
+
+// Event a key going down or up, Delay is the time since the previous event.
+type Event struct {
+	Delay Duration  `json:"delay"`
+	Type  EventType `json:"type"`
+	Key   Key       `json:"key"`
+}
+
+// Macro a named recording.
+type Macro struct {
+	Name   string  `json:"name"`
+	Events []Event `json:"events"`
+}
+
+// Length total run time of the macro at normal speed.
+func (m *Macro) Length() time.Duration {
+	var total time.Duration
+	for _, e := range m.Events {
+		total += time.Duration(e.Delay)
+	}
+	return total
+}
+
+// Validate checks every event has a known type.
+func (m *Macro) Validate() error {
+	if m.Name == "" {
+		return fmt.Errorf("Macro has no name")
+	}
+	for i, e := range m.Events {
+		if e.Type != Press && e.Type != Release {
+			return fmt.Errorf("Macro '%s' event %d has unknown type '%s'", m.Name, i, e.Type)
+		}
+		if e.Delay < 0 {
+			return fmt.Errorf("Macro '%s' event %d has a negative delay", m.Name, i)
+		}
+	}
+	return nil
+}
+
+// Duration a time.Duration written in JSON as a string such as "120ms" so files stay readable.
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Key a keycode written in JSON by its name, see keyboard.KeyByName, so files do not depend on keycode values.
+type Key byte
+
+func (k Key) MarshalJSON() ([]byte, error) {
+	return json.Marshal(keyboard.KeyName(byte(k)))
+}
+
+func (k *Key) UnmarshalJSON(b []byte) error {
+	var name string
+	if err := json.Unmarshal(b, &name); err != nil {
+		return err
+	}
+	key, ok := keyboard.KeyByName(name)
+	if !ok {
+		return fmt.Errorf("Unknown key '%s'", name)
+	}
+	*k = Key(key)
+	return nil
+}
@@ -0,0 +1,239 @@
+package macro
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scirelli/turkey-pi/pkg/keyboard"
+	"github.com/scirelli/turkey-pi/pkg/keyboard/hidtest"
+)
+
+// slack how late a timed event may be on a loaded machine.
+const slack = 50 * time.Millisecond
+
+func TestRecorder(t *testing.T) {
+	var r Recorder
+
+	r.Record(Press, keyboard.KEYCODE_A)
+	if _, err := r.Stop(); err == nil {
+		t.Error("Stop without a recording succeeded")
+	}
+	if err := r.Start("greet"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Start("other"); err == nil || r.Recording() != "greet" {
+		t.Errorf("second Start returned %v while recording '%s'", err, r.Recording())
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	r.Record(Press, keyboard.KEYCODE_LEFT_SHIFT)
+	r.Record(Press, keyboard.KEYCODE_H)
+	time.Sleep(30 * time.Millisecond)
+	r.Record(Release, keyboard.KEYCODE_H)
+	r.Record(Release, keyboard.KEYCODE_LEFT_SHIFT)
+
+	m, err := r.Stop()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Recording() != "" {
+		t.Errorf("still recording '%s' after Stop", r.Recording())
+	}
+	if m.Name != "greet" || len(m.Events) != 4 {
+		t.Fatalf("recorded %+v", m)
+	}
+	for i, want := range []struct {
+		t   EventType
+		key byte
+		min time.Duration
+	}{
+		{Press, keyboard.KEYCODE_LEFT_SHIFT, 20 * time.Millisecond},
+		{Press, keyboard.KEYCODE_H, 0},
+		{Release, keyboard.KEYCODE_H, 30 * time.Millisecond},
+		{Release, keyboard.KEYCODE_LEFT_SHIFT, 0},
+	} {
+		e := m.Events[i]
+		if e.Type != want.t || byte(e.Key) != want.key {
+			t.Errorf("event %d is %s %s, want %s %s", i, e.Type, keyboard.KeyName(byte(e.Key)), want.t, keyboard.KeyName(want.key))
+		}
+		if time.Duration(e.Delay) < want.min || time.Duration(e.Delay) > want.min+slack {
+			t.Errorf("event %d came %s after the one before, want about %s", i, time.Duration(e.Delay), want.min)
+		}
+	}
+}
+
+// pressedAt when each report that pressed a key was written, as an offset from start.
+func pressedAt(dev *hidtest.Device, start time.Time) []time.Duration {
+	var at []time.Duration
+	var prev keyboard.Report
+
+	for _, r := range dev.Reports() {
+		if r.Data[2] != keyboard.KEYCODE_NIL && r.Data != prev {
+			at = append(at, r.Time.Sub(start))
+		}
+		prev = r.Data
+	}
+	return at
+}
+
+func TestPlayTiming(t *testing.T) {
+	var m = &Macro{Name: "ab", Events: []Event{
+		{Delay: Duration(20 * time.Millisecond), Type: Press, Key: Key(keyboard.KEYCODE_A)},
+		{Delay: 0, Type: Release, Key: Key(keyboard.KEYCODE_A)},
+		{Delay: Duration(40 * time.Millisecond), Type: Press, Key: Key(keyboard.KEYCODE_B)},
+		{Delay: Duration(10 * time.Millisecond), Type: Release, Key: Key(keyboard.KEYCODE_B)},
+	}}
+
+	for _, test := range []struct {
+		speed float64
+		want  []time.Duration
+	}{
+		{1, []time.Duration{20 * time.Millisecond, 60 * time.Millisecond}},
+		{2, []time.Duration{10 * time.Millisecond, 30 * time.Millisecond}},
+		{0.5, []time.Duration{40 * time.Millisecond, 120 * time.Millisecond}},
+	} {
+		dev := hidtest.NewDevice()
+		kb := keyboard.File{Device: dev}
+		start := time.Now()
+		if err := Play(&kb, m, test.speed); err != nil {
+			t.Fatal(err)
+		}
+
+		at := pressedAt(dev, start)
+		if len(at) != len(test.want) {
+			t.Fatalf("speed %v: %d presses, want %d", test.speed, len(at), len(test.want))
+		}
+		for i := range at {
+			if at[i] < test.want[i] || at[i] > test.want[i]+slack {
+				t.Errorf("speed %v: press %d at %s, want %s", test.speed, i, at[i], test.want[i])
+			}
+		}
+		if err := hidtest.Decode(nil, dev.Reports()).Check("ab"); err != nil {
+			t.Errorf("speed %v: %v", test.speed, err)
+		}
+	}
+
+	if err := Play(&keyboard.File{Device: hidtest.NewDevice()}, m, 0); err == nil {
+		t.Error("Play at speed 0 succeeded")
+	}
+}
+
+// Keys a finished macro leaves down stay held, like when it was recorded.
+func TestPlayLeavesKeysHeld(t *testing.T) {
+	var m = &Macro{Name: "shift", Events: []Event{
+		{Type: Press, Key: Key(keyboard.KEYCODE_LEFT_SHIFT)},
+		{Type: Press, Key: Key(keyboard.KEYCODE_A)},
+		{Type: Release, Key: Key(keyboard.KEYCODE_A)},
+	}}
+	kb := keyboard.File{Device: hidtest.NewDevice()}
+
+	if err := Play(&kb, m, 1); err != nil {
+		t.Fatal(err)
+	}
+	if modifiers, keys := kb.Held(); modifiers != keyboard.MODIFIER_KEY_LEFT_SHIFT || len(keys) != 0 {
+		t.Errorf("held %08b %v after the macro, want left shift", modifiers, keys)
+	}
+}
+
+// A cancelled macro lets go of what it still holds, not of keys it already released or that were held before it.
+func TestPlayContextCancelReleases(t *testing.T) {
+	var m = &Macro{Name: "hold", Events: []Event{
+		{Type: Press, Key: Key(keyboard.KEYCODE_LEFT_CONTROL)},
+		{Type: Press, Key: Key(keyboard.KEYCODE_A)},
+		{Type: Press, Key: Key(keyboard.KEYCODE_B)},
+		{Type: Release, Key: Key(keyboard.KEYCODE_B)},
+		{Delay: Duration(10 * time.Second), Type: Release, Key: Key(keyboard.KEYCODE_A)},
+	}}
+	kb := keyboard.File{Device: hidtest.NewDevice()}
+	if err := kb.Press(keyboard.KEYCODE_LEFT_CONTROL); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := PlayContext(ctx, &kb, m, 1); err != context.DeadlineExceeded {
+		t.Errorf("PlayContext returned %v, want context.DeadlineExceeded", err)
+	}
+	if modifiers, keys := kb.Held(); modifiers != keyboard.MODIFIER_KEY_LEFT_CTRL || len(keys) != 0 {
+		t.Errorf("held %08b %v after cancelling, want only the left ctrl held before the macro", modifiers, keys)
+	}
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	var fileName = filepath.Join(t.TempDir(), "macros.json")
+	var menu = &Macro{Name: "menu", Events: []Event{
+		{Delay: Duration(120 * time.Millisecond), Type: Press, Key: Key(keyboard.KEYCODE_ARROW_DOWN)},
+		{Delay: Duration(80 * time.Millisecond), Type: Release, Key: Key(keyboard.KEYCODE_ARROW_DOWN)},
+		{Delay: Duration(1500 * time.Millisecond), Type: Press, Key: Key(keyboard.KEYCODE_ENTER)},
+		{Delay: 0, Type: Release, Key: Key(keyboard.KEYCODE_ENTER)},
+	}}
+	var empty = &Macro{Name: "empty", Events: []Event{}}
+
+	store, err := Load(fileName)
+	if err != nil || len(store.Names()) != 0 {
+		t.Fatalf("a missing file loaded %v, %v", store.Names(), err)
+	}
+	for _, m := range []*Macro{menu, empty} {
+		if err := store.Save(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"delay": "120ms"`, `"delay": "1.5s"`, `"key": "ENTER"`, `"type": "release"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("the file has no %s:\n%s", want, data)
+		}
+	}
+
+	loaded, err := Load(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := loaded.Names(); !reflect.DeepEqual(names, []string{"empty", "menu"}) {
+		t.Errorf("names %v", names)
+	}
+	if m, ok := loaded.Get("menu"); !ok || !reflect.DeepEqual(m, menu) {
+		t.Errorf("loaded %+v, want %+v", m, menu)
+	}
+	if got := menu.Length(); got != 1700*time.Millisecond {
+		t.Errorf("length %s, want 1.7s", got)
+	}
+
+	if ok, err := loaded.Delete("menu"); !ok || err != nil {
+		t.Fatalf("Delete: %v, %v", ok, err)
+	}
+	if ok, _ := loaded.Delete("menu"); ok {
+		t.Error("deleted menu twice")
+	}
+	if reloaded, err := Load(fileName); err != nil || !reflect.DeepEqual(reloaded.Names(), []string{"empty"}) {
+		t.Errorf("after Delete the file has %v, %v", reloaded.Names(), err)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	var dir = t.TempDir()
+
+	for name, body := range map[string]string{
+		"unknown key":    `[{"name": "m", "events": [{"delay": "1ms", "type": "press", "key": "NOSUCHKEY"}]}]`,
+		"bad duration":   `[{"name": "m", "events": [{"delay": "soon", "type": "press", "key": "A"}]}]`,
+		"unknown type":   `[{"name": "m", "events": [{"delay": "1ms", "type": "tap", "key": "A"}]}]`,
+		"negative delay": `[{"name": "m", "events": [{"delay": "-1ms", "type": "press", "key": "A"}]}]`,
+		"no name":        `[{"events": []}]`,
+	} {
+		fileName := filepath.Join(dir, strings.Replace(name, " ", "-", -1)+".json")
+		if err := ioutil.WriteFile(fileName, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(fileName); err == nil {
+			t.Errorf("%s loaded", name)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package macro
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/scirelli/turkey-pi/pkg/keyboard"
+)
+
+// Play replays m on kb. speed scales playback, 2 is twice as fast and 0.5 half as fast.
+// Keys the macro leaves down stay held, the same as when it was recorded.
+func Play(kb *keyboard.File, m *Macro, speed float64) error {
+	return PlayContext(context.Background(), kb, m, speed)
+}
+
+// PlayContext replays m like Play but stops once ctx is done. When it stops early, or an event fails, the keys the
+// macro pressed and had not released yet are let go. Keys that were already held before it pressed them stay down.
+func PlayContext(ctx context.Context, kb *keyboard.File, m *Macro, speed float64) error {
+	if speed <= 0 {
+		return fmt.Errorf("Speed must be greater than 0, got %v", speed)
+	}
+
+	var start = time.Now()
+	var at time.Duration
+	var down []byte // keys the macro pressed that are still down
+	for _, e := range m.Events {
+		// Sleep until the event's offset from the start rather than for each delay, so write time does not add up.
+		at += time.Duration(float64(e.Delay) / speed)
+		timer := time.NewTimer(time.Until(start.Add(at)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			release(kb, down)
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		var err error
+		var key byte = byte(e.Key)
+		switch e.Type {
+		case Press:
+			if !kb.IsHeld(key) {
+				err = kb.Press(key)
+				down = append(down, key)
+			}
+		case Release:
+			err = kb.Release(key)
+			down = without(down, key)
+		}
+		if err != nil {
+			release(kb, down)
+			return err
+		}
+	}
+	return nil
+}
+
+// release lets go of keys, best effort since playback already failed or was cancelled.
+func release(kb *keyboard.File, keys []byte) {
+	for _, key := range keys {
+		kb.Release(key)
+	}
+}
+
+func without(keys []byte, key byte) []byte {
+	var rest []byte = keys[:0]
+
+	for _, k := range keys {
+		if k != key {
+			rest = append(rest, k)
+		}
+	}
+	return rest
+}
@@ -0,0 +1,65 @@
+package macro
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Recorder collects events into a macro. Only one recording can be in progress at a time.
+type Recorder struct {
+	mu        sync.Mutex
+	recording *Macro
+	last      time.Time
+}
+
+// Start begins recording a macro called name.
+func (r *Recorder) Start(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.recording != nil {
+		return fmt.Errorf("Already recording macro '%s'", r.recording.Name)
+	}
+	r.recording = &Macro{Name: name, Events: []Event{}}
+	r.last = time.Now()
+	return nil
+}
+
+// Record adds an event if a recording is in progress. The delay is measured from the previous event, or from Start for
+// the first one.
+func (r *Recorder) Record(t EventType, key byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.recording == nil {
+		return
+	}
+	now := time.Now()
+	r.recording.Events = append(r.recording.Events, Event{Delay: Duration(now.Sub(r.last)), Type: t, Key: Key(key)})
+	r.last = now
+}
+
+// Recording the name of the macro being recorded, empty if none.
+func (r *Recorder) Recording() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.recording == nil {
+		return ""
+	}
+	return r.recording.Name
+}
+
+// Stop ends the recording and returns the macro.
+func (r *Recorder) Stop() (*Macro, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.recording == nil {
+		return nil, fmt.Errorf("Not recording")
+	}
+	m := r.recording
+	r.recording = nil
+	return m, nil
+}
@@ -0,0 +1,109 @@
+package macro
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Store macros saved by name in a JSON file. Every change is written back to the file.
+type Store struct {
+	mu       sync.Mutex
+	fileName string
+	macros   map[string]*Macro
+}
+
+// Load reads the macros in fileName. A missing file is an empty store, it is created on the first Save.
+func Load(fileName string) (*Store, error) {
+	var store = Store{fileName: fileName, macros: map[string]*Macro{}}
+	var macros []*Macro
+
+	byteValue, err := ioutil.ReadFile(fileName)
+	if os.IsNotExist(err) {
+		return &store, nil
+	} else if err != nil {
+		return &store, err
+	}
+	if err = json.Unmarshal(byteValue, &macros); err != nil {
+		return &store, fmt.Errorf("Failed to read macros from '%s': %w", fileName, err)
+	}
+	for _, m := range macros {
+		if err := m.Validate(); err != nil {
+			return &store, err
+		}
+		store.macros[m.Name] = m
+	}
+
+	return &store, nil
+}
+
+// Get a macro by name.
+func (s *Store) Get(name string) (*Macro, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, ok := s.macros[name]
+	return m, ok
+}
+
+// Names of all saved macros, sorted.
+func (s *Store) Names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var names []string = make([]string, 0, len(s.macros))
+	for name := range s.macros {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Save adds or replaces a macro and writes the file.
+func (s *Store) Save(m *Macro) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.macros[m.Name] = m
+	return s.write()
+}
+
+// Delete removes a macro and writes the file. ok is false if there was no such macro.
+func (s *Store) Delete(name string) (ok bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok = s.macros[name]; !ok {
+		return false, nil
+	}
+	delete(s.macros, name)
+	return true, s.write()
+}
+
+// write saves every macro to the file, sorted by name so the file diffs well. s.mu must be held.
+func (s *Store) write() error {
+	var macros []*Macro = make([]*Macro, 0, len(s.macros))
+
+	for _, m := range s.macros {
+		macros = append(macros, m)
+	}
+	sort.Slice(macros, func(i, j int) bool { return macros[i].Name < macros[j].Name })
+
+	byteValue, err := json.MarshalIndent(macros, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write to a temp file first so a crash can not leave a half written file behind.
+	tmp := s.fileName + ".tmp"
+	if err = ioutil.WriteFile(tmp, byteValue, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.fileName)
+}
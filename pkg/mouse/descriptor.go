@@ -0,0 +1,37 @@
+package mouse
+
+// ReportDescriptor the HID report descriptor for Report, written to report_desc of a second hid function with
+// protocol 2 (mouse), subclass 1 (boot) and report_length 4. See init/enable-rpi-hid.
+var ReportDescriptor = []byte{
+	0x05, 0x01, // Usage Page (Generic Desktop Ctrls)
+	0x09, 0x02, // Usage (Mouse)
+	0xA1, 0x01, // Collection (Application)
+	0x09, 0x01, //   Usage (Pointer)
+	0xA1, 0x00, //   Collection (Physical)
+	//              -- Buttons --
+	0x05, 0x09, //     Usage Page (Button)
+	0x19, 0x01, //     Usage Minimum (0x01)
+	0x29, 0x05, //     Usage Maximum (0x05)
+	0x15, 0x00, //     Logical Minimum (0)
+	0x25, 0x01, //     Logical Maximum (1)
+	0x95, 0x05, //     Report Count (5)
+	0x75, 0x01, //     Report Size (1)
+	0x81, 0x02, //     Input (Data,Var,Abs)
+	//              -- Padding --
+	0x95, 0x01, //     Report Count (1)
+	0x75, 0x03, //     Report Size (3)
+	0x81, 0x03, //     Input (Const,Var,Abs)
+	//              -- X, Y and wheel --
+	0x05, 0x01, //     Usage Page (Generic Desktop Ctrls)
+	0x09, 0x30, //     Usage (X)
+	0x09, 0x31, //     Usage (Y)
+	0x09, 0x38, //     Usage (Wheel)
+	0x15, 0x81, //     Logical Minimum (-127)
+	0x25, 0x7F, //     Logical Maximum (127)
+	0x75, 0x08, //     Report Size (8)
+	0x95, 0x03, //     Report Count (3)
+	0x81, 0x06, //     Input (Data,Var,Rel)
+	0xC0, //         End Collection
+	0xC0, //       End Collection
+	// 52 bytes
+}
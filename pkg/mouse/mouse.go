@@ -0,0 +1,212 @@
+// Package mouse emulates a relative USB HID mouse on a gadget device file such as /dev/hidg1.
+package mouse
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+Mouse input report, 4 bytes.
+
+	Byte 0   buttons, bit 0 left, 1 right, 2 middle, 3 back, 4 forward, bits 5-7 padding
+	Byte 1   X movement, -127 to 127, positive is right
+	Byte 2   Y movement, -127 to 127, positive is down
+	Byte 3   wheel, -127 to 127, positive scrolls up
+*/
+const ReportSz int = 4
+
+type Report [ReportSz]byte
+
+// MAX_STEP the furthest a single report can move the pointer or wheel.
+const MAX_STEP int = 127
+
+type Button byte
+
+const (
+	BUTTON_NONE    Button = 0
+	BUTTON_LEFT    Button = 0b0000_0001
+	BUTTON_RIGHT   Button = 0b0000_0010
+	BUTTON_MIDDLE  Button = 0b0000_0100
+	BUTTON_BACK    Button = 0b0000_1000
+	BUTTON_FORWARD Button = 0b0001_0000
+)
+
+var buttonNames = map[string]Button{
+	"left":    BUTTON_LEFT,
+	"right":   BUTTON_RIGHT,
+	"middle":  BUTTON_MIDDLE,
+	"back":    BUTTON_BACK,
+	"forward": BUTTON_FORWARD,
+}
+
+// ButtonByName find a button by name, one of left, right, middle, back or forward. Names are case insensitive.
+func ButtonByName(name string) (Button, error) {
+	if b, ok := buttonNames[strings.ToLower(strings.TrimSpace(name))]; ok {
+		return b, nil
+	}
+	return BUTTON_NONE, fmt.Errorf("Unknown mouse button '%s', expected one of: left, right, middle, back, forward", name)
+}
+
+// NewReport a report with buttons held that moves the pointer by dx, dy and the wheel by wheel, each within ±MAX_STEP.
+func NewReport(buttons Button, dx, dy, wheel int) Report {
+	return Report{byte(buttons), byte(int8(clamp(dx))), byte(int8(clamp(dy))), byte(int8(clamp(wheel)))}
+}
+
+func clamp(v int) int {
+	if v > MAX_STEP {
+		return MAX_STEP
+	}
+	if v < -MAX_STEP {
+		return -MAX_STEP
+	}
+	return v
+}
+
+// Device where a File writes its reports. In use it is an *os.File opened on /dev/hidg<#>.
+type Device interface {
+	io.WriteCloser
+}
+
+// File represents the mouse device file in user space /dev/hidg<#>
+type File struct {
+	Device
+	StepDelay time.Duration //StepDelay wait after each report, long moves and clicks are sent as several.
+
+	mu      sync.Mutex
+	buttons Button
+}
+
+// Buttons the buttons held down.
+func (f *File) Buttons() Button {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.buttons
+}
+
+// Move moves the pointer by dx, dy. Moves further than MAX_STEP are split into even steps so the path stays straight.
+func (f *File) Move(dx, dy int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.move(dx, dy, 0)
+}
+
+// Scroll turns the wheel by clicks, positive scrolls up.
+func (f *File) Scroll(clicks int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.move(0, 0, clicks)
+}
+
+// Press holds buttons down until they are released.
+func (f *File) Press(buttons Button) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.setButtons(f.buttons | buttons)
+}
+
+// Release lets go of buttons.
+func (f *File) Release(buttons Button) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.setButtons(f.buttons &^ buttons)
+}
+
+// ReleaseAll lets go of every button.
+func (f *File) ReleaseAll() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.setButtons(BUTTON_NONE)
+}
+
+// Click presses and releases buttons count times, count 2 is a double click.
+func (f *File) Click(buttons Button, count int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	held := f.buttons
+	for i := 0; i < count; i++ {
+		if err := f.setButtons(held | buttons); err != nil {
+			return err
+		}
+		if err := f.setButtons(held &^ buttons); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Drag holds buttons down, moves by dx, dy and releases them.
+func (f *File) Drag(buttons Button, dx, dy int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	held := f.buttons
+	if err := f.setButtons(held | buttons); err != nil {
+		return err
+	}
+	if err := f.move(dx, dy, 0); err != nil {
+		f.setButtons(held)
+		return err
+	}
+	return f.setButtons(held &^ buttons)
+}
+
+// move sends as many reports as needed to move by dx, dy, wheel with the held buttons, f.mu must be held.
+func (f *File) move(dx, dy, wheel int) error {
+	var steps int = (max(abs(dx), abs(dy), abs(wheel)) + MAX_STEP - 1) / MAX_STEP
+	var sentX, sentY, sentWheel int
+
+	for i := 1; i <= steps; i++ {
+		// Each step goes to where the line should be after i steps, so rounding does not add up.
+		x, y, w := dx*i/steps, dy*i/steps, wheel*i/steps
+		if err := f.write(NewReport(f.buttons, x-sentX, y-sentY, w-sentWheel)); err != nil {
+			return err
+		}
+		sentX, sentY, sentWheel = x, y, w
+	}
+	return nil
+}
+
+// setButtons sends a report with buttons held and no movement, f.mu must be held.
+func (f *File) setButtons(buttons Button) error {
+	if err := f.write(NewReport(buttons, 0, 0, 0)); err != nil {
+		return err
+	}
+	f.buttons = buttons
+	return nil
+}
+
+func (f *File) write(r Report) error {
+	if _, err := f.Device.Write(r[:]); err != nil {
+		return err
+	}
+	time.Sleep(f.StepDelay)
+	return nil
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func max(values ...int) int {
+	var m int
+	for _, v := range values {
+		if v > m {
+			m = v
+		}
+	}
+	return m
+}
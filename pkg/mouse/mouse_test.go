@@ -0,0 +1,167 @@
+package mouse
+
+import (
+	"errors"
+	"testing"
+)
+
+// reportRecorder a Device that keeps every report written to it.
+type reportRecorder struct {
+	reports []Report
+	err     error
+}
+
+func (d *reportRecorder) Write(p []byte) (int, error) {
+	if d.err != nil {
+		return 0, d.err
+	}
+	var r Report
+	copy(r[:], p)
+	d.reports = append(d.reports, r)
+	return len(p), nil
+}
+
+func (d *reportRecorder) Close() error {
+	return nil
+}
+
+func TestNewReport(t *testing.T) {
+	var tests = []struct {
+		buttons       Button
+		dx, dy, wheel int
+		want          Report
+	}{
+		{BUTTON_NONE, 0, 0, 0, Report{0, 0, 0, 0}},
+		{BUTTON_LEFT | BUTTON_FORWARD, 1, -1, 0, Report{0x11, 0x01, 0xff, 0}},
+		{BUTTON_RIGHT, 127, -127, 3, Report{0x02, 0x7f, 0x81, 0x03}},
+		{BUTTON_MIDDLE, 500, -500, -128, Report{0x04, 0x7f, 0x81, 0x81}},
+	}
+
+	for _, test := range tests {
+		if got := NewReport(test.buttons, test.dx, test.dy, test.wheel); got != test.want {
+			t.Errorf("NewReport(%05b, %d, %d, %d) = % x, want % x", test.buttons, test.dx, test.dy, test.wheel, got, test.want)
+		}
+	}
+}
+
+// Long moves are split into steps of at most MAX_STEP that add up to the whole move and keep to a straight line.
+func TestMoveSteps(t *testing.T) {
+	var tests = []struct {
+		dx, dy int
+		steps  int
+	}{
+		{0, 0, 0},
+		{10, -5, 1},
+		{127, 127, 1},
+		{128, 0, 2},
+		{-300, 100, 3},
+		{1000, 999, 8},
+		{5, -1270, 10},
+	}
+
+	for _, test := range tests {
+		var dev reportRecorder
+		var f = File{Device: &dev}
+		if err := f.Move(test.dx, test.dy); err != nil {
+			t.Fatal(err)
+		}
+		if len(dev.reports) != test.steps {
+			t.Errorf("Move(%d, %d) sent %d reports, want %d", test.dx, test.dy, len(dev.reports), test.steps)
+		}
+
+		var x, y int
+		for i, r := range dev.reports {
+			stepX, stepY := int(int8(r[1])), int(int8(r[2]))
+			x, y = x+stepX, y+stepY
+			// Each step ends within a count of the straight line from the start.
+			if lineX := test.dx * (i + 1) / test.steps; abs(x-lineX) > 1 {
+				t.Errorf("Move(%d, %d) step %d is at x %d, the line is at %d", test.dx, test.dy, i, x, lineX)
+			}
+			if r[0] != 0 || r[3] != 0 {
+				t.Errorf("Move(%d, %d) step %d is % x, want no buttons or wheel", test.dx, test.dy, i, r)
+			}
+		}
+		if x != test.dx || y != test.dy {
+			t.Errorf("Move(%d, %d) moved %d, %d", test.dx, test.dy, x, y)
+		}
+	}
+}
+
+func TestScrollSteps(t *testing.T) {
+	var dev reportRecorder
+	var f = File{Device: &dev}
+
+	if err := f.Scroll(-200); err != nil {
+		t.Fatal(err)
+	}
+	var wheel int
+	for _, r := range dev.reports {
+		wheel += int(int8(r[3]))
+	}
+	if len(dev.reports) != 2 || wheel != -200 {
+		t.Errorf("Scroll(-200) sent %d reports turning %d", len(dev.reports), wheel)
+	}
+}
+
+func TestButtons(t *testing.T) {
+	var dev reportRecorder
+	var f = File{Device: &dev}
+
+	if err := f.Press(BUTTON_RIGHT); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Click(BUTTON_LEFT, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Drag(BUTTON_LEFT, 200, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Release(BUTTON_RIGHT); err != nil {
+		t.Fatal(err)
+	}
+
+	var want = []Report{
+		{0x02, 0, 0, 0},
+		// The double click keeps the right button held.
+		{0x03, 0, 0, 0}, {0x02, 0, 0, 0},
+		{0x03, 0, 0, 0}, {0x02, 0, 0, 0},
+		// The drag moves in two steps with both buttons down.
+		{0x03, 0, 0, 0}, {0x03, 100, 0, 0}, {0x03, 100, 0, 0}, {0x02, 0, 0, 0},
+		{0x00, 0, 0, 0},
+	}
+	if len(dev.reports) != len(want) {
+		t.Fatalf("sent % x, want % x", dev.reports, want)
+	}
+	for i := range want {
+		if dev.reports[i] != want[i] {
+			t.Errorf("report %d is % x, want % x", i, dev.reports[i], want[i])
+		}
+	}
+	if f.Buttons() != BUTTON_NONE {
+		t.Errorf("%05b held at the end", f.Buttons())
+	}
+}
+
+// A failed write leaves the held buttons as they were.
+func TestPressFails(t *testing.T) {
+	var dev = reportRecorder{err: errors.New("host went away")}
+	var f = File{Device: &dev}
+
+	if err := f.Press(BUTTON_LEFT); err == nil {
+		t.Fatal("Press on a failing device succeeded")
+	}
+	if f.Buttons() != BUTTON_NONE {
+		t.Errorf("%05b held after a failed press", f.Buttons())
+	}
+}
+
+func TestButtonByName(t *testing.T) {
+	for name, want := range map[string]Button{"left": BUTTON_LEFT, " Right ": BUTTON_RIGHT, "MIDDLE": BUTTON_MIDDLE, "forward": BUTTON_FORWARD} {
+		if b, err := ButtonByName(name); err != nil || b != want {
+			t.Errorf("ButtonByName(%q) = %05b, %v, want %05b", name, b, err, want)
+		}
+	}
+	if _, err := ButtonByName("fourth"); err == nil {
+		t.Error("ButtonByName found a fourth button")
+	}
+}
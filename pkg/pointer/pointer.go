@@ -0,0 +1,272 @@
+// Package pointer emulates an absolute USB HID pointer on a gadget device file such as /dev/hidg4. The host puts the
+// pointer exactly where it is told, without the acceleration that makes relative mouse movement miss.
+//
+// It reports as an absolute mouse rather than a touchscreen. Every desktop OS moves the cursor for one, while
+// touchscreens are handled as gestures and need a different descriptor for each OS.
+package pointer
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/scirelli/turkey-pi/pkg/mouse"
+)
+
+/*
+Pointer input report, 6 bytes.
+
+	Byte 0     buttons, the same bits as the mouse package
+	Byte 1-2   X, 0 to MAX_COORD, little endian, 0 is the left edge
+	Byte 3-4   Y, 0 to MAX_COORD, 0 is the top edge
+	Byte 5     wheel, -127 to 127, relative, positive scrolls up
+*/
+const ReportSz int = 6
+
+type Report [ReportSz]byte
+
+// MAX_COORD the logical maximum of X and Y, the host scales it to the right and bottom edges of the screen.
+const MAX_COORD int = 32767
+
+// NewReport a report with buttons held, the pointer at x, y and the wheel turned by wheel.
+func NewReport(buttons mouse.Button, x, y, wheel int) Report {
+	x, y, wheel = clamp(x, 0, MAX_COORD), clamp(y, 0, MAX_COORD), clamp(wheel, -mouse.MAX_STEP, mouse.MAX_STEP)
+	return Report{byte(buttons), byte(x), byte(x >> 8), byte(y), byte(y >> 8), byte(int8(wheel))}
+}
+
+func clamp(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
+
+// Point a position on the screen, normalized from 0 to 1 where 0, 0 is the top left corner.
+type Point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+/*
+Calibration maps screen pixels to the pointer's coordinates.
+
+	Width and Height are the screen's size in pixels, they are only needed to point at pixels. Left, Top, Right and
+	Bottom are the part of the pointer's range the screen covers, normalized. They are 0, 0, 1, 1 for a single screen
+	and need changing when the host spreads the range across several monitors or letterboxes the picture. All zero
+	is the same as 0, 0, 1, 1.
+*/
+type Calibration struct {
+	Width  int     `json:"width"`
+	Height int     `json:"height"`
+	Left   float64 `json:"left"`
+	Top    float64 `json:"top"`
+	Right  float64 `json:"right"`
+	Bottom float64 `json:"bottom"`
+}
+
+// Validate the calibration is usable, pixels can only be converted when Width and Height are set.
+func (c Calibration) Validate() error {
+	if c.Width < 0 || c.Height < 0 {
+		return fmt.Errorf("Screen size %dx%d can not be negative", c.Width, c.Height)
+	}
+	if c.Left == 0 && c.Top == 0 && c.Right == 0 && c.Bottom == 0 {
+		return nil
+	}
+	for _, v := range []float64{c.Left, c.Top, c.Right, c.Bottom} {
+		if v < 0 || v > 1 {
+			return fmt.Errorf("Screen bounds must be between 0 and 1, got left %g, top %g, right %g, bottom %g", c.Left, c.Top, c.Right, c.Bottom)
+		}
+	}
+	if c.Left >= c.Right || c.Top >= c.Bottom {
+		return fmt.Errorf("Screen bounds left %g, top %g, right %g, bottom %g are empty", c.Left, c.Top, c.Right, c.Bottom)
+	}
+	return nil
+}
+
+// area the bounds with the all zero default filled in.
+func (c Calibration) area() (left, top, right, bottom float64) {
+	if c.Right == 0 && c.Bottom == 0 {
+		return 0, 0, 1, 1
+	}
+	return c.Left, c.Top, c.Right, c.Bottom
+}
+
+// Pixel the point at pixel x, y. The last pixel of a row or column is at the edge.
+func (c Calibration) Pixel(x, y int) (Point, error) {
+	if c.Width <= 0 || c.Height <= 0 {
+		return Point{}, fmt.Errorf("Pixel coordinates need the screen size, calibrate the pointer with a width and height")
+	}
+	if x < 0 || x >= c.Width || y < 0 || y >= c.Height {
+		return Point{}, fmt.Errorf("Pixel %d, %d is off the %dx%d screen", x, y, c.Width, c.Height)
+	}
+	return Point{X: edge(x, c.Width), Y: edge(y, c.Height)}, nil
+}
+
+func edge(v, size int) float64 {
+	if size == 1 {
+		return 0
+	}
+	return float64(v) / float64(size-1)
+}
+
+// coords the pointer's coordinates for p, which is clamped to the screen.
+func (c Calibration) coords(p Point) (x, y int) {
+	left, top, right, bottom := c.area()
+	scale := func(v, min, max float64) int {
+		v = math.Max(0, math.Min(1, v))
+		return int(math.Round((min + v*(max-min)) * float64(MAX_COORD)))
+	}
+	return scale(p.X, left, right), scale(p.Y, top, bottom)
+}
+
+// Device where a File writes its reports. In use it is an *os.File opened on /dev/hidg<#>.
+type Device interface {
+	io.WriteCloser
+}
+
+// File represents the pointer device file in user space /dev/hidg<#>
+type File struct {
+	Device
+	TapDelay time.Duration //TapDelay wait after each report, a tap is a move, press and release.
+
+	mu          sync.Mutex
+	calibration Calibration
+	buttons     mouse.Button
+	position    Point
+	placed      bool //placed a position has been sent, until then the host's cursor could be anywhere.
+}
+
+// UnplacedError a button or the wheel was used before the pointer was put anywhere. Every report carries a position,
+// sending one would jump the cursor to the top left corner and click there.
+type UnplacedError struct{}
+
+func (e *UnplacedError) Error() string {
+	return "The pointer has no position yet, move or tap it first"
+}
+
+func (e *UnplacedError) String() string {
+	return e.Error()
+}
+
+// Calibration how screen pixels map to the pointer's coordinates.
+func (f *File) Calibration() Calibration {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.calibration
+}
+
+// Calibrate sets how screen pixels map to the pointer's coordinates.
+func (f *File) Calibrate(c Calibration) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calibration = c
+	return nil
+}
+
+// Position where the pointer was last put, 0, 0 until it is put anywhere.
+func (f *File) Position() Point {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.position
+}
+
+// Buttons the buttons held down.
+func (f *File) Buttons() mouse.Button {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.buttons
+}
+
+// Move puts the pointer at p with the held buttons, dragging if any are held.
+func (f *File) Move(p Point) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.write(f.buttons, p, 0)
+}
+
+// Tap puts the pointer at p and clicks buttons count times, count 2 is a double tap.
+func (f *File) Tap(p Point, buttons mouse.Button, count int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	held := f.buttons
+	if err := f.write(held, p, 0); err != nil {
+		return err
+	}
+	for i := 0; i < count; i++ {
+		if err := f.write(held|buttons, p, 0); err != nil {
+			return err
+		}
+		if err := f.write(held&^buttons, p, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Press holds buttons down where the pointer is until they are released. It fails with UnplacedError before the
+// pointer has been moved or tapped.
+func (f *File) Press(buttons mouse.Button) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.placed {
+		return &UnplacedError{}
+	}
+	return f.write(f.buttons|buttons, f.position, 0)
+}
+
+// Release lets go of buttons. It fails with UnplacedError before the pointer has been moved or tapped.
+func (f *File) Release(buttons mouse.Button) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.placed {
+		return &UnplacedError{}
+	}
+	return f.write(f.buttons&^buttons, f.position, 0)
+}
+
+// Scroll turns the wheel by clicks where the pointer is, positive scrolls up. It fails with UnplacedError before
+// the pointer has been moved or tapped.
+func (f *File) Scroll(clicks int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.placed {
+		return &UnplacedError{}
+	}
+	for clicks != 0 {
+		step := clamp(clicks, -mouse.MAX_STEP, mouse.MAX_STEP)
+		if err := f.write(f.buttons, f.position, step); err != nil {
+			return err
+		}
+		clicks -= step
+	}
+	return nil
+}
+
+// write sends a report and remembers the buttons and position, f.mu must be held.
+func (f *File) write(buttons mouse.Button, p Point, wheel int) error {
+	x, y := f.calibration.coords(p)
+	r := NewReport(buttons, x, y, wheel)
+	if _, err := f.Device.Write(r[:]); err != nil {
+		return err
+	}
+	f.buttons, f.position, f.placed = buttons, p, true
+	time.Sleep(f.TapDelay)
+	return nil
+}
@@ -0,0 +1,194 @@
+package pointer
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/scirelli/turkey-pi/pkg/mouse"
+)
+
+// reportRecorder a Device that keeps every report written to it.
+type reportRecorder struct {
+	reports []Report
+}
+
+func (d *reportRecorder) Write(p []byte) (int, error) {
+	var r Report
+	copy(r[:], p)
+	d.reports = append(d.reports, r)
+	return len(p), nil
+}
+
+func (d *reportRecorder) Close() error {
+	return nil
+}
+
+func TestNewReport(t *testing.T) {
+	var tests = []struct {
+		buttons mouse.Button
+		x, y    int
+		wheel   int
+		want    Report
+	}{
+		{mouse.BUTTON_NONE, 0, 0, 0, Report{0, 0, 0, 0, 0, 0}},
+		{mouse.BUTTON_LEFT, 0x1234, 0x0102, -1, Report{0x01, 0x34, 0x12, 0x02, 0x01, 0xff}},
+		{mouse.BUTTON_RIGHT, MAX_COORD, MAX_COORD, 127, Report{0x02, 0xff, 0x7f, 0xff, 0x7f, 0x7f}},
+		{mouse.BUTTON_NONE, -5, 40000, -300, Report{0, 0, 0, 0xff, 0x7f, 0x81}},
+	}
+
+	for _, test := range tests {
+		if got := NewReport(test.buttons, test.x, test.y, test.wheel); got != test.want {
+			t.Errorf("NewReport(%05b, %d, %d, %d) = % x, want % x", test.buttons, test.x, test.y, test.wheel, got, test.want)
+		}
+	}
+}
+
+func TestCalibrationCoords(t *testing.T) {
+	var full = Calibration{}
+	var rightHalf = Calibration{Left: 0.5, Top: 0, Right: 1, Bottom: 1}
+	var letterbox = Calibration{Left: 0.1, Top: 0.25, Right: 0.9, Bottom: 0.75}
+
+	var tests = []struct {
+		name string
+		c    Calibration
+		p    Point
+		x, y int
+	}{
+		{"top left", full, Point{0, 0}, 0, 0},
+		{"bottom right", full, Point{1, 1}, MAX_COORD, MAX_COORD},
+		{"center rounds up", full, Point{0.5, 0.5}, 16384, 16384},
+		{"rounds to nearest", full, Point{1.0 / 3, 2.0 / 3}, 10922, 21845},
+		{"clamped to the screen", full, Point{-0.5, 1.5}, 0, MAX_COORD},
+		{"right half left edge", rightHalf, Point{0, 0}, 16384, 0},
+		{"right half right edge", rightHalf, Point{1, 1}, MAX_COORD, MAX_COORD},
+		{"letterbox corners", letterbox, Point{0, 1}, 3277, 24575},
+		{"letterbox clamped", letterbox, Point{2, -1}, 29490, 8192},
+	}
+
+	for _, test := range tests {
+		if x, y := test.c.coords(test.p); x != test.x || y != test.y {
+			t.Errorf("%s: %v is at %d, %d, want %d, %d", test.name, test.p, x, y, test.x, test.y)
+		}
+	}
+}
+
+func TestCalibrationPixel(t *testing.T) {
+	var c = Calibration{Width: 1920, Height: 1080}
+
+	for _, test := range []struct {
+		x, y int
+		want Point
+	}{
+		{0, 0, Point{0, 0}},
+		{1919, 1079, Point{1, 1}},
+		{959, 0, Point{959.0 / 1919, 0}},
+	} {
+		if p, err := c.Pixel(test.x, test.y); err != nil || p != test.want {
+			t.Errorf("Pixel(%d, %d) = %v, %v, want %v", test.x, test.y, p, err, test.want)
+		}
+	}
+	for _, off := range [][2]int{{-1, 0}, {1920, 0}, {0, 1080}} {
+		if _, err := c.Pixel(off[0], off[1]); err == nil {
+			t.Errorf("Pixel(%d, %d) is on a 1920x1080 screen", off[0], off[1])
+		}
+	}
+	if _, err := (Calibration{}).Pixel(0, 0); err == nil {
+		t.Error("Pixel without a screen size succeeded")
+	}
+	if p, err := (Calibration{Width: 1, Height: 1}).Pixel(0, 0); err != nil || p != (Point{}) {
+		t.Errorf("the only pixel of a 1x1 screen is %v, %v", p, err)
+	}
+}
+
+func TestCalibrationValidate(t *testing.T) {
+	for _, c := range []Calibration{
+		{},
+		{Width: 800, Height: 600},
+		{Left: 0, Top: 0, Right: 0.5, Bottom: 1},
+	} {
+		if err := c.Validate(); err != nil {
+			t.Errorf("%+v: %v", c, err)
+		}
+	}
+	for _, c := range []Calibration{
+		{Width: -1},
+		{Left: 0.5, Top: 0, Right: 0.5, Bottom: 1},
+		{Left: 0, Top: 0, Right: 1.5, Bottom: 1},
+		{Left: 0.2, Top: 0.8, Right: 1, Bottom: 0.4},
+	} {
+		if err := c.Validate(); err == nil {
+			t.Errorf("%+v is valid", c)
+		}
+	}
+}
+
+// Every report carries a position, so buttons and the wheel can only be used once the pointer has been put somewhere.
+func TestUnplaced(t *testing.T) {
+	var dev reportRecorder
+	var f = File{Device: &dev}
+
+	for name, use := range map[string]func() error{
+		"Press":   func() error { return f.Press(mouse.BUTTON_LEFT) },
+		"Release": func() error { return f.Release(mouse.BUTTON_LEFT) },
+		"Scroll":  func() error { return f.Scroll(1) },
+	} {
+		var unplaced *UnplacedError
+		if err := use(); !errors.As(err, &unplaced) {
+			t.Errorf("%s before a move returned %v, want an UnplacedError", name, err)
+		}
+	}
+	if len(dev.reports) != 0 {
+		t.Fatalf("sent % x before the pointer was placed", dev.reports)
+	}
+
+	if err := f.Move(Point{0.5, 0.5}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Press(mouse.BUTTON_LEFT); err != nil {
+		t.Errorf("Press after a move: %v", err)
+	}
+}
+
+func TestFileReports(t *testing.T) {
+	var dev reportRecorder
+	var f = File{Device: &dev}
+
+	if err := f.Tap(Point{1, 0}, mouse.BUTTON_LEFT, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Press(mouse.BUTTON_RIGHT); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Move(Point{0, 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Scroll(-130); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Release(mouse.BUTTON_RIGHT); err != nil {
+		t.Fatal(err)
+	}
+
+	var want = []Report{
+		// The double tap moves first, then clicks where the pointer is.
+		{0x00, 0xff, 0x7f, 0, 0, 0},
+		{0x01, 0xff, 0x7f, 0, 0, 0}, {0x00, 0xff, 0x7f, 0, 0, 0},
+		{0x01, 0xff, 0x7f, 0, 0, 0}, {0x00, 0xff, 0x7f, 0, 0, 0},
+		{0x02, 0xff, 0x7f, 0, 0, 0},
+		// Moving with the right button held drags.
+		{0x02, 0, 0, 0xff, 0x7f, 0},
+		{0x02, 0, 0, 0xff, 0x7f, 0x81}, {0x02, 0, 0, 0xff, 0x7f, 0xfd},
+		{0x00, 0, 0, 0xff, 0x7f, 0},
+	}
+	if len(dev.reports) != len(want) {
+		t.Fatalf("sent % x, want % x", dev.reports, want)
+	}
+	for i := range want {
+		if dev.reports[i] != want[i] {
+			t.Errorf("report %d is % x, want % x", i, dev.reports[i], want[i])
+		}
+	}
+	if f.Position() != (Point{0, 1}) || f.Buttons() != mouse.BUTTON_NONE {
+		t.Errorf("at %v with %05b held", f.Position(), f.Buttons())
+	}
+}
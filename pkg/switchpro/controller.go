@@ -0,0 +1,307 @@
+package switchpro
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// DEFAULT_PERIOD how often a Pro Controller on USB sends REPORT_FULL.
+const DEFAULT_PERIOD time.Duration = 8 * time.Millisecond
+
+// DEFAULT_HOLD how long Press holds buttons when asked to by the server, long enough for games that read input once
+// a frame.
+const DEFAULT_HOLD time.Duration = 100 * time.Millisecond
+
+// Subcommands, byte 10 of an OUTPUT_SUBCOMMAND report. Those not listed are acknowledged without doing anything.
+const (
+	SUBCMD_BT_PAIRING      byte = 0x01
+	SUBCMD_DEVICE_INFO     byte = 0x02
+	SUBCMD_INPUT_MODE      byte = 0x03
+	SUBCMD_TRIGGER_ELAPSED byte = 0x04
+	SUBCMD_SHIPMENT        byte = 0x08
+	SUBCMD_SPI_READ        byte = 0x10
+	SUBCMD_MCU_CONFIG      byte = 0x21
+	SUBCMD_MCU_STATE       byte = 0x22
+	SUBCMD_PLAYER_LIGHTS   byte = 0x30
+	SUBCMD_HOME_LIGHT      byte = 0x38
+	SUBCMD_IMU             byte = 0x40
+	SUBCMD_IMU_SENSITIVITY byte = 0x41
+	SUBCMD_VIBRATION       byte = 0x48
+)
+
+// SUBCMD_OFFSET where the subcommand ID is in an OUTPUT_SUBCOMMAND report, after the packet counter and rumble data.
+const SUBCMD_OFFSET int = 10
+
+// ACK_OK the ACK byte of a subcommand reply without data, replies with data use 0x80 | their data type.
+const ACK_OK byte = 0x80
+
+// Status how far the host has got setting up the controller.
+type Status struct {
+	Handshake    bool  `json:"handshake"`    // Handshake the host sent USB_HANDSHAKE
+	Streaming    bool  `json:"streaming"`    // Streaming REPORT_FULL is being sent every Period
+	PlayerLights byte  `json:"playerLights"` // PlayerLights set by the host once it has accepted the controller
+	IMU          bool  `json:"imu"`
+	Vibration    bool  `json:"vibration"`
+	Subcommands  int   `json:"subcommands"` // Subcommands replied to
+	Reports      int64 `json:"reports"`     // Reports REPORT_FULL sent
+}
+
+// Controller represents the Pro Controller device file in user space /dev/hidg<#>. It must be opened for reading and
+// writing, the host's output reports drive the handshake.
+type Controller struct {
+	os.File
+	Period time.Duration //Period between REPORT_FULL reports, DEFAULT_PERIOD when 0.
+	MAC    [6]byte       //MAC reported to the host, it tells controllers apart by it.
+	Flash  Flash         //Flash read by the host, DefaultFlash when nil.
+
+	mu     sync.Mutex
+	state  State
+	status Status
+	timer  byte
+}
+
+// State the buttons and sticks being reported.
+func (c *Controller) State() State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.state
+}
+
+// Status how far the host has got setting up the controller.
+func (c *Controller) Status() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.status
+}
+
+// Hold holds buttons down until they are released. The host sees the change in the next REPORT_FULL.
+func (c *Controller) Hold(buttons Button) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.state.Buttons |= buttons
+}
+
+// Release lets go of buttons.
+func (c *Controller) Release(buttons Button) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.state.Buttons &^= buttons
+}
+
+// ReleaseAll lets go of every button and centers the sticks.
+func (c *Controller) ReleaseAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.state = State{}
+}
+
+// SetStick moves a stick, see StickPosition.
+func (c *Controller) SetStick(s Stick, p StickPosition) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.state.Sticks[s] = p
+}
+
+// Press holds buttons for hold then releases them count times, waiting hold after each release. A button has to be
+// down for at least one report for the host to see it, so hold should be a few Periods.
+func (c *Controller) Press(ctx context.Context, buttons Button, count int, hold time.Duration) error {
+	for i := 0; i < count; i++ {
+		c.Hold(buttons)
+		err := sleepContext(ctx, hold)
+		c.Release(buttons)
+		if err != nil {
+			return err
+		}
+		if err := sleepContext(ctx, hold); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run answers the host's output reports and sends REPORT_FULL every Period once the host asks for it, until ctx is
+// done or reading or writing the device fails. A read in progress when ctx is done only returns once the device is
+// closed.
+func (c *Controller) Run(ctx context.Context) error {
+	var period time.Duration = c.Period
+	var outputs = make(chan Report)
+	var readErr = make(chan error, 1)
+
+	if period <= 0 {
+		period = DEFAULT_PERIOD
+	}
+	if c.Flash == nil {
+		c.Flash = DefaultFlash()
+	}
+
+	go func() {
+		var r Report
+		for {
+			n, err := c.File.Read(r[:])
+			if n > 0 {
+				select {
+				case outputs <- r:
+				case <-ctx.Done():
+					return
+				}
+				r = Report{}
+			}
+			if err == io.EOF {
+				err = nil
+			}
+			if err != nil || n == 0 {
+				readErr <- err
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-readErr:
+			return err
+		case r := <-outputs:
+			if err := c.handle(r); err != nil {
+				return err
+			}
+		case <-ticker.C:
+			if err := c.sendFull(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// sendFull sends REPORT_FULL if the host has asked for it.
+func (c *Controller) sendFull() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.status.Streaming {
+		return nil
+	}
+	var r Report = c.report(REPORT_FULL)
+	c.status.Reports++
+	return c.write(r)
+}
+
+// handle answers an output report from the host.
+func (c *Controller) handle(out Report) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch out[0] {
+	case OUTPUT_USB:
+		return c.handleUSB(out[1])
+	case OUTPUT_SUBCOMMAND:
+		return c.handleSubcommand(out[SUBCMD_OFFSET], out[SUBCMD_OFFSET+1:])
+	}
+	// OUTPUT_RUMBLE and anything unknown need no reply.
+	return nil
+}
+
+func (c *Controller) handleUSB(cmd byte) error {
+	var r Report = Report{REPORT_USB_REPLY, cmd}
+
+	switch cmd {
+	case USB_STATUS:
+		// Controller type then the MAC, least significant byte first.
+		r[3] = 0x03
+		for i := range c.MAC {
+			r[4+i] = c.MAC[len(c.MAC)-1-i]
+		}
+	case USB_HANDSHAKE:
+		c.status.Handshake = true
+	case USB_BAUDRATE:
+	case USB_NO_TIMEOUT:
+		c.status.Streaming = true
+		return nil
+	case USB_ALLOW_BT:
+		c.status.Streaming = false
+		return nil
+	default:
+		return nil
+	}
+	return c.write(r)
+}
+
+func (c *Controller) handleSubcommand(id byte, args []byte) error {
+	var ack byte = ACK_OK
+	var data []byte
+
+	switch id {
+	case SUBCMD_BT_PAIRING:
+		ack, data = 0x81, []byte{0x03}
+	case SUBCMD_DEVICE_INFO:
+		// Firmware 3.139, Pro Controller, always 0x02, MAC, always 0x01, colours are in the SPI flash.
+		ack, data = 0x82, append(append([]byte{0x03, 0x8B, 0x03, 0x02}, c.MAC[:]...), 0x01, 0x01)
+	case SUBCMD_TRIGGER_ELAPSED:
+		ack, data = 0x83, make([]byte, 14)
+	case SUBCMD_SPI_READ:
+		addr := uint32(args[0]) | uint32(args[1])<<8 | uint32(args[2])<<16 | uint32(args[3])<<24
+		n := int(args[4])
+		if n > MAX_SPI_READ {
+			n = MAX_SPI_READ
+		}
+		ack, data = 0x90, append(append([]byte{}, args[:5]...), c.Flash.Read(addr, n)...)
+	case SUBCMD_MCU_CONFIG:
+		// MCU state report, the last byte is its CRC-8.
+		ack, data = 0xA0, make([]byte, 34)
+		copy(data, []byte{0x01, 0x00, 0xFF, 0x00, 0x08, 0x00, 0x1B, 0x01})
+		data[33] = 0xC8
+	case SUBCMD_PLAYER_LIGHTS:
+		c.status.PlayerLights = args[0]
+	case SUBCMD_IMU:
+		c.status.IMU = args[0] != 0
+	case SUBCMD_VIBRATION:
+		c.status.Vibration = args[0] != 0
+	}
+
+	var r Report = c.report(REPORT_SUBCOMMAND_REPLY)
+	r[HEADER_SZ] = ack
+	r[HEADER_SZ+1] = id
+	copy(r[HEADER_SZ+2:], data)
+	c.status.Subcommands++
+	return c.write(r)
+}
+
+// report an input report with the header filled in, c.mu must be held.
+func (c *Controller) report(id byte) Report {
+	var r Report = Report{id}
+
+	c.timer++
+	c.state.header(&r, c.timer)
+	return r
+}
+
+// write c.mu must be held so replies and REPORT_FULL are not interleaved.
+func (c *Controller) write(r Report) error {
+	_, err := c.File.Write(r[:])
+	return err
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
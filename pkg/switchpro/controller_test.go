@@ -0,0 +1,148 @@
+//go:build linux
+// +build linux
+
+package switchpro
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+var testMAC = [6]byte{0x98, 0xB6, 0xE9, 0x00, 0x00, 0x01}
+
+// runHandshake runs a Controller against a FakeHost playing SWITCH_HANDSHAKE and listening for listen.
+func runHandshake(t *testing.T, period, listen time.Duration) (*Controller, Transcript) {
+	t.Helper()
+	device, host, err := NewPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var controller = &Controller{File: *device, Period: period, MAC: testMAC}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- controller.Run(ctx) }()
+	t.Cleanup(func() {
+		// Run stops before the files close, a report written to a closed file would fail it.
+		cancel()
+		var err error
+		select {
+		case err = <-done:
+		case <-time.After(time.Second):
+			host.Close()
+			err = <-done
+		}
+		host.Close()
+		controller.Close()
+		if err != nil && err != context.Canceled {
+			t.Errorf("Run = %v", err)
+		}
+	})
+
+	fakeHost := FakeHost{File: host, Listen: listen}
+	transcript, err := fakeHost.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return controller, transcript
+}
+
+func TestControllerHandshake(t *testing.T) {
+	controller, transcript := runHandshake(t, DEFAULT_PERIOD, 0)
+
+	if len(transcript.Exchanges) != len(SWITCH_HANDSHAKE) {
+		t.Fatalf("%d exchanges, want %d", len(transcript.Exchanges), len(SWITCH_HANDSHAKE))
+	}
+	for i, e := range transcript.Exchanges {
+		step := SWITCH_HANDSHAKE[i]
+		if e.Step.Name != step.Name {
+			t.Errorf("exchange %d is %q, want %q", i, e.Step.Name, step.Name)
+		}
+		_, _, wantsReply := step.expectsReply()
+		if (e.Reply != nil) != wantsReply {
+			t.Errorf("%s: reply %v, want one %v", step.Name, e.Reply != nil, wantsReply)
+		}
+	}
+
+	// The status reply carries the controller type and the MAC backwards.
+	status := transcript.Exchanges[0].Reply
+	if status[0] != REPORT_USB_REPLY || status[1] != USB_STATUS || status[3] != 0x03 {
+		t.Errorf("status reply % x", status[:10])
+	}
+	for i := range testMAC {
+		if status[4+i] != testMAC[len(testMAC)-1-i] {
+			t.Errorf("status reply MAC % x, want % x reversed", status[4:10], testMAC)
+			break
+		}
+	}
+
+	s := controller.Status()
+	if !s.Handshake || !s.Streaming || s.PlayerLights != 0x01 || !s.IMU || !s.Vibration {
+		t.Errorf("Status() = %+v, want handshake, streaming, player 1, IMU and vibration", s)
+	}
+	if want := len(SWITCH_HANDSHAKE) - 5; s.Subcommands != want {
+		t.Errorf("%d subcommands replied to, want %d", s.Subcommands, want)
+	}
+}
+
+// SPI reads echo the address and length asked for, followed by the flash contents there.
+func TestControllerSPIReads(t *testing.T) {
+	_, transcript := runHandshake(t, DEFAULT_PERIOD, 0)
+	var flash = DefaultFlash()
+	var reads int
+
+	for _, e := range transcript.Exchanges {
+		out := e.Step.Output
+		if out[0] != OUTPUT_SUBCOMMAND || out[SUBCMD_OFFSET] != SUBCMD_SPI_READ {
+			continue
+		}
+		reads++
+		args := out[SUBCMD_OFFSET+1 : SUBCMD_OFFSET+6]
+		reply := e.Reply[HEADER_SZ:]
+		if reply[0] != 0x90 || reply[1] != SUBCMD_SPI_READ {
+			t.Errorf("%s: ACK %#02x for subcommand %#02x", e.Step.Name, reply[0], reply[1])
+		}
+		if !bytes.Equal(reply[2:7], args) {
+			t.Errorf("%s: echoed % x, want % x", e.Step.Name, reply[2:7], args)
+		}
+		addr := uint32(args[0]) | uint32(args[1])<<8 | uint32(args[2])<<16 | uint32(args[3])<<24
+		if want := flash.Read(addr, int(args[4])); !bytes.Equal(reply[7:7+len(want)], want) {
+			t.Errorf("%s: read % x, want % x", e.Step.Name, reply[7:7+len(want)], want)
+		}
+	}
+	if reads != 8 {
+		t.Errorf("%d SPI reads, want 8", reads)
+	}
+}
+
+// REPORT_FULL arrives every Period once the host has set the controller up.
+func TestControllerFullReportCadence(t *testing.T) {
+	const period = 8 * time.Millisecond
+	_, transcript := runHandshake(t, period, 400*time.Millisecond)
+
+	if transcript.FullReports < 30 {
+		t.Fatalf("%d full reports in 400ms, want about 50", transcript.FullReports)
+	}
+	if diff := transcript.Period - period; diff < -period/10 || diff > period/10 {
+		t.Errorf("full reports every %s, want %s ±10%%", transcript.Period, period)
+	}
+	if transcript.MaxGap > 4*period {
+		t.Errorf("longest gap between full reports %s, want under %s", transcript.MaxGap, 4*period)
+	}
+}
+
+func TestSPIReadStepCheck(t *testing.T) {
+	var step = SPIReadStep("colours", 0, SPI_COLORS, 0x0D)
+	var reply Report
+
+	copy(reply[HEADER_SZ+2:], step.Output[SUBCMD_OFFSET+1:SUBCMD_OFFSET+6])
+	if err := step.Check(reply); err != nil {
+		t.Errorf("Check of a matching echo = %v", err)
+	}
+	reply[HEADER_SZ+2]++
+	if err := step.Check(reply); err == nil {
+		t.Error("Check of a reply for another address = nil")
+	}
+}
@@ -0,0 +1,266 @@
+package switchpro
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// NEUTRAL_RUMBLE rumble data for both motors that does not rumble, sent with every subcommand.
+var NEUTRAL_RUMBLE = [8]byte{0x00, 0x01, 0x40, 0x40, 0x00, 0x01, 0x40, 0x40}
+
+// Step one output report a FakeHost sends. The reply it waits for follows from the report, a USB_REPLY to USB_STATUS,
+// USB_HANDSHAKE and USB_BAUDRATE and a REPORT_SUBCOMMAND_REPLY to every subcommand.
+type Step struct {
+	Name   string
+	Output Report
+	Check  func(reply Report) error //Check optional, further checks on the reply.
+}
+
+// USBStep a step that sends USB command cmd.
+func USBStep(name string, cmd byte) Step {
+	return Step{Name: name, Output: Report{OUTPUT_USB, cmd}}
+}
+
+// SubcommandStep a step that sends subcommand id with args, counter is the packet counter from 0 to 15.
+func SubcommandStep(name string, counter, id byte, args ...byte) Step {
+	var r Report = Report{OUTPUT_SUBCOMMAND, counter & 0x0F}
+
+	copy(r[2:], NEUTRAL_RUMBLE[:])
+	r[SUBCMD_OFFSET] = id
+	copy(r[SUBCMD_OFFSET+1:], args)
+	return Step{Name: name, Output: r}
+}
+
+// SPIReadStep a step that reads n bytes of SPI flash at addr and checks the reply echoes them.
+func SPIReadStep(name string, counter byte, addr uint32, n byte) Step {
+	s := SubcommandStep(name, counter, SUBCMD_SPI_READ, byte(addr), byte(addr>>8), byte(addr>>16), byte(addr>>24), n)
+	s.Check = func(reply Report) error {
+		for i, b := range s.Output[SUBCMD_OFFSET+1 : SUBCMD_OFFSET+6] {
+			if reply[HEADER_SZ+2+i] != b {
+				return fmt.Errorf("SPI read of %d bytes at %#04x replied for %d bytes at %#04x", n, addr, reply[HEADER_SZ+6],
+					uint32(reply[HEADER_SZ+2])|uint32(reply[HEADER_SZ+3])<<8)
+			}
+		}
+		return nil
+	}
+	return s
+}
+
+// expectsReply true and the reply's report ID and command or subcommand if s gets a reply.
+func (s Step) expectsReply() (id, cmd byte, ok bool) {
+	switch s.Output[0] {
+	case OUTPUT_USB:
+		switch s.Output[1] {
+		case USB_STATUS, USB_HANDSHAKE, USB_BAUDRATE:
+			return REPORT_USB_REPLY, s.Output[1], true
+		}
+	case OUTPUT_SUBCOMMAND:
+		return REPORT_SUBCOMMAND_REPLY, s.Output[SUBCMD_OFFSET], true
+	}
+	return 0, 0, false
+}
+
+/*
+SWITCH_HANDSHAKE the output reports a Switch sends a wired Pro Controller before it takes input from it, in order.
+
+	The USB commands come first, USB_NO_TIMEOUT starts REPORT_FULL. The console then asks for the device info, reads
+	the serial number, colours and calibration from SPI flash, sets up the IMU, vibration and the NFC/IR MCU and
+	finally sets the player lights, which is when the controller shows up as connected.
+
+	It is written by hand from the published reverse engineering notes, not replayed from a capture of a real console.
+	A Switch may order the steps differently, repeat them or send rumble in between, so passing it shows the
+	controller answers each request correctly rather than that a console will accept it.
+	  https://github.com/dekuNukem/Nintendo_Switch_Reverse_Engineering
+*/
+var SWITCH_HANDSHAKE = []Step{
+	USBStep("status", USB_STATUS),
+	USBStep("handshake", USB_HANDSHAKE),
+	USBStep("baudrate", USB_BAUDRATE),
+	USBStep("handshake at 3 Mbit", USB_HANDSHAKE),
+	USBStep("usb only", USB_NO_TIMEOUT),
+	SubcommandStep("device info", 0, SUBCMD_DEVICE_INFO),
+	SubcommandStep("shipment state", 1, SUBCMD_SHIPMENT, 0x00),
+	SPIReadStep("serial number", 2, SPI_SERIAL_NUMBER, 0x10),
+	SPIReadStep("colours", 3, SPI_COLORS, 0x0D),
+	SubcommandStep("input mode full", 4, SUBCMD_INPUT_MODE, REPORT_FULL),
+	SubcommandStep("trigger elapsed time", 5, SUBCMD_TRIGGER_ELAPSED),
+	SPIReadStep("factory IMU parameters", 6, SPI_FACTORY_IMU_PARAMS, 0x18),
+	SPIReadStep("right stick parameters", 7, SPI_RIGHT_STICK_PARAMS, 0x12),
+	SPIReadStep("user stick calibration", 8, SPI_USER_STICK_CAL, 0x16),
+	SPIReadStep("factory stick calibration", 9, SPI_FACTORY_LEFT_CAL, 0x12),
+	SPIReadStep("user IMU calibration", 10, SPI_USER_IMU_CAL, 0x1A),
+	SPIReadStep("factory IMU calibration", 11, SPI_FACTORY_IMU_CAL, 0x18),
+	SubcommandStep("enable IMU", 12, SUBCMD_IMU, 0x01),
+	SubcommandStep("enable vibration", 13, SUBCMD_VIBRATION, 0x01),
+	SubcommandStep("MCU state", 14, SUBCMD_MCU_STATE, 0x01),
+	SubcommandStep("MCU config", 15, SUBCMD_MCU_CONFIG, 0x21, 0x00, 0x00),
+	SubcommandStep("player lights", 0, SUBCMD_PLAYER_LIGHTS, 0x01),
+	SubcommandStep("home light", 1, SUBCMD_HOME_LIGHT, 0x01, 0x00),
+}
+
+// Exchange a step and the reply to it.
+type Exchange struct {
+	Step    Step
+	Reply   *Report // nil for steps that get no reply
+	Latency time.Duration
+}
+
+// Transcript what happened during FakeHost.Run.
+type Transcript struct {
+	Exchanges   []Exchange
+	FullReports int           // REPORT_FULL seen over the whole run
+	Period      time.Duration // mean time between REPORT_FULL while listening
+	MaxGap      time.Duration // longest time between REPORT_FULL while listening
+}
+
+// FakeHost plays the host's side of the protocol from a script, so a Controller can be checked without a Switch.
+type FakeHost struct {
+	File    *os.File      //File the host end of NewPair.
+	Steps   []Step        //Steps sent in order, SWITCH_HANDSHAKE when nil.
+	Timeout time.Duration //Timeout how long to wait for each reply, a second when 0.
+	Listen  time.Duration //Listen how long to keep reading REPORT_FULL after the last step.
+}
+
+// received an input report and when it arrived.
+type received struct {
+	r  Report
+	at time.Time
+}
+
+// Run sends each step and waits for its reply, then listens for REPORT_FULL. It stops at the first missing or wrong
+// reply, the transcript holds the exchanges up to it. The reader it starts stops when File is closed.
+func (h *FakeHost) Run() (Transcript, error) {
+	var t Transcript
+	var steps []Step = h.Steps
+	var timeout time.Duration = h.Timeout
+	var inputs = make(chan received, 64)
+
+	if steps == nil {
+		steps = SWITCH_HANDSHAKE
+	}
+	if timeout <= 0 {
+		timeout = time.Second
+	}
+
+	go func() {
+		defer close(inputs)
+		for {
+			var r Report
+			n, err := h.File.Read(r[:])
+			if err != nil || n == 0 {
+				return
+			}
+			inputs <- received{r, time.Now()}
+		}
+	}()
+
+	for _, s := range steps {
+		sent := time.Now()
+		if _, err := h.File.Write(s.Output[:]); err != nil {
+			return t, fmt.Errorf("%s: %w", s.Name, err)
+		}
+
+		id, cmd, ok := s.expectsReply()
+		if !ok {
+			t.Exchanges = append(t.Exchanges, Exchange{Step: s})
+			continue
+		}
+		reply, err := h.await(&t, inputs, id, timeout)
+		if err != nil {
+			return t, fmt.Errorf("%s: %w", s.Name, err)
+		}
+		t.Exchanges = append(t.Exchanges, Exchange{Step: s, Reply: &reply.r, Latency: reply.at.Sub(sent)})
+
+		if err := checkReply(reply.r, id, cmd); err != nil {
+			return t, fmt.Errorf("%s: %w", s.Name, err)
+		}
+		if s.Check != nil {
+			if err := s.Check(reply.r); err != nil {
+				return t, fmt.Errorf("%s: %w", s.Name, err)
+			}
+		}
+	}
+
+	h.listen(&t, inputs)
+	return t, nil
+}
+
+// await the next input report with ID id, counting the REPORT_FULL that arrive first.
+func (h *FakeHost) await(t *Transcript, inputs <-chan received, id byte, timeout time.Duration) (received, error) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		select {
+		case in, ok := <-inputs:
+			if !ok {
+				return in, fmt.Errorf("device closed waiting for report %#02x", id)
+			}
+			if in.r[0] == id {
+				return in, nil
+			}
+			if in.r[0] == REPORT_FULL {
+				t.FullReports++
+			}
+		case <-deadline.C:
+			return received{}, fmt.Errorf("no report %#02x after %s", id, timeout)
+		}
+	}
+}
+
+// listen counts REPORT_FULL for h.Listen and measures the time between them.
+func (h *FakeHost) listen(t *Transcript, inputs <-chan received) {
+	var first, last time.Time
+	var count int
+
+	if h.Listen <= 0 {
+		return
+	}
+	deadline := time.NewTimer(h.Listen)
+	defer deadline.Stop()
+
+	for {
+		select {
+		case in, ok := <-inputs:
+			if !ok {
+				return
+			}
+			if in.r[0] != REPORT_FULL {
+				continue
+			}
+			t.FullReports++
+			if count > 0 && in.at.Sub(last) > t.MaxGap {
+				t.MaxGap = in.at.Sub(last)
+			}
+			if count == 0 {
+				first = in.at
+			}
+			last = in.at
+			count++
+			if count > 1 {
+				t.Period = last.Sub(first) / time.Duration(count-1)
+			}
+		case <-deadline.C:
+			return
+		}
+	}
+}
+
+// checkReply the reply answers command or subcommand cmd.
+func checkReply(r Report, id, cmd byte) error {
+	switch id {
+	case REPORT_USB_REPLY:
+		if r[1] != cmd {
+			return fmt.Errorf("reply for USB command %#02x, expected %#02x", r[1], cmd)
+		}
+	case REPORT_SUBCOMMAND_REPLY:
+		if r[HEADER_SZ]&ACK_OK == 0 {
+			return fmt.Errorf("subcommand %#02x not acknowledged, ACK %#02x", cmd, r[HEADER_SZ])
+		}
+		if r[HEADER_SZ+1] != cmd {
+			return fmt.Errorf("reply for subcommand %#02x, expected %#02x", r[HEADER_SZ+1], cmd)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package switchpro
+
+// Flash the controller's SPI flash, the host reads the controller's colours and calibration from it. Only written
+// bytes are kept, everything else reads as 0xFF like erased flash. It is not safe for concurrent use.
+type Flash map[uint32]byte
+
+// SPI flash addresses the host reads during the handshake.
+const (
+	SPI_SERIAL_NUMBER      uint32 = 0x6000 // 16 bytes, 0xFF when there is none
+	SPI_DEVICE_TYPE        uint32 = 0x6012
+	SPI_FACTORY_IMU_CAL    uint32 = 0x6020 // 24 bytes
+	SPI_FACTORY_LEFT_CAL   uint32 = 0x603D // 9 bytes
+	SPI_FACTORY_RIGHT_CAL  uint32 = 0x6046 // 9 bytes
+	SPI_COLORS             uint32 = 0x6050 // body, buttons, left grip and right grip, 3 bytes each RGB
+	SPI_FACTORY_IMU_PARAMS uint32 = 0x6080 // 24 bytes, IMU offsets then the left stick parameters
+	SPI_RIGHT_STICK_PARAMS uint32 = 0x6098 // 18 bytes
+	SPI_USER_STICK_CAL     uint32 = 0x8010 // 22 bytes, 0xFF when the user has not calibrated
+	SPI_USER_IMU_CAL       uint32 = 0x8026 // 26 bytes, 0xFF when the user has not calibrated
+)
+
+// MAX_SPI_READ the most a single SPI read subcommand returns, what fits in a subcommand reply.
+const MAX_SPI_READ int = 0x1D
+
+// Read n bytes from addr.
+func (f Flash) Read(addr uint32, n int) []byte {
+	var data []byte = make([]byte, n)
+
+	for i := range data {
+		if b, ok := f[addr+uint32(i)]; ok {
+			data[i] = b
+		} else {
+			data[i] = 0xFF
+		}
+	}
+	return data
+}
+
+// Write data at addr.
+func (f Flash) Write(addr uint32, data []byte) {
+	for i, b := range data {
+		f[addr+uint32(i)] = b
+	}
+}
+
+// Colors the body, buttons and grip colours shown on the Switch's controller screens.
+type Colors struct {
+	Body, Buttons, LeftGrip, RightGrip [3]byte
+}
+
+// DEFAULT_COLORS a dark grey Pro Controller.
+var DEFAULT_COLORS = Colors{
+	Body:      [3]byte{0x32, 0x32, 0x32},
+	Buttons:   [3]byte{0xFF, 0xFF, 0xFF},
+	LeftGrip:  [3]byte{0x32, 0x32, 0x32},
+	RightGrip: [3]byte{0x32, 0x32, 0x32},
+}
+
+// DefaultFlash the flash of a Pro Controller with the stick calibration this package encodes sticks with, see
+// STICK_CENTER.
+func DefaultFlash() Flash {
+	var f Flash = Flash{}
+	var center, max, min [3]byte = pack12(STICK_CENTER, STICK_CENTER), pack12(STICK_RANGE, STICK_RANGE), pack12(STICK_RANGE, STICK_RANGE)
+
+	f.Write(SPI_DEVICE_TYPE, []byte{0x03})
+	f.Write(SPI_FACTORY_IMU_CAL, []byte{
+		0xD3, 0xFF, 0xD5, 0xFF, 0x55, 0x01, // accelerometer origin
+		0x00, 0x40, 0x00, 0x40, 0x00, 0x40, // accelerometer sensitivity
+		0x19, 0x00, 0xDD, 0xFF, 0xDC, 0xFF, // gyro origin
+		0x3B, 0x34, 0x3B, 0x34, 0x3B, 0x34, // gyro sensitivity
+	})
+	// The left stick is stored as above center, center, below center. The right stick as center, below, above.
+	f.Write(SPI_FACTORY_LEFT_CAL, append(append(max[:], center[:]...), min[:]...))
+	f.Write(SPI_FACTORY_RIGHT_CAL, append(append(center[:], min[:]...), max[:]...))
+	f.SetColors(DEFAULT_COLORS)
+	f.Write(SPI_FACTORY_IMU_PARAMS, []byte{
+		0x50, 0xFD, 0x00, 0x00, 0xC6, 0x0F, // IMU horizontal offsets
+		0x0F, 0x30, 0x61, 0x96, 0x30, 0xF3, 0xD4, 0x14, 0x54, 0x41, 0x15, 0x54, 0xC7, 0x79, 0x9C, 0x33, 0x36, 0x63,
+	})
+	f.Write(SPI_RIGHT_STICK_PARAMS, []byte{
+		0x0F, 0x30, 0x61, 0x96, 0x30, 0xF3, 0xD4, 0x14, 0x54, 0x41, 0x15, 0x54, 0xC7, 0x79, 0x9C, 0x33, 0x36, 0x63,
+	})
+	return f
+}
+
+// SetColors writes c where the host looks for the controller's colours.
+func (f Flash) SetColors(c Colors) {
+	var data []byte = make([]byte, 0, 12)
+
+	for _, rgb := range [][3]byte{c.Body, c.Buttons, c.LeftGrip, c.RightGrip} {
+		data = append(data, rgb[:]...)
+	}
+	f.Write(SPI_COLORS, data)
+}
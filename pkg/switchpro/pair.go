@@ -0,0 +1,19 @@
+//go:build linux
+// +build linux
+
+package switchpro
+
+import (
+	"os"
+	"syscall"
+)
+
+// NewPair two connected files that behave like the two ends of a hidg device, each write is read as one report on the
+// other end. Give device to a Controller and host to a FakeHost. Close both when done.
+func NewPair() (device, host *os.File, err error) {
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_SEQPACKET|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, nil, os.NewSyscallError("socketpair", err)
+	}
+	return os.NewFile(uintptr(fds[0]), "switchpro-device"), os.NewFile(uintptr(fds[1]), "switchpro-host"), nil
+}
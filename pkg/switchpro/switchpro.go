@@ -0,0 +1,215 @@
+// Package switchpro emulates a Nintendo Switch Pro Controller over USB on a gadget device file such as /dev/hidg0.
+//
+// Unlike a generic gamepad the Switch expects the controller to answer a handshake and subcommands before it accepts
+// any input, see Controller.Run. The gadget must use Nintendo's vendor and product IDs, see init/enable-switchpro-hid.
+package switchpro
+
+import (
+	"fmt"
+	"strings"
+)
+
+// REPORT_SZ every report in both directions is 64 bytes, the first byte is the report ID.
+const REPORT_SZ int = 64
+
+type Report [REPORT_SZ]byte
+
+// Input report IDs, sent by the controller.
+const (
+	REPORT_SUBCOMMAND_REPLY byte = 0x21 // reply to an OUTPUT_SUBCOMMAND
+	REPORT_FULL             byte = 0x30 // buttons, sticks and IMU, sent every Period once the host asks for them
+	REPORT_USB_REPLY        byte = 0x81 // reply to an OUTPUT_USB command
+)
+
+// Output report IDs, sent by the host.
+const (
+	OUTPUT_SUBCOMMAND byte = 0x01 // rumble data then a subcommand
+	OUTPUT_RUMBLE     byte = 0x10 // rumble data only
+	OUTPUT_USB        byte = 0x80 // USB commands, see USB_*
+)
+
+// USB commands, the second byte of an OUTPUT_USB report.
+const (
+	USB_STATUS     byte = 0x01 // replied to with the controller type and MAC
+	USB_HANDSHAKE  byte = 0x02
+	USB_BAUDRATE   byte = 0x03 // switch the UART between the USB chip and the controller to 3 Mbit
+	USB_NO_TIMEOUT byte = 0x04 // talk over USB only, the controller starts sending REPORT_FULL
+	USB_ALLOW_BT   byte = 0x05 // back to Bluetooth, the controller stops sending REPORT_FULL
+)
+
+/*
+Input report header, the first 13 bytes of REPORT_FULL and REPORT_SUBCOMMAND_REPLY.
+
+	Byte 0      report ID
+	Byte 1      timer, incremented with each report
+	Byte 2      battery level in the high nibble, connection info in the low nibble
+	Byte 3-5    buttons, right, shared and left
+	Byte 6-8    left stick, 12 bit X and Y
+	Byte 9-11   right stick
+	Byte 12     vibrator report
+
+REPORT_FULL continues with three frames of IMU data in bytes 13 to 48. REPORT_SUBCOMMAND_REPLY continues with the ACK
+byte, the subcommand ID and its reply data.
+*/
+const HEADER_SZ int = 13
+
+// BATTERY_CONNECTION battery full and charging, powered by USB.
+const BATTERY_CONNECTION byte = 0x91
+
+// VIBRATOR_REPORT what the controller reports about its vibrators when nothing is rumbling.
+const VIBRATOR_REPORT byte = 0x80
+
+// Button the bits of bytes 3 to 5 of the header, bits 0-7 are byte 3.
+type Button uint32
+
+const (
+	BUTTON_NONE    Button = 0
+	BUTTON_Y       Button = 1 << 0
+	BUTTON_X       Button = 1 << 1
+	BUTTON_B       Button = 1 << 2
+	BUTTON_A       Button = 1 << 3
+	BUTTON_R_SR    Button = 1 << 4 // Joy-Con only
+	BUTTON_R_SL    Button = 1 << 5 // Joy-Con only
+	BUTTON_R       Button = 1 << 6
+	BUTTON_ZR      Button = 1 << 7
+	BUTTON_MINUS   Button = 1 << 8
+	BUTTON_PLUS    Button = 1 << 9
+	BUTTON_RSTICK  Button = 1 << 10
+	BUTTON_LSTICK  Button = 1 << 11
+	BUTTON_HOME    Button = 1 << 12
+	BUTTON_CAPTURE Button = 1 << 13
+	BUTTON_DOWN    Button = 1 << 16
+	BUTTON_UP      Button = 1 << 17
+	BUTTON_RIGHT   Button = 1 << 18
+	BUTTON_LEFT    Button = 1 << 19
+	BUTTON_L_SR    Button = 1 << 20 // Joy-Con only
+	BUTTON_L_SL    Button = 1 << 21 // Joy-Con only
+	BUTTON_L       Button = 1 << 22
+	BUTTON_ZL      Button = 1 << 23
+)
+
+var buttonNames = map[string]Button{
+	"a":       BUTTON_A,
+	"b":       BUTTON_B,
+	"x":       BUTTON_X,
+	"y":       BUTTON_Y,
+	"l":       BUTTON_L,
+	"r":       BUTTON_R,
+	"zl":      BUTTON_ZL,
+	"zr":      BUTTON_ZR,
+	"minus":   BUTTON_MINUS,
+	"plus":    BUTTON_PLUS,
+	"lstick":  BUTTON_LSTICK,
+	"rstick":  BUTTON_RSTICK,
+	"home":    BUTTON_HOME,
+	"capture": BUTTON_CAPTURE,
+	"up":      BUTTON_UP,
+	"down":    BUTTON_DOWN,
+	"left":    BUTTON_LEFT,
+	"right":   BUTTON_RIGHT,
+}
+
+// ButtonByName find a button by name, e.g. "a", "zl", "plus" or "up" for the d-pad. Names are case insensitive.
+func ButtonByName(name string) (Button, error) {
+	if b, ok := buttonNames[strings.ToLower(strings.TrimSpace(name))]; ok {
+		return b, nil
+	}
+	return BUTTON_NONE, fmt.Errorf("Unknown Pro Controller button '%s', expected one of: a, b, x, y, l, r, zl, zr, minus, plus, lstick, rstick, home, capture, up, down, left, right", name)
+}
+
+// ButtonNames the names of the buttons in b.
+func ButtonNames(b Button) []string {
+	var names []string = []string{}
+
+	for i := 0; i < 24; i++ {
+		bit := Button(1) << i
+		if b&bit == 0 {
+			continue
+		}
+		for name, nb := range buttonNames {
+			if nb == bit {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+type Stick int
+
+const (
+	STICK_LEFT Stick = iota
+	STICK_RIGHT
+)
+
+// StickByName "left" or "right".
+func StickByName(name string) (Stick, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "left", "l":
+		return STICK_LEFT, nil
+	case "right", "r":
+		return STICK_RIGHT, nil
+	}
+	return STICK_LEFT, fmt.Errorf("Unknown Pro Controller stick '%s', expected left or right", name)
+}
+
+func (s Stick) String() string {
+	if s == STICK_RIGHT {
+		return "right"
+	}
+	return "left"
+}
+
+/*
+Sticks are 12 bit values the host maps to a direction with the calibration in the controller's SPI flash. This
+package writes its own calibration, see DefaultFlash, so a stick at -MAX_STICK to MAX_STICK spans STICK_RANGE either
+side of STICK_CENTER. Positions are given the same way as the gamepad package, positive X is right and positive Y is
+down, and are flipped to the controller's positive up when encoded.
+*/
+const (
+	MAX_STICK    int    = 127
+	STICK_CENTER uint16 = 0x800
+	STICK_RANGE  uint16 = 0x600
+)
+
+// StickPosition X and Y from -MAX_STICK to MAX_STICK.
+type StickPosition struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// raw the 12 bit values for the position, out of range values are clamped.
+func (p StickPosition) raw() (x, y uint16) {
+	scale := func(v int) uint16 {
+		if v > MAX_STICK {
+			v = MAX_STICK
+		} else if v < -MAX_STICK {
+			v = -MAX_STICK
+		}
+		return uint16(int(STICK_CENTER) + v*int(STICK_RANGE)/MAX_STICK)
+	}
+	return scale(p.X), scale(-p.Y)
+}
+
+// pack12 packs two 12 bit values into 3 bytes the way the controller does for sticks and their calibration.
+func pack12(x, y uint16) [3]byte {
+	return [3]byte{byte(x), byte(x>>8&0x0F) | byte(y<<4), byte(y >> 4)}
+}
+
+// State the buttons and sticks the controller reports.
+type State struct {
+	Buttons Button
+	Sticks  [2]StickPosition
+}
+
+// header fills bytes 1 to 12 of an input report.
+func (s State) header(r *Report, timer byte) {
+	r[1] = timer
+	r[2] = BATTERY_CONNECTION
+	r[3], r[4], r[5] = byte(s.Buttons), byte(s.Buttons>>8), byte(s.Buttons>>16)
+	for i, p := range s.Sticks {
+		packed := pack12(p.raw())
+		copy(r[6+3*i:], packed[:])
+	}
+	r[12] = VIBRATOR_REPORT
+}
@@ -0,0 +1,125 @@
+// Package usbmon reads USB traffic captured on a Linux host with usbmon, either the text the kernel writes to
+// /sys/kernel/debug/usb/usbmon/<bus>u or a pcap/pcapng file saved by tcpdump or Wireshark.
+//
+// References:
+//
+//	https://www.kernel.org/doc/html/latest/usb/usbmon.html
+//	https://www.tcpdump.org/linktypes/LINKTYPE_USB_LINUX_MMAPPED.html
+package usbmon
+
+import (
+	"fmt"
+	"time"
+)
+
+// Event whether a packet is a URB being submitted or completed.
+type Event byte
+
+const (
+	EVENT_SUBMIT   Event = 'S'
+	EVENT_COMPLETE Event = 'C'
+	EVENT_ERROR    Event = 'E'
+)
+
+// TransferType the kind of endpoint, the values are the ones in the pcap header.
+type TransferType byte
+
+const (
+	TRANSFER_ISOCHRONOUS TransferType = 0
+	TRANSFER_INTERRUPT   TransferType = 1
+	TRANSFER_CONTROL     TransferType = 2
+	TRANSFER_BULK        TransferType = 3
+)
+
+func (t TransferType) String() string {
+	switch t {
+	case TRANSFER_ISOCHRONOUS:
+		return "isochronous"
+	case TRANSFER_INTERRUPT:
+		return "interrupt"
+	case TRANSFER_CONTROL:
+		return "control"
+	case TRANSFER_BULK:
+		return "bulk"
+	}
+	return fmt.Sprintf("transfer type %d", t)
+}
+
+// ENDPOINT_IN the direction bit of an endpoint address, set for device to host.
+const ENDPOINT_IN byte = 0x80
+
+// Control requests the HID class uses to send reports over the control endpoint.
+const (
+	HID_SET_REPORT     byte = 0x09
+	HID_REPORT_OUTPUT  byte = 0x02
+	REQUEST_TYPE_CLASS byte = 0x21 // REQUEST_TYPE_CLASS host to device, class request, to an interface
+)
+
+// Packet one usbmon event, a URB submitted by the host or completed by the device.
+type Packet struct {
+	ID       uint64 // ID the URB's tag, the same for its submission and completion
+	Time     time.Time
+	Event    Event
+	Transfer TransferType
+	Bus      int
+	Device   int
+	Endpoint byte // Endpoint the endpoint address, ENDPOINT_IN set for device to host
+	Status   int
+	Length   int    // Length the URB's length, for a submission how much the host asked for or sent
+	Setup    []byte // Setup the 8 byte setup packet of a control submission, nil otherwise
+	Data     []byte // Data what was captured of the transfer's data, usbmon keeps at most 32 bytes in text
+}
+
+// In true for device to host transfers.
+func (p Packet) In() bool {
+	return p.Endpoint&ENDPOINT_IN != 0
+}
+
+// EndpointNumber the endpoint without its direction bit.
+func (p Packet) EndpointNumber() byte {
+	return p.Endpoint &^ ENDPOINT_IN
+}
+
+// OutputReport the HID output report a packet sends the device: the data of an interrupt OUT submission, or of a
+// SET_REPORT(Output) control request. ok is false for any other packet.
+func (p Packet) OutputReport() (report []byte, ok bool) {
+	if p.Event != EVENT_SUBMIT || p.In() || len(p.Data) == 0 {
+		return nil, false
+	}
+	switch p.Transfer {
+	case TRANSFER_INTERRUPT:
+		return p.Data, true
+	case TRANSFER_CONTROL:
+		if len(p.Setup) == 8 && p.Setup[0] == REQUEST_TYPE_CLASS && p.Setup[1] == HID_SET_REPORT && p.Setup[3] == HID_REPORT_OUTPUT {
+			return p.Data, true
+		}
+	}
+	return nil, false
+}
+
+// InputReport the HID input report a packet brings the host, the data of a completed interrupt IN transfer. ok is
+// false for any other packet.
+func (p Packet) InputReport() (report []byte, ok bool) {
+	if p.Event != EVENT_COMPLETE || !p.In() || p.Transfer != TRANSFER_INTERRUPT || p.Status != 0 || len(p.Data) == 0 {
+		return nil, false
+	}
+	return p.Data, true
+}
+
+// FormatError a capture that could not be read, Line is 0 for binary captures.
+type FormatError struct {
+	Line   int
+	Offset int64
+	Msg    string
+}
+
+func (e *FormatError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("Line %d: %s", e.Line, e.Msg)
+	}
+	return fmt.Sprintf("Offset %d: %s", e.Offset, e.Msg)
+}
+
+func (e *FormatError) String() string {
+	return e.Error()
+}
@@ -0,0 +1,262 @@
+package usbmon
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Link types of usbmon captures.
+const (
+	LINKTYPE_USB_LINUX         uint32 = 189 // LINKTYPE_USB_LINUX 48 byte header
+	LINKTYPE_USB_LINUX_MMAPPED uint32 = 220 // LINKTYPE_USB_LINUX_MMAPPED 64 byte header, what tcpdump and Wireshark save
+)
+
+const (
+	PCAP_MAGIC          uint32 = 0xA1B2C3D4
+	PCAP_MAGIC_NANO     uint32 = 0xA1B23C4D
+	PCAPNG_SECTION      uint32 = 0x0A0D0D0A
+	PCAPNG_BYTE_ORDER   uint32 = 0x1A2B3C4D
+	PCAPNG_INTERFACE    uint32 = 0x00000001
+	PCAPNG_SIMPLE       uint32 = 0x00000003
+	PCAPNG_ENHANCED     uint32 = 0x00000006
+	PCAPNG_OPT_END      uint16 = 0
+	PCAPNG_OPT_IF_NAME  uint16 = 2
+	PCAPNG_OPT_TSRESOL  uint16 = 9
+	USB_HEADER_SZ       int    = 48
+	USB_MMAP_HEADER_SZ  int    = 64
+	usbSetupPresent     byte   = 0 // usbSetupPresent setup_flag value when the header's setup bytes are valid
+	usbDataPresent      byte   = 0 // usbDataPresent data_flag value when data follows the header
+	maxPcapngBlockBytes uint32 = 16 * 1024 * 1024
+)
+
+// ReadCapture reads a pcap or pcapng file of usbmon packets, telling them apart by their first bytes.
+func ReadCapture(r io.Reader) ([]Packet, error) {
+	var br *bufio.Reader = bufio.NewReader(r)
+
+	magic, err := br.Peek(4)
+	if err != nil {
+		return nil, &FormatError{Msg: "too short for a pcap or pcapng file"}
+	}
+	if binary.LittleEndian.Uint32(magic) == PCAPNG_SECTION {
+		return readPcapng(br)
+	}
+	return readPcap(br)
+}
+
+// readPcap the classic libpcap format: a 24 byte file header then a 16 byte header before each packet.
+func readPcap(r io.Reader) ([]Packet, error) {
+	var header [24]byte
+	var order binary.ByteOrder
+	var unit time.Duration = time.Microsecond
+
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, &FormatError{Msg: "too short for a pcap file"}
+	}
+	switch {
+	case binary.LittleEndian.Uint32(header[:]) == PCAP_MAGIC:
+		order = binary.LittleEndian
+	case binary.BigEndian.Uint32(header[:]) == PCAP_MAGIC:
+		order = binary.BigEndian
+	case binary.LittleEndian.Uint32(header[:]) == PCAP_MAGIC_NANO:
+		order, unit = binary.LittleEndian, time.Nanosecond
+	case binary.BigEndian.Uint32(header[:]) == PCAP_MAGIC_NANO:
+		order, unit = binary.BigEndian, time.Nanosecond
+	default:
+		return nil, &FormatError{Msg: fmt.Sprintf("not a pcap or pcapng file, magic %x", header[:4])}
+	}
+	linkType := order.Uint32(header[20:]) & 0x0FFFFFFF
+	if linkType != LINKTYPE_USB_LINUX && linkType != LINKTYPE_USB_LINUX_MMAPPED {
+		return nil, &FormatError{Offset: 20, Msg: fmt.Sprintf("link type %d is not a usbmon capture", linkType)}
+	}
+
+	var packets []Packet
+	var offset int64 = 24
+	for {
+		var rec [16]byte
+		if _, err := io.ReadFull(r, rec[:]); err == io.EOF {
+			return packets, nil
+		} else if err != nil {
+			return packets, &FormatError{Offset: offset, Msg: "truncated packet header"}
+		}
+		data := make([]byte, order.Uint32(rec[8:]))
+		if _, err := io.ReadFull(r, data); err != nil {
+			return packets, &FormatError{Offset: offset, Msg: "truncated packet"}
+		}
+		ts := time.Unix(int64(order.Uint32(rec[0:])), int64(order.Uint32(rec[4:]))*int64(unit))
+		p, err := parseUSBHeader(data, linkType, order, ts)
+		if err != nil {
+			return packets, &FormatError{Offset: offset, Msg: err.Error()}
+		}
+		packets = append(packets, p)
+		offset += 16 + int64(len(data))
+	}
+}
+
+// pcapngInterface what the packets of one interface need to be read.
+type pcapngInterface struct {
+	linkType uint32
+	unit     time.Duration
+}
+
+// readPcapng blocks of a type, a length, the body and the length again. Only the blocks that hold packets are read,
+// the rest are skipped.
+func readPcapng(r io.Reader) ([]Packet, error) {
+	var packets []Packet
+	var order binary.ByteOrder = binary.LittleEndian
+	var interfaces []pcapngInterface
+	var offset int64
+
+	for {
+		var head [8]byte
+		if _, err := io.ReadFull(r, head[:]); err == io.EOF {
+			return packets, nil
+		} else if err != nil {
+			return packets, &FormatError{Offset: offset, Msg: "truncated block header"}
+		}
+		blockType := order.Uint32(head[:])
+		if blockType == PCAPNG_SECTION {
+			// A new section can change the byte order, it is in the first 4 bytes of the body.
+			var bom [4]byte
+			if _, err := io.ReadFull(r, bom[:]); err != nil {
+				return packets, &FormatError{Offset: offset, Msg: "truncated section header"}
+			}
+			if binary.BigEndian.Uint32(bom[:]) == PCAPNG_BYTE_ORDER {
+				order = binary.BigEndian
+			} else if binary.LittleEndian.Uint32(bom[:]) == PCAPNG_BYTE_ORDER {
+				order = binary.LittleEndian
+			} else {
+				return packets, &FormatError{Offset: offset + 8, Msg: "bad byte order magic"}
+			}
+			interfaces = nil
+			length := order.Uint32(head[4:])
+			if length < 28 || length > maxPcapngBlockBytes {
+				return packets, &FormatError{Offset: offset, Msg: fmt.Sprintf("bad section header length %d", length)}
+			}
+			if _, err := io.CopyN(io.Discard, r, int64(length)-12); err != nil {
+				return packets, &FormatError{Offset: offset, Msg: "truncated section header"}
+			}
+			offset += int64(length)
+			continue
+		}
+
+		length := order.Uint32(head[4:])
+		if length < 12 || length%4 != 0 || length > maxPcapngBlockBytes {
+			return packets, &FormatError{Offset: offset, Msg: fmt.Sprintf("bad block length %d", length)}
+		}
+		body := make([]byte, length-8)
+		if _, err := io.ReadFull(r, body); err != nil {
+			return packets, &FormatError{Offset: offset, Msg: "truncated block"}
+		}
+		body = body[:len(body)-4]
+
+		switch blockType {
+		case PCAPNG_INTERFACE:
+			if len(body) < 8 {
+				return packets, &FormatError{Offset: offset, Msg: "short interface description"}
+			}
+			iface := pcapngInterface{linkType: uint32(order.Uint16(body)), unit: time.Microsecond}
+			if resol, ok := pcapngOption(body[8:], order, PCAPNG_OPT_TSRESOL); ok && len(resol) == 1 {
+				iface.unit = tsResolution(resol[0])
+			}
+			interfaces = append(interfaces, iface)
+		case PCAPNG_ENHANCED:
+			if len(body) < 20 {
+				return packets, &FormatError{Offset: offset, Msg: "short enhanced packet"}
+			}
+			id := order.Uint32(body)
+			if int(id) >= len(interfaces) {
+				return packets, &FormatError{Offset: offset, Msg: fmt.Sprintf("packet for undescribed interface %d", id)}
+			}
+			iface := interfaces[id]
+			if iface.linkType != LINKTYPE_USB_LINUX && iface.linkType != LINKTYPE_USB_LINUX_MMAPPED {
+				break
+			}
+			captured := order.Uint32(body[12:])
+			if int(captured) > len(body)-20 {
+				return packets, &FormatError{Offset: offset, Msg: "packet longer than its block"}
+			}
+			ticks := uint64(order.Uint32(body[4:]))<<32 | uint64(order.Uint32(body[8:]))
+			ts := time.Unix(0, 0).Add(time.Duration(ticks) * iface.unit)
+			p, err := parseUSBHeader(body[20:20+captured], iface.linkType, order, ts)
+			if err != nil {
+				return packets, &FormatError{Offset: offset, Msg: err.Error()}
+			}
+			packets = append(packets, p)
+		case PCAPNG_SIMPLE:
+			// Simple packets have no timestamp and no interface, they are for the first one.
+			if len(interfaces) == 0 || len(body) < 4 {
+				break
+			}
+			p, err := parseUSBHeader(body[4:], interfaces[0].linkType, order, time.Unix(0, 0))
+			if err != nil {
+				return packets, &FormatError{Offset: offset, Msg: err.Error()}
+			}
+			packets = append(packets, p)
+		}
+		offset += int64(length)
+	}
+}
+
+// pcapngOption the value of the first option with code, options are padded to 32 bits.
+func pcapngOption(options []byte, order binary.ByteOrder, code uint16) ([]byte, bool) {
+	for len(options) >= 4 {
+		c, n := order.Uint16(options), int(order.Uint16(options[2:]))
+		if c == PCAPNG_OPT_END || 4+n > len(options) {
+			return nil, false
+		}
+		if c == code {
+			return options[4 : 4+n], true
+		}
+		options = options[4+(n+3)/4*4:]
+	}
+	return nil, false
+}
+
+// tsResolution if_tsresol, a power of 10 or with the high bit set of 2.
+func tsResolution(resol byte) time.Duration {
+	var perSecond float64 = 1
+	for i := byte(0); i < resol&0x7F; i++ {
+		if resol&0x80 != 0 {
+			perSecond *= 2
+		} else {
+			perSecond *= 10
+		}
+	}
+	if unit := time.Duration(float64(time.Second) / perSecond); unit > 0 {
+		return unit
+	}
+	return time.Nanosecond
+}
+
+// parseUSBHeader the usbmon header in front of each packet's data. The header's own timestamp is ignored, the
+// record's is as good and is what Wireshark shows.
+func parseUSBHeader(b []byte, linkType uint32, order binary.ByteOrder, ts time.Time) (Packet, error) {
+	var size int = USB_HEADER_SZ
+	if linkType == LINKTYPE_USB_LINUX_MMAPPED {
+		size = USB_MMAP_HEADER_SZ
+	}
+	if len(b) < size {
+		return Packet{}, fmt.Errorf("usbmon header needs %d bytes, packet has %d", size, len(b))
+	}
+	var p = Packet{
+		ID:       order.Uint64(b[0:]),
+		Time:     ts,
+		Event:    Event(b[8]),
+		Transfer: TransferType(b[9]),
+		Endpoint: b[10],
+		Device:   int(b[11]),
+		Bus:      int(order.Uint16(b[12:])),
+		Status:   int(int32(order.Uint32(b[28:]))),
+		Length:   int(order.Uint32(b[32:])),
+	}
+	if b[14] == usbSetupPresent {
+		p.Setup = append([]byte{}, b[40:48]...)
+	}
+	if b[15] == usbDataPresent && len(b) > size {
+		p.Data = append([]byte{}, b[size:]...)
+	}
+	return p, nil
+}
@@ -0,0 +1,149 @@
+package usbmon
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+ReadText reads the text usbmon writes to /sys/kernel/debug/usb/usbmon/<bus>u, one event per line:
+
+	ffff8880b1a4e000 3075883937 S Ii:1:003:1 -115:8 8 <
+	ffff8880b1a4e000 3075891912 C Ii:1:003:1 0:8 8 = 02000400 00000000
+	ffff8880b18ad600 3075892050 S Co:1:003:0 s 21 09 0200 0000 0001 1 = 02
+
+	URB tag, timestamp in microseconds, event, type and direction:bus:device:endpoint, then either a setup packet or
+	the status (with the interval for interrupt and isochronous endpoints), the data length and the data words. Blank
+	lines are skipped. Timestamps have no fixed start, so they are returned counting from the Unix epoch.
+*/
+func ReadText(r io.Reader) ([]Packet, error) {
+	var packets []Packet
+	var line int
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		p, err := parseTextLine(scanner.Text())
+		if err != nil {
+			return packets, &FormatError{Line: line, Msg: err.Error()}
+		}
+		packets = append(packets, p)
+	}
+	return packets, scanner.Err()
+}
+
+func parseTextLine(s string) (Packet, error) {
+	var p Packet
+	var fields []string = strings.Fields(s)
+
+	if len(fields) < 5 {
+		return p, fmt.Errorf("expected at least 5 fields, got %d", len(fields))
+	}
+	id, err := strconv.ParseUint(fields[0], 16, 64)
+	if err != nil {
+		return p, fmt.Errorf("bad URB tag '%s'", fields[0])
+	}
+	us, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return p, fmt.Errorf("bad timestamp '%s'", fields[1])
+	}
+	p.ID, p.Time = id, time.Unix(0, us*int64(time.Microsecond))
+
+	if len(fields[2]) != 1 || !strings.Contains("SCE", fields[2]) {
+		return p, fmt.Errorf("bad event '%s', expected S, C or E", fields[2])
+	}
+	p.Event = Event(fields[2][0])
+
+	if err := parseAddress(fields[3], &p); err != nil {
+		return p, err
+	}
+
+	rest := fields[4:]
+	if rest[0] == "s" {
+		if len(rest) < 6 {
+			return p, fmt.Errorf("setup packet needs 5 words")
+		}
+		if p.Setup, err = parseSetup(rest[1:6]); err != nil {
+			return p, err
+		}
+		rest = rest[6:]
+	} else {
+		status := strings.SplitN(rest[0], ":", 2)[0]
+		if p.Status, err = strconv.Atoi(status); err != nil {
+			return p, fmt.Errorf("bad status '%s'", rest[0])
+		}
+		rest = rest[1:]
+	}
+
+	if len(rest) == 0 {
+		return p, nil
+	}
+	if p.Length, err = strconv.Atoi(rest[0]); err != nil {
+		return p, fmt.Errorf("bad data length '%s'", rest[0])
+	}
+	if len(rest) > 1 && rest[1] == "=" {
+		if p.Data, err = hex.DecodeString(strings.Join(rest[2:], "")); err != nil {
+			return p, fmt.Errorf("bad data words: %s", err)
+		}
+	}
+	return p, nil
+}
+
+// parseAddress "Ii:1:003:1", transfer type and direction, bus, device and endpoint.
+func parseAddress(s string, p *Packet) error {
+	parts := strings.Split(s, ":")
+	if len(parts) != 4 || len(parts[0]) != 2 {
+		return fmt.Errorf("bad address '%s', expected like Ii:1:003:1", s)
+	}
+	switch parts[0][0] {
+	case 'Z':
+		p.Transfer = TRANSFER_ISOCHRONOUS
+	case 'I':
+		p.Transfer = TRANSFER_INTERRUPT
+	case 'C':
+		p.Transfer = TRANSFER_CONTROL
+	case 'B':
+		p.Transfer = TRANSFER_BULK
+	default:
+		return fmt.Errorf("bad transfer type in '%s'", s)
+	}
+	bus, err1 := strconv.Atoi(parts[1])
+	device, err2 := strconv.Atoi(parts[2])
+	endpoint, err3 := strconv.Atoi(parts[3])
+	if err1 != nil || err2 != nil || err3 != nil || endpoint > 15 {
+		return fmt.Errorf("bad address '%s', expected like Ii:1:003:1", s)
+	}
+	p.Bus, p.Device, p.Endpoint = bus, device, byte(endpoint)
+	if parts[0][1] == 'i' {
+		p.Endpoint |= ENDPOINT_IN
+	}
+	return nil
+}
+
+// parseSetup "21 09 0200 0000 0001", bmRequestType, bRequest and wValue, wIndex, wLength in the order usbmon prints
+// them, into the 8 byte little endian setup packet.
+func parseSetup(words []string) ([]byte, error) {
+	var setup []byte = make([]byte, 8)
+
+	for i, w := range words {
+		v, err := strconv.ParseUint(w, 16, 16)
+		if err != nil {
+			return nil, fmt.Errorf("bad setup word '%s'", w)
+		}
+		if i < 2 {
+			setup[i] = byte(v)
+			continue
+		}
+		setup[2*i-2], setup[2*i-1] = byte(v), byte(v>>8)
+	}
+	return setup, nil
+}
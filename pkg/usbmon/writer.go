@@ -0,0 +1,106 @@
+package usbmon
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// Writer writes packets to a pcapng file the way Wireshark saves a usbmon capture: one section, one
+// LINKTYPE_USB_LINUX_MMAPPED interface with nanosecond timestamps and an enhanced packet block per packet.
+type Writer struct {
+	w       io.Writer
+	written int64
+}
+
+// NewWriter writes the section header and interface description to w.
+func NewWriter(w io.Writer, name string) (*Writer, error) {
+	var pw = Writer{w: w}
+
+	var shb []byte = make([]byte, 16)
+	binary.LittleEndian.PutUint32(shb[0:], PCAPNG_BYTE_ORDER)
+	binary.LittleEndian.PutUint16(shb[4:], 1) // Version 1.0
+	binary.LittleEndian.PutUint64(shb[8:], 0xFFFFFFFFFFFFFFFF)
+	if err := pw.block(PCAPNG_SECTION, shb); err != nil {
+		return nil, err
+	}
+
+	var idb []byte = make([]byte, 8)
+	binary.LittleEndian.PutUint16(idb[0:], uint16(LINKTYPE_USB_LINUX_MMAPPED))
+	binary.LittleEndian.PutUint32(idb[4:], 0) // No snap length limit
+	idb = appendOption(idb, PCAPNG_OPT_IF_NAME, []byte(name))
+	idb = appendOption(idb, PCAPNG_OPT_TSRESOL, []byte{9})
+	idb = appendOption(idb, PCAPNG_OPT_END, nil)
+	if err := pw.block(PCAPNG_INTERFACE, idb); err != nil {
+		return nil, err
+	}
+	return &pw, nil
+}
+
+// WritePacket writes p with a usbmon header in front of its data.
+func (pw *Writer) WritePacket(p Packet) error {
+	var header []byte = make([]byte, USB_MMAP_HEADER_SZ)
+	binary.LittleEndian.PutUint64(header[0:], p.ID)
+	header[8], header[9], header[10], header[11] = byte(p.Event), byte(p.Transfer), p.Endpoint, byte(p.Device)
+	binary.LittleEndian.PutUint16(header[12:], uint16(p.Bus))
+	header[14], header[15] = '-', usbDataPresent
+	if p.Setup != nil {
+		header[14] = usbSetupPresent
+		copy(header[40:48], p.Setup)
+	}
+	if len(p.Data) == 0 {
+		header[15] = '>'
+		if p.In() {
+			header[15] = '<'
+		}
+	}
+	binary.LittleEndian.PutUint64(header[16:], uint64(p.Time.Unix()))
+	binary.LittleEndian.PutUint32(header[24:], uint32(p.Time.Nanosecond()/1000))
+	binary.LittleEndian.PutUint32(header[28:], uint32(int32(p.Status)))
+	binary.LittleEndian.PutUint32(header[32:], uint32(p.Length))
+	binary.LittleEndian.PutUint32(header[36:], uint32(len(p.Data)))
+
+	var captured int = len(header) + len(p.Data)
+	var ns uint64 = uint64(p.Time.UnixNano())
+	var epb []byte = make([]byte, 20, 20+captured+3)
+	binary.LittleEndian.PutUint32(epb[0:], 0) // Interface 0
+	binary.LittleEndian.PutUint32(epb[4:], uint32(ns>>32))
+	binary.LittleEndian.PutUint32(epb[8:], uint32(ns))
+	binary.LittleEndian.PutUint32(epb[12:], uint32(captured))
+	binary.LittleEndian.PutUint32(epb[16:], uint32(captured))
+	epb = append(append(epb, header...), p.Data...)
+	return pw.block(PCAPNG_ENHANCED, epb)
+}
+
+// Written how many bytes have been written, to know when to start a new file.
+func (pw *Writer) Written() int64 {
+	return pw.written
+}
+
+// block writes a block padding its body to 32 bits.
+func (pw *Writer) block(blockType uint32, body []byte) error {
+	for len(body)%4 != 0 {
+		body = append(body, 0)
+	}
+	var b []byte = make([]byte, 8, len(body)+12)
+	var length uint32 = uint32(len(body) + 12)
+	binary.LittleEndian.PutUint32(b[0:], blockType)
+	binary.LittleEndian.PutUint32(b[4:], length)
+	b = append(b, body...)
+	b = append(b, b[4:8]...)
+
+	n, err := pw.w.Write(b)
+	pw.written += int64(n)
+	return err
+}
+
+// appendOption adds a pcapng option padded to 32 bits.
+func appendOption(b []byte, code uint16, value []byte) []byte {
+	var head []byte = make([]byte, 4)
+	binary.LittleEndian.PutUint16(head[0:], code)
+	binary.LittleEndian.PutUint16(head[2:], uint16(len(value)))
+	b = append(append(b, head...), value...)
+	for len(b)%4 != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
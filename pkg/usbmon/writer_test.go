@@ -0,0 +1,136 @@
+package usbmon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Packets written with a Writer read back with ReadCapture unchanged.
+func TestWriterRoundTrip(t *testing.T) {
+	var start = time.Date(2024, 5, 1, 12, 30, 0, 123456789, time.UTC)
+	var packets = []Packet{
+		{
+			ID: 1, Time: start, Event: EVENT_SUBMIT, Transfer: TRANSFER_CONTROL, Bus: 1, Device: 2, Endpoint: ENDPOINT_IN,
+			Status: -115, Length: 18, Setup: []byte{0x80, 0x06, 0x00, 0x01, 0x00, 0x00, 0x12, 0x00},
+		},
+		{
+			ID: 1, Time: start.Add(time.Millisecond), Event: EVENT_COMPLETE, Transfer: TRANSFER_CONTROL, Bus: 1, Device: 2,
+			Endpoint: ENDPOINT_IN, Length: 18, Data: []byte{0x12, 0x01, 0x00, 0x02, 0x00, 0x00, 0x00, 0x40, 0x6b, 0x1d, 0x04, 0x01, 0x00, 0x01, 0x01, 0x02, 0x03, 0x01},
+		},
+		{
+			ID: 2, Time: start.Add(2 * time.Millisecond), Event: EVENT_COMPLETE, Transfer: TRANSFER_INTERRUPT, Bus: 1, Device: 2,
+			Endpoint: ENDPOINT_IN | 1, Length: 8, Data: []byte{0x02, 0, 0x04, 0, 0, 0, 0, 0},
+		},
+		{
+			ID: 3, Time: start.Add(3 * time.Millisecond), Event: EVENT_SUBMIT, Transfer: TRANSFER_INTERRUPT, Bus: 1, Device: 2,
+			Endpoint: 1, Status: -115, Length: 1, Data: []byte{0x02},
+		},
+		{
+			ID: 4, Time: start.Add(4 * time.Millisecond), Event: EVENT_ERROR, Transfer: TRANSFER_INTERRUPT, Bus: 3, Device: 7,
+			Endpoint: 1, Status: -32,
+		},
+	}
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, "usbmon1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range packets {
+		if err := w.WritePacket(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if w.Written() != int64(buf.Len()) {
+		t.Errorf("Written is %d, %d bytes were written", w.Written(), buf.Len())
+	}
+	if buf.Len()%4 != 0 {
+		t.Errorf("%d bytes is not a whole number of 32 bit words", buf.Len())
+	}
+
+	got, err := ReadCapture(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(packets) {
+		t.Fatalf("read %d packets, wrote %d", len(got), len(packets))
+	}
+	for i := range packets {
+		if !got[i].Time.Equal(packets[i].Time) {
+			t.Errorf("packet %d at %s, want %s", i, got[i].Time, packets[i].Time)
+		}
+		got[i].Time = packets[i].Time
+		if !reflect.DeepEqual(got[i], packets[i]) {
+			t.Errorf("packet %d read back as\n%+v\nwant\n%+v", i, got[i], packets[i])
+		}
+	}
+}
+
+// Every block is padded to 32 bits and ends with its own length, the way pcapng readers walk a file backwards.
+func TestWriterBlocks(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, "usbmon1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WritePacket(Packet{Event: EVENT_COMPLETE, Transfer: TRANSFER_INTERRUPT, Endpoint: ENDPOINT_IN | 1, Length: 3, Data: []byte{1, 2, 3}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var types []uint32
+	for b := buf.Bytes(); len(b) > 0; {
+		if len(b) < 12 {
+			t.Fatalf("%d bytes left over", len(b))
+		}
+		blockType, length := binary.LittleEndian.Uint32(b), binary.LittleEndian.Uint32(b[4:])
+		if length%4 != 0 || int(length) > len(b) {
+			t.Fatalf("block %x is %d bytes long", blockType, length)
+		}
+		if trailer := binary.LittleEndian.Uint32(b[length-4:]); trailer != length {
+			t.Errorf("block %x is %d bytes but ends with %d", blockType, length, trailer)
+		}
+		types = append(types, blockType)
+		b = b[length:]
+	}
+	if want := []uint32{PCAPNG_SECTION, PCAPNG_INTERFACE, PCAPNG_ENHANCED}; !reflect.DeepEqual(types, want) {
+		t.Errorf("blocks %x, want %x", types, want)
+	}
+}
+
+type failingWriter struct {
+	n int // n how many bytes are written before failing
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		n := w.n
+		w.n = 0
+		return n, errors.New("disk full")
+	}
+	w.n -= len(p)
+	return len(p), nil
+}
+
+func TestWriterErrors(t *testing.T) {
+	if _, err := NewWriter(&failingWriter{n: 10}, "usbmon1"); err == nil {
+		t.Error("NewWriter on a full disk succeeded")
+	}
+
+	var fw = failingWriter{n: 1000}
+	w, err := NewWriter(&fw, "usbmon1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := w.Written()
+	fw.n = 20
+	if err := w.WritePacket(Packet{Event: EVENT_COMPLETE, Endpoint: ENDPOINT_IN | 1, Data: []byte{0}}); err == nil {
+		t.Error("WritePacket on a full disk succeeded")
+	}
+	if w.Written() != before+20 {
+		t.Errorf("Written is %d after a short write, want %d", w.Written(), before+20)
+	}
+}